@@ -4,9 +4,9 @@ go 1.24.0
 
 toolchain go1.24.3
 
-require golang.org/x/crypto v0.17.0
-
 require (
-	golang.org/x/sync v0.19.0 // indirect
-	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/crypto v0.17.0
+	golang.org/x/sync v0.19.0
 )
+
+require golang.org/x/sys v0.15.0 // indirect
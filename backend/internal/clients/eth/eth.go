@@ -65,12 +65,19 @@ type rpcRequest struct {
 
 type rpcResponse struct {
 	Result json.RawMessage `json:"result"`
-	Error  *struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
-	} `json:"error,omitempty"`
+	Error  *RPCError       `json:"error,omitempty"`
 }
 
+// RPCError is a JSON-RPC error response from a provider. Data carries extra
+// error data, e.g. the ABI-encoded revert payload of a failed eth_call.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string { return e.Message }
+
 // bareError matches non-standard rate-limit responses from providers like Infura
 // that return {"code":-32005,"message":"Too Many Requests"} without a JSON-RPC envelope.
 type bareError struct {
@@ -92,7 +99,7 @@ func callOne(url, method string, params any) (json.RawMessage, error) {
 		return nil, err
 	}
 	if parsed.Error != nil {
-		return nil, errors.New(parsed.Error.Message)
+		return nil, parsed.Error
 	}
 	// Detect non-standard error responses (e.g. Infura rate limits)
 	if parsed.Result == nil {
@@ -105,9 +112,24 @@ func callOne(url, method string, params any) (json.RawMessage, error) {
 	return parsed.Result, nil
 }
 
+// raceTimeout bounds a multi-provider Call; later answers are discarded.
+const raceTimeout = 5 * time.Second
+
+// CallTimeout is how long Call waits for an answer: the HTTP client timeout,
+// capped at raceTimeout when several providers are raced.
+func CallTimeout() time.Duration {
+	d := rpcHTTPClient.Timeout
+	if len(rpcProviders) > 1 && d > raceTimeout {
+		d = raceTimeout
+	}
+	return d
+}
+
 // Call invokes an Ethereum JSON-RPC method, racing all providers in parallel.
 // Returns the first successful response. This provides both redundancy and
-// load distribution across multiple RPC endpoints.
+// load distribution across multiple RPC endpoints. Errors reported by a node
+// are *RPCError (use errors.As); transport failures are plain errors. When every
+// provider fails, the most informative error is returned (see errRank).
 func Call(method string, params any) (json.RawMessage, error) {
 	if len(rpcProviders) == 1 {
 		// Single provider - direct call
@@ -121,7 +143,7 @@ func Call(method string, params any) (json.RawMessage, error) {
 	}
 
 	// Multiple providers - race them all in parallel
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), raceTimeout)
 	defer cancel()
 
 	type rpcResult struct {
@@ -158,7 +180,9 @@ func Call(method string, params any) (json.RawMessage, error) {
 			rpcHealth.SetSuccess()
 			return r.data, nil
 		}
-		lastErr = r.err
+		if lastErr == nil || errRank(r.err) > errRank(lastErr) {
+			lastErr = r.err
+		}
 	}
 
 	// All providers failed
@@ -169,6 +193,21 @@ func Call(method string, params any) (json.RawMessage, error) {
 	return nil, lastErr
 }
 
+// errRank orders provider errors so the most informative one is returned when
+// all fail: a node error carrying data (e.g. revert data) beats any other node
+// error, which beats a transport failure.
+func errRank(err error) int {
+	var rpcErr *RPCError
+	switch {
+	case !errors.As(err, &rpcErr):
+		return 0
+	case len(rpcErr.Data) > 0:
+		return 2
+	default:
+		return 1
+	}
+}
+
 // CheckHealth performs one RPC call and returns health status.
 func CheckHealth() pkg.HealthStatus {
 	_, err := Call("eth_blockNumber", []any{})
@@ -0,0 +1,292 @@
+// WebSocket JSON-RPC subscriptions live in package eth (see eth.go for package doc).
+// This is a minimal RFC 6455 client (text frames, ping/pong, close) so the backend
+// can use eth_subscribe without pulling in a WebSocket dependency.
+package eth
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xa
+
+	// wsMaxMessage bounds a single reassembled message so a misbehaving node cannot exhaust memory.
+	wsMaxMessage = 16 << 20
+	// wsGUID is the fixed key suffix from RFC 6455 §1.3 used to validate Sec-WebSocket-Accept.
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+// ErrWSNotConfigured is returned by Subscribe when RPC_WS_URL is empty.
+var ErrWSNotConfigured = errors.New("RPC_WS_URL not configured")
+
+// wsConn is a client-side WebSocket connection. Reads happen on one goroutine
+// (the subscriber); writes are serialized by mu because the reader answers pings.
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	mu   sync.Mutex
+}
+
+// dialWS performs the HTTP/1.1 upgrade handshake against a ws:// or wss:// URL.
+func dialWS(rawURL string, timeout time.Duration) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		switch u.Scheme {
+		case "wss":
+			host += ":443"
+		case "ws":
+			host += ":80"
+		default:
+			return nil, fmt.Errorf("unsupported websocket scheme %q", u.Scheme)
+		}
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if u.Scheme == "wss" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.Hostname()})
+	} else {
+		conn, err = dialer.Dial("tcp", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	path := u.RequestURI()
+	req := "GET " + path + " HTTP/1.1\r\n" +
+		"Host: " + u.Host + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := io.WriteString(conn, req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, &http.Request{Method: "GET"})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: HTTP %d", resp.StatusCode)
+	}
+	sum := sha1.Sum([]byte(key + wsGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		conn.Close()
+		return nil, errors.New("websocket handshake failed: bad Sec-WebSocket-Accept")
+	}
+	conn.SetDeadline(time.Time{})
+	return &wsConn{conn: conn, br: br}, nil
+}
+
+// writeFrame sends a single masked frame (clients must always mask per RFC 6455 §5.3).
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	header := []byte{0x80 | op}
+	n := len(payload)
+	switch {
+	case n < 126:
+		header = append(header, 0x80|byte(n))
+	case n <= 0xffff:
+		header = append(header, 0x80|126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 0x80|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	header = append(header, mask[:]...)
+	masked := make([]byte, n)
+	for i := range payload {
+		masked[i] = payload[i] ^ mask[i%4]
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(append(header, masked...))
+	return err
+}
+
+// readMessage returns the next complete text or binary message, transparently
+// answering pings and reassembling fragmented messages.
+func (c *wsConn) readMessage(idle time.Duration) ([]byte, error) {
+	var msg []byte
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(idle))
+		var hdr [2]byte
+		if _, err := io.ReadFull(c.br, hdr[:]); err != nil {
+			return nil, err
+		}
+		fin := hdr[0]&0x80 != 0
+		op := hdr[0] & 0x0f
+		length := uint64(hdr[1] & 0x7f)
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+				return nil, err
+			}
+			length = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+				return nil, err
+			}
+			length = binary.BigEndian.Uint64(ext[:])
+		}
+		if length > wsMaxMessage || uint64(len(msg))+length > wsMaxMessage {
+			return nil, errors.New("websocket message too large")
+		}
+		var mask [4]byte
+		masked := hdr[1]&0x80 != 0
+		if masked {
+			if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+				return nil, err
+			}
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.br, payload); err != nil {
+			return nil, err
+		}
+		if masked {
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+		}
+		switch op {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return nil, err
+			}
+		case wsOpPong:
+		case wsOpClose:
+			_ = c.writeFrame(wsOpClose, nil)
+			return nil, io.EOF
+		case wsOpText, wsOpBinary, wsOpContinuation:
+			msg = append(msg, payload...)
+			if fin {
+				return msg, nil
+			}
+		default:
+			return nil, fmt.Errorf("websocket: unexpected opcode %d", op)
+		}
+	}
+}
+
+func (c *wsConn) close() error {
+	_ = c.writeFrame(wsOpClose, nil)
+	return c.conn.Close()
+}
+
+// Subscription is one live eth_subscribe stream over RPC_WS_URL. It is not safe
+// for concurrent Next calls; the mempool monitor owns it on a single goroutine.
+type Subscription struct {
+	ID   string
+	conn *wsConn
+	idle time.Duration
+}
+
+// WSConfigured reports whether RPC_WS_URL is set, i.e. whether Subscribe can work.
+func WSConfigured() bool {
+	return rpcWS != ""
+}
+
+// Subscribe dials RPC_WS_URL and issues eth_subscribe with params (e.g.
+// ["newPendingTransactions"]). It returns once the node has acknowledged the
+// subscription with an ID; call Next to receive notifications. A JSON-RPC error
+// from the node (e.g. unsupported params) is returned as *RPCError.
+func Subscribe(params []any) (*Subscription, error) {
+	if rpcWS == "" {
+		return nil, ErrWSNotConfigured
+	}
+	conn, err := dialWS(rpcWS, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	payload, _ := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: 1, Method: "eth_subscribe", Params: params})
+	if err := conn.writeFrame(wsOpText, payload); err != nil {
+		conn.close()
+		return nil, err
+	}
+	raw, err := conn.readMessage(10 * time.Second)
+	if err != nil {
+		conn.close()
+		return nil, err
+	}
+	var parsed rpcResponse
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		conn.close()
+		return nil, err
+	}
+	if parsed.Error != nil {
+		conn.close()
+		return nil, parsed.Error
+	}
+	var id string
+	if err := json.Unmarshal(parsed.Result, &id); err != nil || id == "" {
+		conn.close()
+		return nil, errors.New("eth_subscribe returned no subscription id")
+	}
+	return &Subscription{ID: id, conn: conn, idle: 2 * time.Minute}, nil
+}
+
+// Next blocks until the next notification for this subscription and returns its
+// params.result payload. An error means the connection is gone and the caller
+// should resubscribe.
+func (s *Subscription) Next() (json.RawMessage, error) {
+	for {
+		raw, err := s.conn.readMessage(s.idle)
+		if err != nil {
+			return nil, err
+		}
+		var note struct {
+			Method string `json:"method"`
+			Params struct {
+				Subscription string          `json:"subscription"`
+				Result       json.RawMessage `json:"result"`
+			} `json:"params"`
+		}
+		if json.Unmarshal(raw, &note) != nil || note.Method != "eth_subscription" || note.Params.Subscription != s.ID {
+			continue
+		}
+		return note.Params.Result, nil
+	}
+}
+
+// Close tears down the underlying connection. The node drops the subscription with it.
+func (s *Subscription) Close() error {
+	return s.conn.close()
+}
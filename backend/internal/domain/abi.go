@@ -0,0 +1,582 @@
+// Package domain: this file is the ABI decoding engine behind txdecode. It parses
+// canonical Solidity types (elementary, bytes/string, T[], T[k], tuples, nested
+// freely) and decodes ABI-encoded calldata or revert data into named, typed values.
+package domain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrABIDecode is wrapped by every decoding failure.
+var ErrABIDecode = errors.New("abi decode failed")
+
+type abiKind int
+
+const (
+	abiUint abiKind = iota
+	abiInt
+	abiAddress
+	abiBool
+	abiFixedBytes // bytes1..bytes32
+	abiBytes
+	abiString
+	abiSlice // T[]
+	abiArray // T[k]
+	abiTuple
+)
+
+// abiType is a parsed Solidity ABI type. Size is the bit width for ints, N for
+// bytesN and k for T[k]; Names optionally labels tuple components.
+type abiType struct {
+	Kind       abiKind
+	Size       int
+	Elem       *abiType
+	Components []abiType
+	Names      []string
+	Canonical  string
+
+	// isDynamic and head cache dynamic() and headSize(), set by withLayout.
+	isDynamic bool
+	head      int
+}
+
+// ABIValue is one decoded value. Value is a string for addresses (lowercase
+// hex), integers (decimal), bytes (hex) and strings; a bool; or []ABIValue for
+// arrays and tuples (tuple entries carry component names when known).
+type ABIValue struct {
+	Name  string `json:"name,omitempty"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+
+	// off is 1 + the payload offset of a bytes/string value's length word (0
+	// when unknown); equal offsets mean entries share one encoding.
+	off int
+}
+
+// DecodedCall is calldata decoded against a function signature.
+type DecodedCall struct {
+	Selector  string     `json:"selector"`
+	Signature string     `json:"signature"`
+	Name      string     `json:"name"`
+	Args      []ABIValue `json:"args"`
+}
+
+// abiMaxNesting bounds type nesting so a hostile signature can't recurse forever.
+const abiMaxNesting = 16
+
+// abiMaxValues bounds the values decoded from one payload. Offsets may point at
+// shared data, so nested dynamic arrays could otherwise expand exponentially.
+const abiMaxValues = 100000
+
+// abiMaxBytes bounds the bytes/string content copied out of one payload. Every
+// reference counts, so many entries aliasing one large blob can't multiply it.
+const abiMaxBytes = 1 << 20
+
+// abiMaxHead bounds the inline size of a static array type, so T[k][k]... can't
+// overflow the offset arithmetic.
+const abiMaxHead = abiMaxValues * 32
+
+// parseABIType parses a canonical type such as "uint256", "address[]",
+// "(address,bytes)[]" or "uint8[2][]".
+func parseABIType(s string) (abiType, error) {
+	return parseABITypeDepth(strings.TrimSpace(s), 0)
+}
+
+func parseABITypeDepth(s string, depth int) (abiType, error) {
+	if depth > abiMaxNesting {
+		return abiType{}, fmt.Errorf("%w: type nesting too deep", ErrABIDecode)
+	}
+	// Array suffixes bind last: peel the outermost one.
+	if strings.HasSuffix(s, "]") {
+		open := strings.LastIndexByte(s, '[')
+		if open <= 0 {
+			return abiType{}, fmt.Errorf("%w: bad array type %q", ErrABIDecode, s)
+		}
+		elem, err := parseABITypeDepth(s[:open], depth+1)
+		if err != nil {
+			return abiType{}, err
+		}
+		t := abiType{Kind: abiSlice, Elem: &elem, Canonical: elem.Canonical + s[open:]}
+		if n := s[open+1 : len(s)-1]; n != "" {
+			k, err := strconv.Atoi(n)
+			if err != nil || k <= 0 || k > abiMaxValues {
+				return abiType{}, fmt.Errorf("%w: bad array length in %q", ErrABIDecode, s)
+			}
+			if !elem.dynamic() && elem.headSize() > abiMaxHead/k {
+				return abiType{}, fmt.Errorf("%w: static array %q too large", ErrABIDecode, s)
+			}
+			t.Kind, t.Size = abiArray, k
+		}
+		return t.withLayout(), nil
+	}
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		parts, err := splitABIList(s[1 : len(s)-1])
+		if err != nil {
+			return abiType{}, err
+		}
+		t := abiType{Kind: abiTuple}
+		canon := make([]string, len(parts))
+		for i, p := range parts {
+			c, err := parseABITypeDepth(p, depth+1)
+			if err != nil {
+				return abiType{}, err
+			}
+			t.Components = append(t.Components, c)
+			canon[i] = c.Canonical
+		}
+		t.Canonical = "(" + strings.Join(canon, ",") + ")"
+		return t.withLayout(), nil
+	}
+	switch {
+	case s == "address":
+		return abiType{Kind: abiAddress, Canonical: s}.withLayout(), nil
+	case s == "bool":
+		return abiType{Kind: abiBool, Canonical: s}.withLayout(), nil
+	case s == "string":
+		return abiType{Kind: abiString, Canonical: s}.withLayout(), nil
+	case s == "bytes":
+		return abiType{Kind: abiBytes, Canonical: s}.withLayout(), nil
+	case s == "function":
+		return abiType{Kind: abiFixedBytes, Size: 24, Canonical: s}.withLayout(), nil
+	case strings.HasPrefix(s, "bytes"):
+		n, err := strconv.Atoi(s[5:])
+		if err != nil || n < 1 || n > 32 {
+			return abiType{}, fmt.Errorf("%w: bad type %q", ErrABIDecode, s)
+		}
+		return abiType{Kind: abiFixedBytes, Size: n, Canonical: s}.withLayout(), nil
+	case strings.HasPrefix(s, "uint"), strings.HasPrefix(s, "int"):
+		kind, bits := abiUint, strings.TrimPrefix(s, "uint")
+		if !strings.HasPrefix(s, "uint") {
+			kind, bits = abiInt, strings.TrimPrefix(s, "int")
+		}
+		n := 256
+		if bits != "" {
+			var err error
+			if n, err = strconv.Atoi(bits); err != nil || n < 8 || n > 256 || n%8 != 0 {
+				return abiType{}, fmt.Errorf("%w: bad type %q", ErrABIDecode, s)
+			}
+		}
+		return abiType{Kind: kind, Size: n, Canonical: s[:len(s)-len(bits)] + strconv.Itoa(n)}.withLayout(), nil
+	}
+	return abiType{}, fmt.Errorf("%w: unsupported type %q", ErrABIDecode, s)
+}
+
+// splitABIList splits a comma-separated type list at top-level commas.
+func splitABIList(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("%w: unbalanced parentheses in %q", ErrABIDecode, s)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("%w: unbalanced parentheses in %q", ErrABIDecode, s)
+	}
+	return append(parts, strings.TrimSpace(s[start:])), nil
+}
+
+// parseSignature splits "name(type,...)" into the name and a tuple of the
+// argument types.
+func parseSignature(sig string) (string, abiType, error) {
+	open := strings.IndexByte(sig, '(')
+	if open <= 0 || !strings.HasSuffix(sig, ")") {
+		return "", abiType{}, fmt.Errorf("%w: bad signature %q", ErrABIDecode, sig)
+	}
+	args, err := parseABIType(sig[open:])
+	if err != nil {
+		return "", abiType{}, err
+	}
+	return sig[:open], args, nil
+}
+
+// withLayout returns t with dynamic() and headSize() computed from its
+// (already laid out) element or components, so decoding never re-walks the type.
+func (t abiType) withLayout() abiType {
+	switch t.Kind {
+	case abiBytes, abiString, abiSlice:
+		t.isDynamic = true
+	case abiArray:
+		t.isDynamic = t.Elem.dynamic()
+	case abiTuple:
+		for i := range t.Components {
+			t.isDynamic = t.isDynamic || t.Components[i].dynamic()
+		}
+	}
+	switch {
+	case t.isDynamic:
+		t.head = 32
+	case t.Kind == abiArray:
+		t.head = t.Size * t.Elem.headSize()
+	case t.Kind == abiTuple:
+		for i := range t.Components {
+			t.head += t.Components[i].headSize()
+		}
+	default:
+		t.head = 32
+	}
+	return t
+}
+
+// dynamic reports whether t is encoded out of line (behind an offset).
+func (t *abiType) dynamic() bool { return t.isDynamic }
+
+// headSize is the bytes t occupies in its enclosing head.
+func (t *abiType) headSize() int { return t.head }
+
+// abiWord returns the 32-byte word at off, bounds-checked.
+func abiWord(data []byte, off int) ([]byte, error) {
+	if off < 0 || off+32 > len(data) {
+		return nil, fmt.Errorf("%w: read past end of data at offset %d", ErrABIDecode, off)
+	}
+	return data[off : off+32], nil
+}
+
+// abiOffset reads a word used as an offset or length, rejecting values that
+// can't fit in data (which also stops absurd allocations).
+func abiOffset(data []byte, off int) (int, error) {
+	w, err := abiWord(data, off)
+	if err != nil {
+		return 0, err
+	}
+	v := new(big.Int).SetBytes(w)
+	if !v.IsInt64() || v.Int64() > int64(len(data)) {
+		return 0, fmt.Errorf("%w: offset or length %s out of range", ErrABIDecode, v)
+	}
+	return int(v.Int64()), nil
+}
+
+// abiDecoder carries the value and byte budgets for one payload. A strict
+// decoder also rejects words a real encoder never produces (dirty address or
+// bytesN padding, bools other than 0/1, uintN/intN out of range); the registry
+// uses it to choose between signatures that share a selector.
+type abiDecoder struct {
+	budget int
+	bytes  int
+	strict bool
+	base   int // cap of the payload, to turn sub-slices back into offsets
+}
+
+// newABIDecoder returns a decoder with the full abiMaxValues/abiMaxBytes budgets.
+func newABIDecoder(strict bool) *abiDecoder {
+	return &abiDecoder{budget: abiMaxValues, bytes: abiMaxBytes, strict: strict}
+}
+
+// value decodes t at off within data, where data is the enclosing tuple's
+// encoding (offsets are relative to its start).
+func (d *abiDecoder) value(t *abiType, data []byte, off int) (ABIValue, error) {
+	v := ABIValue{Type: t.Canonical}
+	if d.budget--; d.budget < 0 {
+		return v, fmt.Errorf("%w: more than %d values", ErrABIDecode, abiMaxValues)
+	}
+	if off > len(data) {
+		return v, fmt.Errorf("%w: read past end of data at offset %d", ErrABIDecode, off)
+	}
+	if t.dynamic() {
+		rel, err := abiOffset(data, off)
+		if err != nil {
+			return v, err
+		}
+		data, off = data[rel:], 0
+	}
+	switch t.Kind {
+	case abiUint, abiInt:
+		w, err := abiWord(data, off)
+		if err != nil {
+			return v, err
+		}
+		n := new(big.Int).SetBytes(w)
+		if t.Kind == abiInt && w[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		if d.strict && !abiFits(n, t) {
+			return v, fmt.Errorf("%w: %s out of range for %s", ErrABIDecode, n, t.Canonical)
+		}
+		v.Value = n.String()
+	case abiAddress:
+		w, err := abiWord(data, off)
+		if err != nil {
+			return v, err
+		}
+		if d.strict && !allZero(w[:12]) {
+			return v, fmt.Errorf("%w: dirty address padding at offset %d", ErrABIDecode, off)
+		}
+		v.Value = "0x" + hex.EncodeToString(w[12:])
+	case abiBool:
+		w, err := abiWord(data, off)
+		if err != nil {
+			return v, err
+		}
+		if d.strict && (!allZero(w[:31]) || w[31] > 1) {
+			return v, fmt.Errorf("%w: bad bool at offset %d", ErrABIDecode, off)
+		}
+		v.Value = w[31] != 0
+	case abiFixedBytes:
+		w, err := abiWord(data, off)
+		if err != nil {
+			return v, err
+		}
+		if d.strict && !allZero(w[t.Size:]) {
+			return v, fmt.Errorf("%w: dirty %s padding at offset %d", ErrABIDecode, t.Canonical, off)
+		}
+		v.Value = "0x" + hex.EncodeToString(w[:t.Size])
+	case abiBytes, abiString:
+		n, err := abiOffset(data, off)
+		if err != nil {
+			return v, err
+		}
+		if off+32+n > len(data) {
+			return v, fmt.Errorf("%w: %s of length %d runs past end of data", ErrABIDecode, t.Canonical, n)
+		}
+		if d.bytes -= n; d.bytes < 0 {
+			return v, fmt.Errorf("%w: more than %d bytes of bytes/string data", ErrABIDecode, abiMaxBytes)
+		}
+		b := data[off+32 : off+32+n]
+		v.off = d.base - cap(data) + off + 1
+		if t.Kind == abiString {
+			v.Value = string(b)
+		} else {
+			v.Value = "0x" + hex.EncodeToString(b)
+		}
+	case abiSlice, abiArray:
+		n := t.Size
+		if t.Kind == abiSlice {
+			var err error
+			if n, err = abiOffset(data, off); err != nil {
+				return v, err
+			}
+			off += 32
+		}
+		// Elements form a tuple of n copies of Elem, starting at off.
+		elems := data[off:]
+		if n > 0 && t.Elem.headSize() > len(elems)/n {
+			return v, fmt.Errorf("%w: %s of length %d runs past end of data", ErrABIDecode, t.Canonical, n)
+		}
+		out := make([]ABIValue, n)
+		for i := range out {
+			ev, err := d.value(t.Elem, elems, i*t.Elem.headSize())
+			if err != nil {
+				return v, err
+			}
+			out[i] = ev
+		}
+		v.Value = out
+	case abiTuple:
+		out, err := d.tuple(t, data[off:])
+		if err != nil {
+			return v, err
+		}
+		v.Value = out
+	}
+	return v, nil
+}
+
+// abiFits reports whether n is representable in t's bit width.
+func abiFits(n *big.Int, t *abiType) bool {
+	if t.Kind == abiUint {
+		return n.BitLen() <= t.Size
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+	return n.Cmp(limit) < 0 && n.Cmp(new(big.Int).Neg(limit)) >= 0
+}
+
+func allZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// tuple decodes each component of t from data (the tuple's encoding).
+func (d *abiDecoder) tuple(t *abiType, data []byte) ([]ABIValue, error) {
+	out := make([]ABIValue, len(t.Components))
+	head := 0
+	for i := range t.Components {
+		c := &t.Components[i]
+		v, err := d.value(c, data, head)
+		if err != nil {
+			return nil, err
+		}
+		if i < len(t.Names) {
+			v.Name = t.Names[i]
+		}
+		out[i] = v
+		head += c.headSize()
+	}
+	return out, nil
+}
+
+// decodeABIArgs decodes data (no selector) as the argument tuple of sig.
+func decodeABIArgs(sig string, data []byte, names []string) ([]ABIValue, error) {
+	return newABIDecoder(false).args(sig, data, names)
+}
+
+func (d *abiDecoder) args(sig string, data []byte, names []string) ([]ABIValue, error) {
+	_, args, err := parseSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+	args.Names = names
+	d.base = cap(data)
+	return d.tuple(&args, data)
+}
+
+// DecodeCalldata decodes hex input (selector + arguments) against signature,
+// e.g. "transfer(address,uint256)". Argument names come from methodParamNames
+// when known.
+func DecodeCalldata(signature, input string) (*DecodedCall, error) {
+	sel := strings.ToLower(strings.TrimPrefix(input, "0x"))
+	if len(sel) >= 8 {
+		sel = "0x" + sel[:8]
+	}
+	return decodeCall(signature, input, methodParamNames[sel], false)
+}
+
+// decodeCall is DecodeCalldata with explicit argument names and strictness.
+func decodeCall(signature, input string, names []string, strict bool) (*DecodedCall, error) {
+	input = strings.TrimPrefix(strings.ToLower(input), "0x")
+	if len(input) < 8 {
+		return nil, fmt.Errorf("%w: input shorter than a selector", ErrABIDecode)
+	}
+	data, err := hex.DecodeString(input[8:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrABIDecode, err)
+	}
+	name, _, err := parseSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	args, err := newABIDecoder(strict).args(signature, data, names)
+	if err != nil {
+		return nil, err
+	}
+	return &DecodedCall{Selector: "0x" + input[:8], Signature: signature, Name: name, Args: args}, nil
+}
+
+// methodParamNames labels the arguments of methodSignatures entries.
+var methodParamNames = map[string][]string{
+	"0xa9059cbb": {"to", "amount"},
+	"0x23b872dd": {"from", "to", "amount"},
+	"0x095ea7b3": {"spender", "amount"},
+	"0x38ed1739": {"amountIn", "amountOutMin", "path", "to", "deadline"},
+	"0x7ff36ab5": {"amountOutMin", "path", "to", "deadline"},
+	"0x18cbafe5": {"amountIn", "amountOutMin", "path", "to", "deadline"},
+	"0xfb3bdb41": {"amountOut", "path", "to", "deadline"},
+	"0x8803dbee": {"amountOut", "amountInMax", "path", "to", "deadline"},
+	"0x791ac947": {"amountIn", "amountOutMin", "path", "to", "deadline"},
+	"0xb6f9de95": {"amountOutMin", "path", "to", "deadline"},
+	"0x5c11d795": {"amountIn", "amountOutMin", "path", "to", "deadline"},
+	"0x2e1a7d4d": {"amount"},
+	"0xb6b55f25": {"amount"},
+	"0x379607f5": {"amount"},
+	"0x40c10f19": {"to", "amount"},
+	"0xa0712d68": {"amount"},
+	"0x6a627842": {"to"},
+	"0x94bf804d": {"request", "signature"},
+	"0xb61d27f6": {"target", "value", "data"},
+	"0x1cff79cd": {"target", "data"},
+	"0x3593564c": {"commands", "inputs", "deadline"},
+	"0x24856bc3": {"commands", "inputs"},
+	"0xac9650d8": {"data"},
+	"0x5ae401dc": {"deadline", "data"},
+	"0x252dba42": {"calls"},
+	"0x6a761202": {"to", "value", "data", "operation", "safeTxGas", "baseGas", "gasPrice", "gasToken", "refundReceiver", "signatures"},
+	"0x1fad948c": {"ops", "beneficiary"},
+	"0x765e827f": {"ops", "beneficiary"},
+	"0xfa89401a": {"to"},
+}
+
+// arg returns the argument called name, or nil.
+func (c *DecodedCall) arg(name string) *ABIValue {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Args {
+		if c.Args[i].Name == name {
+			return &c.Args[i]
+		}
+	}
+	return nil
+}
+
+// argString returns a string-valued argument (address, integer, bytes, string).
+func (c *DecodedCall) argString(name string) (string, bool) {
+	a := c.arg(name)
+	if a == nil {
+		return "", false
+	}
+	s, ok := a.Value.(string)
+	return s, ok
+}
+
+// argBig returns an integer argument.
+func (c *DecodedCall) argBig(name string) (*big.Int, bool) {
+	s, ok := c.argString(name)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Int).SetString(s, 10)
+}
+
+// argAddresses returns an address[] argument.
+func (c *DecodedCall) argAddresses(name string) ([]string, bool) {
+	a := c.arg(name)
+	if a == nil {
+		return nil, false
+	}
+	elems, ok := a.Value.([]ABIValue)
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, 0, len(elems))
+	for _, e := range elems {
+		s, ok := e.Value.(string)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
+
+// formatABIValue renders v compactly for human-readable messages.
+func formatABIValue(v ABIValue) string {
+	switch x := v.Value.(type) {
+	case string:
+		if v.Type == "string" {
+			return strconv.Quote(x)
+		}
+		return x
+	case []ABIValue:
+		parts := make([]string, len(x))
+		for i, e := range x {
+			parts[i] = formatABIValue(e)
+		}
+		if strings.HasPrefix(v.Type, "(") && !strings.HasSuffix(v.Type, "]") {
+			return "(" + strings.Join(parts, ", ") + ")"
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	return fmt.Sprint(v.Value)
+}
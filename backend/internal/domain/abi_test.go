@@ -0,0 +1,368 @@
+package domain
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// abiEncode ABI-encodes vals as the tuple of types. Integers are decimal
+// strings, addresses and bytes/bytesN 0x-hex, strings Go strings, bools bool,
+// arrays and tuples []any.
+func abiEncode(t testing.TB, types []string, vals ...any) []byte {
+	t.Helper()
+	ts := make([]abiType, len(types))
+	for i, s := range types {
+		pt, err := parseABIType(s)
+		if err != nil {
+			t.Fatalf("parseABIType(%q): %v", s, err)
+		}
+		ts[i] = pt
+	}
+	return abiEncodeTuple(t, ts, vals)
+}
+
+// calldata returns selector(sig) followed by the encoded vals as 0x-hex.
+func calldata(t testing.TB, sig string, vals ...any) string {
+	t.Helper()
+	_, args, err := parseSignature(sig)
+	if err != nil {
+		t.Fatalf("parseSignature(%q): %v", sig, err)
+	}
+	return keccakTopic(sig)[:10] + hex.EncodeToString(abiEncodeTuple(t, args.Components, vals))
+}
+
+func abiEncodeTuple(t testing.TB, ts []abiType, vals []any) []byte {
+	t.Helper()
+	if len(ts) != len(vals) {
+		t.Fatalf("abiEncode: %d types, %d values", len(ts), len(vals))
+	}
+	headLen := 0
+	for i := range ts {
+		headLen += ts[i].headSize()
+	}
+	var head, tail []byte
+	for i := range ts {
+		enc := abiEncodeValue(t, &ts[i], vals[i])
+		if ts[i].dynamic() {
+			head = append(head, abiWordOf(big.NewInt(int64(headLen+len(tail))))...)
+			tail = append(tail, enc...)
+		} else {
+			head = append(head, enc...)
+		}
+	}
+	return append(head, tail...)
+}
+
+func abiEncodeValue(t testing.TB, at *abiType, v any) []byte {
+	t.Helper()
+	switch at.Kind {
+	case abiUint, abiInt:
+		n, ok := new(big.Int).SetString(v.(string), 10)
+		if !ok {
+			t.Fatalf("abiEncode: bad integer %v", v)
+		}
+		if n.Sign() < 0 {
+			n.Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		return abiWordOf(n)
+	case abiAddress:
+		return append(make([]byte, 12), abiHexBytes(t, v.(string))...)
+	case abiBool:
+		if v.(bool) {
+			return abiWordOf(big.NewInt(1))
+		}
+		return make([]byte, 32)
+	case abiFixedBytes:
+		w := make([]byte, 32)
+		copy(w, abiHexBytes(t, v.(string)))
+		return w
+	case abiBytes, abiString:
+		b := []byte(v.(string))
+		if at.Kind == abiBytes {
+			b = abiHexBytes(t, v.(string))
+		}
+		out := abiWordOf(big.NewInt(int64(len(b))))
+		out = append(out, b...)
+		return append(out, make([]byte, (32-len(b)%32)%32)...)
+	case abiSlice, abiArray:
+		elems := v.([]any)
+		ts := make([]abiType, len(elems))
+		for i := range ts {
+			ts[i] = *at.Elem
+		}
+		enc := abiEncodeTuple(t, ts, elems)
+		if at.Kind == abiSlice {
+			return append(abiWordOf(big.NewInt(int64(len(elems)))), enc...)
+		}
+		return enc
+	case abiTuple:
+		return abiEncodeTuple(t, at.Components, v.([]any))
+	}
+	t.Fatalf("abiEncode: unsupported type %s", at.Canonical)
+	return nil
+}
+
+func abiWordOf(n *big.Int) []byte {
+	return n.FillBytes(make([]byte, 32))
+}
+
+func abiHexBytes(t testing.TB, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		t.Fatalf("abiEncode: bad hex %q", s)
+	}
+	return b
+}
+
+// abiWords concatenates raw 32-byte words given as integers, for hand-built
+// (usually malformed) encodings.
+func abiWords(words ...*big.Int) []byte {
+	var out []byte
+	for _, w := range words {
+		out = append(out, abiWordOf(w)...)
+	}
+	return out
+}
+
+// abiAliased encodes sig, taking one dynamic array of dynamic entries, with n
+// entries whose offsets all point at one shared element: a length word of size
+// followed by size bytes (bytes) or size zero words (arrays).
+func abiAliased(sig string, n, size int) string {
+	words := []*big.Int{big.NewInt(32), big.NewInt(int64(n))}
+	for i := 0; i < n; i++ {
+		words = append(words, big.NewInt(int64(n*32)))
+	}
+	words = append(words, big.NewInt(int64(size)))
+	body := (size + 31) / 32 * 32
+	if !strings.Contains(sig, "bytes") {
+		body = size * 32
+	}
+	data := append(abiWords(words...), make([]byte, body)...)
+	return keccakTopic(sig)[:10] + hex.EncodeToString(data)
+}
+
+func TestDecodeCalldata(t *testing.T) {
+	const addr = "0x1111111111111111111111111111111111111111"
+	huge := new(big.Int).Lsh(big.NewInt(1), 255)
+	maxWord := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	tests := []struct {
+		name    string
+		sig     string
+		input   string
+		strict  bool
+		want    string // formatted args, joined by "; "
+		wantErr bool
+	}{
+		{
+			name:  "elementary",
+			sig:   "f(uint8,int16,address,bool,bytes4)",
+			input: calldata(t, "f(uint8,int16,address,bool,bytes4)", "255", "-2", addr, true, "0xdeadbeef"),
+			want:  "255; -2; " + addr + "; true; 0xdeadbeef",
+		},
+		{
+			name:  "dynamic",
+			sig:   "f(bytes,string,uint256[])",
+			input: calldata(t, "f(bytes,string,uint256[])", "0x0102", "hi", []any{"1", "2", "3"}),
+			want:  `0x0102; "hi"; [1, 2, 3]`,
+		},
+		{
+			name: "nested tuples and arrays",
+			sig:  "f((uint256,address[])[],uint8[2][],(bytes,(bool,string)))",
+			input: calldata(t, "f((uint256,address[])[],uint8[2][],(bytes,(bool,string)))",
+				[]any{[]any{"7", []any{addr}}, []any{"8", []any{}}},
+				[]any{[]any{"1", "2"}, []any{"3", "4"}},
+				[]any{"0xff", []any{false, "x"}}),
+			want: `[(7, [` + addr + `]), (8, [])]; [[1, 2], [3, 4]]; (0xff, (false, "x"))`,
+		},
+		{
+			name:  "empty input for no arguments",
+			sig:   "f()",
+			input: keccakTopic("f()")[:10],
+			want:  "",
+		},
+		{
+			name:    "shorter than a selector",
+			sig:     "f(uint256)",
+			input:   "0x1234",
+			wantErr: true,
+		},
+		{
+			name:    "truncated word",
+			sig:     "f(uint256)",
+			input:   keccakTopic("f(uint256)")[:10] + "00",
+			wantErr: true,
+		},
+		{
+			name:    "offset overflow",
+			sig:     "f(bytes)",
+			input:   keccakTopic("f(bytes)")[:10] + hex.EncodeToString(abiWords(huge)),
+			wantErr: true,
+		},
+		{
+			name:    "length overflow",
+			sig:     "f(bytes)",
+			input:   keccakTopic("f(bytes)")[:10] + hex.EncodeToString(abiWords(big.NewInt(32), maxWord)),
+			wantErr: true,
+		},
+		{
+			name:    "length past end",
+			sig:     "f(string)",
+			input:   keccakTopic("f(string)")[:10] + hex.EncodeToString(abiWords(big.NewInt(32), big.NewInt(33), big.NewInt(0))),
+			wantErr: true,
+		},
+		{
+			name:    "array length overflow",
+			sig:     "f(uint256[])",
+			input:   keccakTopic("f(uint256[])")[:10] + hex.EncodeToString(abiWords(big.NewInt(32), huge)),
+			wantErr: true,
+		},
+		{
+			name:    "array length past end",
+			sig:     "f(uint256[])",
+			input:   keccakTopic("f(uint256[])")[:10] + hex.EncodeToString(abiWords(big.NewInt(32), big.NewInt(3), big.NewInt(1))),
+			wantErr: true,
+		},
+		{
+			name:    "static array too large",
+			sig:     "f(uint256[100000][100000])",
+			input:   keccakTopic("f(uint256[100000][100000])")[:10],
+			wantErr: true,
+		},
+		{
+			name:  "aliased entries within budget",
+			sig:   "f(bytes[])",
+			input: abiAliased("f(bytes[])", 2, 2),
+			want:  "[0x0000, 0x0000]",
+		},
+		{
+			name:    "aliased entries over the byte budget",
+			sig:     "f(bytes[])",
+			input:   abiAliased("f(bytes[])", 64, 32<<10),
+			wantErr: true,
+		},
+		{
+			name:    "aliased arrays over the value budget",
+			sig:     "f(uint256[][])",
+			input:   abiAliased("f(uint256[][])", 400, 300),
+			wantErr: true,
+		},
+		{
+			name:    "type nesting too deep",
+			sig:     "f(" + strings.Repeat("(", 20) + "uint256" + strings.Repeat(")", 20) + ")",
+			input:   "0x00000000",
+			wantErr: true,
+		},
+		{
+			name:   "strict accepts clean words",
+			sig:    "f(address,bool,uint8,bytes4)",
+			input:  calldata(t, "f(address,bool,uint8,bytes4)", addr, true, "255", "0x01020304"),
+			strict: true,
+			want:   addr + "; true; 255; 0x01020304",
+		},
+		{
+			name:    "strict rejects dirty address padding",
+			sig:     "f(address)",
+			input:   keccakTopic("f(address)")[:10] + hex.EncodeToString(abiWords(maxWord)),
+			strict:  true,
+			wantErr: true,
+		},
+		{
+			name:    "strict rejects non-0/1 bool",
+			sig:     "f(bool)",
+			input:   keccakTopic("f(bool)")[:10] + hex.EncodeToString(abiWords(big.NewInt(2))),
+			strict:  true,
+			wantErr: true,
+		},
+		{
+			name:    "strict rejects out of range uint8",
+			sig:     "f(uint8)",
+			input:   keccakTopic("f(uint8)")[:10] + hex.EncodeToString(abiWords(big.NewInt(256))),
+			strict:  true,
+			wantErr: true,
+		},
+		{
+			name:    "strict rejects out of range int8",
+			sig:     "f(int8)",
+			input:   calldata(t, "f(int16)", "-129"),
+			strict:  true,
+			wantErr: true,
+		},
+		{
+			name:    "strict rejects dirty bytes4 padding",
+			sig:     "f(bytes4)",
+			input:   calldata(t, "f(bytes5)", "0x0102030405"),
+			strict:  true,
+			wantErr: true,
+		},
+		{
+			name:  "lenient keeps dirty words",
+			sig:   "f(uint8,bool)",
+			input: keccakTopic("f(uint8,bool)")[:10] + hex.EncodeToString(abiWords(big.NewInt(256), big.NewInt(2))),
+			want:  "256; true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, err := decodeCall(tt.sig, tt.input, nil, tt.strict)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("decoded %v, want error", call.Args)
+				}
+				if !errors.Is(err, ErrABIDecode) {
+					t.Fatalf("error %v does not wrap ErrABIDecode", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeCall: %v", err)
+			}
+			parts := make([]string, len(call.Args))
+			for i, a := range call.Args {
+				parts[i] = formatABIValue(a)
+			}
+			if got := strings.Join(parts, "; "); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCalldataNames(t *testing.T) {
+	const to = "0x2222222222222222222222222222222222222222"
+	call, err := DecodeCalldata("transfer(address,uint256)", calldata(t, "transfer(address,uint256)", to, "1000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call.Selector != "0xa9059cbb" || call.Name != "transfer" {
+		t.Errorf("selector/name = %s/%s", call.Selector, call.Name)
+	}
+	if s, _ := call.argString("to"); s != to {
+		t.Errorf("to = %q", s)
+	}
+	if n, _ := call.argBig("amount"); n == nil || n.Int64() != 1000 {
+		t.Errorf("amount = %v", n)
+	}
+}
+
+func FuzzDecodeCalldata(f *testing.F) {
+	f.Add("transfer(address,uint256)", calldata(f, "transfer(address,uint256)", "0x1111111111111111111111111111111111111111", "1"))
+	f.Add("f((uint256,bytes)[],string)", calldata(f, "f((uint256,bytes)[],string)", []any{[]any{"1", "0x01"}}, "s"))
+	f.Add("f(bytes[])", abiAliased("f(bytes[])", 4, 64))
+	f.Add("f(uint256[][][])", keccakTopic("f(uint256[][][])")[:10]+hex.EncodeToString(abiWords(big.NewInt(32), big.NewInt(1), big.NewInt(32))))
+	f.Fuzz(func(t *testing.T, sig, input string) {
+		call, err := DecodeCalldata(sig, input)
+		if err != nil {
+			if !errors.Is(err, ErrABIDecode) {
+				t.Fatalf("error %v does not wrap ErrABIDecode", err)
+			}
+			return
+		}
+		if _, err := decodeCall(sig, input, nil, true); err == nil {
+			_ = formatABIValue(ABIValue{Type: "(", Value: call.Args})
+		}
+	})
+}
@@ -0,0 +1,262 @@
+// Package domain: this file is the consensus-timing component. It loads the
+// chain's SECONDS_PER_SLOT / SLOTS_PER_EPOCH from /eth/v1/config/spec and the
+// genesis time once, maps execution blocks to beacon slots (checked against the
+// beacon block's execution_payload.block_hash), and resolves each checkpoint to
+// the slot of its actual root block, so finality is right on any network.
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/you/eth-tx-lifecycle-backend/internal/clients/beacon"
+)
+
+// ConsensusSpec is the subset of the beacon config this backend needs.
+type ConsensusSpec struct {
+	SecondsPerSlot uint64 `json:"secondsPerSlot"`
+	SlotsPerEpoch  uint64 `json:"slotsPerEpoch"`
+	GenesisTime    uint64 `json:"genesisTime"`
+}
+
+// BlockSlot is an execution block's beacon slot. Verified is true when the beacon
+// block at Slot carries this block hash in its execution payload.
+type BlockSlot struct {
+	Slot     uint64 `json:"slot"`
+	Verified bool   `json:"verified"`
+}
+
+// consensusCacheMax bounds the block-hash and checkpoint-root caches.
+const consensusCacheMax = 4096
+
+var (
+	consensusMu   sync.Mutex
+	consensusSpec *ConsensusSpec
+
+	slotCacheMu    sync.RWMutex
+	blockSlotCache = map[string]BlockSlot{}
+	rootSlotCache  = map[string]uint64{}
+)
+
+// loadConsensusSpec returns the spec, fetching it on first use. Failures aren't
+// cached, so a beacon node that was down at startup is retried on the next call.
+// The beacon requests run outside consensusMu so a slow node doesn't block
+// callers; concurrent first loads may fetch twice and the first stored wins.
+func loadConsensusSpec() (*ConsensusSpec, error) {
+	consensusMu.Lock()
+	spec := consensusSpec
+	consensusMu.Unlock()
+	if spec != nil {
+		return spec, nil
+	}
+	spec, err := fetchConsensusSpec()
+	if err != nil {
+		return nil, err
+	}
+	consensusMu.Lock()
+	defer consensusMu.Unlock()
+	if consensusSpec == nil {
+		consensusSpec = spec
+	}
+	return consensusSpec, nil
+}
+
+// fetchConsensusSpec reads the slot timing from config/spec and the genesis time.
+func fetchConsensusSpec() (*ConsensusSpec, error) {
+	raw, status, err := beacon.Get("/eth/v1/config/spec")
+	if err != nil {
+		return nil, err
+	}
+	if status/100 != 2 {
+		return nil, fmt.Errorf("config/spec: HTTP %d", status)
+	}
+	var spec struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, err
+	}
+	sps, err1 := strconv.ParseUint(fmt.Sprint(spec.Data["SECONDS_PER_SLOT"]), 10, 64)
+	spe, err2 := strconv.ParseUint(fmt.Sprint(spec.Data["SLOTS_PER_EPOCH"]), 10, 64)
+	if err1 != nil || err2 != nil || sps == 0 || spe == 0 {
+		return nil, fmt.Errorf("config/spec: missing SECONDS_PER_SLOT or SLOTS_PER_EPOCH")
+	}
+	raw, status, err = beacon.Get("/eth/v1/beacon/genesis")
+	if err != nil {
+		return nil, err
+	}
+	if status/100 != 2 {
+		return nil, fmt.Errorf("genesis: HTTP %d", status)
+	}
+	var genesis struct {
+		Data struct {
+			GenesisTime string `json:"genesis_time"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &genesis); err != nil {
+		return nil, err
+	}
+	g, err := strconv.ParseUint(genesis.Data.GenesisTime, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("genesis: %w", err)
+	}
+	return &ConsensusSpec{SecondsPerSlot: sps, SlotsPerEpoch: spe, GenesisTime: g}, nil
+}
+
+// slotDuration is the slot time, or 12s until the spec has loaded.
+func slotDuration() time.Duration {
+	if spec, err := loadConsensusSpec(); err == nil {
+		return time.Duration(spec.SecondsPerSlot) * time.Second
+	}
+	return 12 * time.Second
+}
+
+// slotAt maps a block timestamp to its slot. Post-merge every block's timestamp
+// is exactly genesis + slot*SECONDS_PER_SLOT.
+func slotAt(ts uint64) (uint64, bool) {
+	spec, err := loadConsensusSpec()
+	if err != nil || ts < spec.GenesisTime {
+		return 0, false
+	}
+	return (ts - spec.GenesisTime) / spec.SecondsPerSlot, true
+}
+
+// epochStartSlot is the first slot of epoch.
+func epochStartSlot(epoch uint64) (uint64, bool) {
+	spec, err := loadConsensusSpec()
+	if err != nil {
+		return 0, false
+	}
+	return epoch * spec.SlotsPerEpoch, true
+}
+
+// payloadBlockHash returns the execution block hash carried by the beacon block at
+// slot, trying the small blinded form first. ok is false for empty slots or errors.
+func payloadBlockHash(slot uint64) (string, bool) {
+	s := strconv.FormatUint(slot, 10)
+	if raw, status, err := beacon.Get("/eth/v1/beacon/blinded_blocks/" + s); err == nil && status/100 == 2 {
+		var b struct {
+			Data struct {
+				Message struct {
+					Body struct {
+						Header struct {
+							BlockHash string `json:"block_hash"`
+						} `json:"execution_payload_header"`
+					} `json:"body"`
+				} `json:"message"`
+			} `json:"data"`
+		}
+		if json.Unmarshal(raw, &b) == nil && b.Data.Message.Body.Header.BlockHash != "" {
+			return b.Data.Message.Body.Header.BlockHash, true
+		}
+	}
+	raw, status, err := beacon.Get("/eth/v2/beacon/blocks/" + s)
+	if err != nil || status/100 != 2 {
+		return "", false
+	}
+	var b struct {
+		Data struct {
+			Message struct {
+				Body struct {
+					Payload struct {
+						BlockHash string `json:"block_hash"`
+					} `json:"execution_payload"`
+				} `json:"body"`
+			} `json:"message"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(raw, &b) != nil || b.Data.Message.Body.Payload.BlockHash == "" {
+		return "", false
+	}
+	return b.Data.Message.Body.Payload.BlockHash, true
+}
+
+// blockSlotFor maps an execution block (hash + timestamp) to its slot and checks
+// the mapping against the beacon chain. Verified results are cached by hash.
+func blockSlotFor(blockHash string, ts uint64) (BlockSlot, bool) {
+	key := strings.ToLower(blockHash)
+	slotCacheMu.RLock()
+	bs, ok := blockSlotCache[key]
+	slotCacheMu.RUnlock()
+	if ok {
+		return bs, true
+	}
+	slot, ok := slotAt(ts)
+	if !ok {
+		return BlockSlot{}, false
+	}
+	bs = BlockSlot{Slot: slot}
+	if h, ok := payloadBlockHash(slot); ok && strings.EqualFold(h, blockHash) {
+		bs.Verified = true
+		slotCacheMu.Lock()
+		if len(blockSlotCache) >= consensusCacheMax {
+			clear(blockSlotCache)
+		}
+		blockSlotCache[key] = bs
+		slotCacheMu.Unlock()
+	}
+	return bs, true
+}
+
+// checkpointSlot is the slot of the checkpoint's root block. When the epoch's
+// first slot was empty the root is an earlier block, so this can be below the
+// epoch start; blocks at or before it are ancestors of the checkpoint. Falls
+// back to the epoch start slot if the root can't be looked up.
+func checkpointSlot(cp Checkpoint) (uint64, bool) {
+	epoch, err := strconv.ParseUint(cp.Epoch, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	start, ok := epochStartSlot(epoch)
+	if !ok {
+		return 0, false
+	}
+	root := strings.ToLower(cp.Root)
+	if root == "" || strings.TrimLeft(strings.TrimPrefix(root, "0x"), "0") == "" {
+		return start, true
+	}
+	slotCacheMu.RLock()
+	s, ok := rootSlotCache[root]
+	slotCacheMu.RUnlock()
+	if ok {
+		return s, true
+	}
+	raw, status, err := beacon.Get("/eth/v1/beacon/headers/" + root)
+	if err != nil || status/100 != 2 {
+		return start, true
+	}
+	var h struct {
+		Data struct {
+			Header struct {
+				Message struct {
+					Slot string `json:"slot"`
+				} `json:"message"`
+			} `json:"header"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(raw, &h) != nil {
+		return start, true
+	}
+	s, err = strconv.ParseUint(h.Data.Header.Message.Slot, 10, 64)
+	if err != nil {
+		return start, true
+	}
+	slotCacheMu.Lock()
+	if len(rootSlotCache) >= consensusCacheMax {
+		clear(rootSlotCache)
+	}
+	rootSlotCache[root] = s
+	slotCacheMu.Unlock()
+	return s, true
+}
+
+// checkpointCovers reports whether the block at slot is at or before cp's root,
+// i.e. justified/finalized along with it (assuming it is canonical).
+func checkpointCovers(cp Checkpoint, slot uint64) bool {
+	cs, ok := checkpointSlot(cp)
+	return ok && slot <= cs
+}
@@ -0,0 +1,130 @@
+// Package domain: this file breaks a mined transaction's fee down into where
+// each wei went: base fee burned, priority tip to the fee recipient, blob fee
+// burned (type-3), the user's total cost, and how the tip compares with the
+// builder's relay-reported payment to the proposer.
+package domain
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+)
+
+// Amount is a wei value with its exact ETH rendering.
+type Amount struct {
+	Wei string `json:"wei"`
+	ETH string `json:"eth"`
+}
+
+// TxCost is the fee breakdown in TrackEconomics.Cost. The tip is what the fee
+// recipient (the builder under PBS) earns; burned amounts leave supply. BuilderPayment
+// is the value paid to the proposer per the relay bidtrace for the including block
+// hash, and TipShareOfBuilderPayment is Tip / BuilderPayment: how much of that
+// payment this one tx's tip would cover.
+type TxCost struct {
+	GasUsed                  uint64   `json:"gas_used"`
+	BaseFeePerGasGwei        float64  `json:"base_fee_per_gas_gwei"`
+	PriorityFeePerGasGwei    float64  `json:"priority_fee_per_gas_gwei"`
+	BaseFeeBurned            Amount   `json:"base_fee_burned"`
+	Tip                      Amount   `json:"priority_tip"`
+	ExecutionFee             Amount   `json:"execution_fee"`
+	BlobGasUsed              uint64   `json:"blob_gas_used,omitempty"`
+	BlobGasPriceGwei         float64  `json:"blob_gas_price_gwei,omitempty"`
+	BlobFeeBurned            *Amount  `json:"blob_fee_burned,omitempty"`
+	TotalBurned              Amount   `json:"total_burned"`
+	TotalFee                 Amount   `json:"total_fee"`
+	Value                    Amount   `json:"value"`
+	TotalCost                Amount   `json:"total_cost"`
+	BuilderPayment           *Amount  `json:"builder_payment,omitempty"`
+	TipShareOfBuilderPayment *float64 `json:"tip_share_of_builder_payment,omitempty"`
+}
+
+// newAmount renders wei exactly; nil is treated as zero.
+func newAmount(wei *big.Int) Amount {
+	if wei == nil {
+		wei = new(big.Int)
+	}
+	return Amount{Wei: wei.String(), ETH: formatUnits(wei, 18)}
+}
+
+// formatUnits renders v / 10^decimals without rounding, trimming trailing zeros.
+func formatUnits(v *big.Int, decimals int) string {
+	s := new(big.Int).Abs(v).String()
+	if len(s) <= decimals {
+		s = strings.Repeat("0", decimals-len(s)+1) + s
+	}
+	whole, frac := s[:len(s)-decimals], strings.TrimRight(s[len(s)-decimals:], "0")
+	if v.Sign() < 0 {
+		whole = "-" + whole
+	}
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+// txCost computes the breakdown from the receipt fields in e, the including
+// block's base fee (nil before London) and the relay bidtrace (nil if none).
+// It returns nil when the receipt's gas used or effective gas price is missing,
+// or when the base fee exceeds the effective gas price (a mismatched block).
+func txCost(e *TrackEconomics, baseFee *big.Int, relay *TrackRelay) *TxCost {
+	gasUsed, err := config.ParseHexUint64(e.GasUsed)
+	price, ok := config.ParseHexBigInt(e.EffectiveGasPrice)
+	if err != nil || !ok {
+		return nil
+	}
+	if baseFee == nil {
+		baseFee = new(big.Int)
+	} else if baseFee.Cmp(price) > 0 {
+		return nil // no tx pays less than its block's base fee: the inputs disagree
+	}
+	gas := new(big.Int).SetUint64(gasUsed)
+	tipPerGas := new(big.Int).Sub(price, baseFee)
+	burned := new(big.Int).Mul(baseFee, gas)
+	tip := new(big.Int).Mul(tipPerGas, gas)
+	execFee := new(big.Int).Mul(price, gas)
+	value, ok := config.ParseHexBigInt(e.Value)
+	if !ok {
+		value = new(big.Int)
+	}
+
+	c := &TxCost{
+		GasUsed:               gasUsed,
+		BaseFeePerGasGwei:     weiToGwei(baseFee),
+		PriorityFeePerGasGwei: weiToGwei(tipPerGas),
+		BaseFeeBurned:         newAmount(burned),
+		Tip:                   newAmount(tip),
+		ExecutionFee:          newAmount(execFee),
+		Value:                 newAmount(value),
+	}
+	totalBurned := new(big.Int).Set(burned)
+	totalFee := new(big.Int).Set(execFee)
+	if e.BlobGasUsed != "" && e.BlobGasPrice != "" {
+		blobGas, err := config.ParseHexUint64(e.BlobGasUsed)
+		blobPrice, ok := config.ParseHexBigInt(e.BlobGasPrice)
+		if err == nil && ok {
+			blobFee := new(big.Int).Mul(new(big.Int).SetUint64(blobGas), blobPrice)
+			a := newAmount(blobFee)
+			c.BlobGasUsed = blobGas
+			c.BlobGasPriceGwei = weiToGwei(blobPrice)
+			c.BlobFeeBurned = &a
+			totalBurned.Add(totalBurned, blobFee)
+			totalFee.Add(totalFee, blobFee)
+		}
+	}
+	c.TotalBurned = newAmount(totalBurned)
+	c.TotalFee = newAmount(totalFee)
+	c.TotalCost = newAmount(new(big.Int).Add(totalFee, value))
+
+	// Relay bidtrace values are decimal wei.
+	if relay != nil {
+		if pay, ok := new(big.Int).SetString(relay.Value, 10); ok && pay.Sign() > 0 {
+			a := newAmount(pay)
+			c.BuilderPayment = &a
+			share, _ := new(big.Float).Quo(new(big.Float).SetInt(tip), new(big.Float).SetInt(pay)).Float64()
+			c.TipShareOfBuilderPayment = &share
+		}
+	}
+	return c
+}
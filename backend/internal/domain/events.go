@@ -0,0 +1,175 @@
+// Package domain: this file is the in-process event bus behind /api/stream. The
+// mempool store, head follower and finality watcher publish here; each SSE client
+// holds a buffered subscription filtered to the topics it asked for.
+package domain
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event topics accepted by SubscribeEvents.
+const (
+	TopicMempool  = "mempool"
+	TopicHeads    = "heads"
+	TopicFinality = "finality"
+)
+
+// EventTopics lists every topic, in the order clients see them documented.
+var EventTopics = []string{TopicMempool, TopicHeads, TopicFinality}
+
+// Event is one pushed update. ID increases monotonically across all topics.
+// Dropped counts the events this subscriber missed (its buffer was full) since
+// the previous one it received; a client applying mempool deltas should
+// re-fetch /api/mempool when it is non-zero.
+type Event struct {
+	ID      uint64 `json:"id"`
+	Topic   string `json:"topic"`
+	Type    string `json:"type"`
+	Time    int64  `json:"time"`
+	Data    any    `json:"data"`
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+// MempoolDelta is the payload of mempool "delta" events. Truncated counts the
+// adds and removals left out past maxDeltaHashes; when it is non-zero the
+// delta is incomplete and clients should re-fetch /api/mempool.
+type MempoolDelta struct {
+	Added     []string         `json:"added"`
+	Removed   []MempoolRemoval `json:"removed"`
+	Count     int              `json:"count"`
+	Truncated int              `json:"truncated,omitempty"`
+}
+
+// MempoolRemoval says why a hash left the pending set (a history status).
+type MempoolRemoval struct {
+	Hash   string `json:"hash"`
+	Reason string `json:"reason"`
+}
+
+// eventSub is one subscriber; events that don't fit in ch are dropped and
+// counted in dropped until the next delivered event reports them.
+type eventSub struct {
+	ch      chan Event
+	topics  map[string]bool
+	dropped atomic.Uint64
+}
+
+var (
+	eventMu   sync.RWMutex
+	eventSubs = map[*eventSub]struct{}{}
+	eventSeq  atomic.Uint64
+
+	// deltaMu guards the pending mempool delta flushed by flushMempoolDeltas.
+	deltaMu        sync.Mutex
+	deltaAdded     []string
+	deltaRemoved   []MempoolRemoval
+	deltaTruncated int
+)
+
+func init() { onHead(publishHead) }
+
+// HeadEvent is the payload of heads "new_head" events.
+type HeadEvent struct {
+	Number      uint64  `json:"number"`
+	Hash        string  `json:"hash"`
+	Timestamp   uint64  `json:"timestamp"`
+	TxCount     int     `json:"txCount"`
+	BaseFeeGwei float64 `json:"baseFeeGwei"`
+}
+
+func publishHead(b *headBlock) {
+	ev := HeadEvent{Number: b.Number, Hash: b.Hash, Timestamp: b.Timestamp, TxCount: len(b.Transactions)}
+	if b.BaseFeePerGas != nil {
+		ev.BaseFeeGwei = weiToGwei(b.BaseFeePerGas)
+	}
+	publishEvent(TopicHeads, "new_head", ev)
+}
+
+// maxDeltaHashes caps one delta event so a burst can't produce a multi-MB frame.
+const maxDeltaHashes = 2000
+
+// SubscribeEvents registers a subscriber for topics (all topics if empty). The
+// returned cancel func must be called to release it.
+func SubscribeEvents(topics []string, buffer int) (<-chan Event, func()) {
+	sub := &eventSub{ch: make(chan Event, buffer), topics: map[string]bool{}}
+	for _, t := range topics {
+		sub.topics[t] = true
+	}
+	eventMu.Lock()
+	eventSubs[sub] = struct{}{}
+	eventMu.Unlock()
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			eventMu.Lock()
+			delete(eventSubs, sub)
+			eventMu.Unlock()
+		})
+	}
+}
+
+// publishEvent fans out to matching subscribers without blocking the publisher.
+func publishEvent(topic, typ string, data any) {
+	ev := Event{ID: eventSeq.Add(1), Topic: topic, Type: typ, Time: time.Now().Unix(), Data: data}
+	eventMu.RLock()
+	defer eventMu.RUnlock()
+	for sub := range eventSubs {
+		if len(sub.topics) > 0 && !sub.topics[topic] {
+			continue
+		}
+		n := sub.dropped.Swap(0)
+		e := ev
+		e.Dropped = n
+		select {
+		case sub.ch <- e:
+		default:
+			sub.dropped.Add(n + 1)
+		}
+	}
+}
+
+// noteMempoolAdded / noteMempoolRemoved queue hashes for the next delta event.
+func noteMempoolAdded(hash string) {
+	deltaMu.Lock()
+	if len(deltaAdded) < maxDeltaHashes {
+		deltaAdded = append(deltaAdded, hash)
+	} else {
+		deltaTruncated++
+	}
+	deltaMu.Unlock()
+}
+
+func noteMempoolRemoved(hash, reason string) {
+	deltaMu.Lock()
+	if len(deltaRemoved) < maxDeltaHashes {
+		deltaRemoved = append(deltaRemoved, MempoolRemoval{Hash: hash, Reason: reason})
+	} else {
+		deltaTruncated++
+	}
+	deltaMu.Unlock()
+}
+
+// flushMempoolDeltas publishes accumulated adds/removals once a second, so the
+// WebSocket firehose becomes at most one mempool event per second per client.
+func flushMempoolDeltas() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		deltaMu.Lock()
+		added, removed, truncated := deltaAdded, deltaRemoved, deltaTruncated
+		deltaAdded, deltaRemoved, deltaTruncated = nil, nil, 0
+		deltaMu.Unlock()
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		if added == nil {
+			added = []string{}
+		}
+		if removed == nil {
+			removed = []MempoolRemoval{}
+		}
+		publishEvent(TopicMempool, "delta", MempoolDelta{Added: added, Removed: removed, Count: GetData().Count, Truncated: truncated})
+	}
+}
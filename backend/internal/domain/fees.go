@@ -0,0 +1,340 @@
+// Package domain: this file computes the mempool's priority-fee distribution and
+// a fee estimator that combines the pending set with recent blocks' base fee trend
+// (eth_feeHistory) to suggest the tip needed to land within 1, 3 or 10 blocks.
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+	"github.com/you/eth-tx-lifecycle-backend/internal/clients/eth"
+	"github.com/you/eth-tx-lifecycle-backend/internal/pkg"
+)
+
+// FeePercentiles are effective priority fees in gwei.
+type FeePercentiles struct {
+	P10 float64 `json:"p10"`
+	P25 float64 `json:"p25"`
+	P50 float64 `json:"p50"`
+	P75 float64 `json:"p75"`
+	P90 float64 `json:"p90"`
+}
+
+// FeeBucket is one histogram bin of effective priority fee. MaxGwei 0 means open-ended.
+type FeeBucket struct {
+	MinGwei float64 `json:"minGwei"`
+	MaxGwei float64 `json:"maxGwei,omitempty"`
+	Count   int     `json:"count"`
+}
+
+// feeBucketEdges are histogram bin lower bounds in gwei; the last bin is open-ended.
+var feeBucketEdges = []float64{0, 0.1, 0.5, 1, 2, 5, 10, 50}
+
+// BlockFeeStats summarizes one recent block from eth_feeHistory.
+type BlockFeeStats struct {
+	Number        uint64  `json:"number"`
+	BaseFeeGwei   float64 `json:"baseFeeGwei"`
+	GasUsedRatio  float64 `json:"gasUsedRatio"`
+	FloorTipGwei  float64 `json:"floorTipGwei"`
+	P25TipGwei    float64 `json:"p25TipGwei"`
+	MedianTipGwei float64 `json:"medianTipGwei"`
+}
+
+// FeeSuggestion is the recommended bid to be included within Blocks blocks.
+type FeeSuggestion struct {
+	Blocks            int     `json:"blocks"`
+	PriorityFeeGwei   float64 `json:"priorityFeeGwei"`
+	MaxFeePerGasGwei  float64 `json:"maxFeePerGasGwei"`
+	Probability       float64 `json:"probability"`
+	PendingAheadCount int     `json:"pendingAheadCount"`
+}
+
+// TipProbability estimates inclusion odds for a caller-supplied tip.
+type TipProbability struct {
+	TipGwei  float64 `json:"tipGwei"`
+	Within1  float64 `json:"within1"`
+	Within3  float64 `json:"within3"`
+	Within10 float64 `json:"within10"`
+}
+
+// FeeEstimate is the /api/fees/estimate response.
+type FeeEstimate struct {
+	BaseFeeGwei     float64         `json:"baseFeeGwei"`
+	NextBaseFeeGwei float64         `json:"nextBaseFeeGwei"`
+	BaseFeeTrend    string          `json:"baseFeeTrend"`
+	PendingCount    int             `json:"pendingCount"`
+	Percentiles     *FeePercentiles `json:"percentiles,omitempty"`
+	Suggestions     []FeeSuggestion `json:"suggestions"`
+	Query           *TipProbability `json:"query,omitempty"`
+	RecentBlocks    []BlockFeeStats `json:"recentBlocks"`
+	Method          string          `json:"method"`
+}
+
+// feeTargets are the inclusion horizons (in blocks) the estimator answers for.
+var feeTargets = []int{1, 3, 10}
+
+// feeHistoryBlocks is how many recent blocks eth_feeHistory is asked for.
+const feeHistoryBlocks = 20
+
+var feeHistoryCache *pkg.Cache[feeHistory]
+
+func init() {
+	feeHistoryCache = pkg.NewCache[feeHistory](6*time.Second, 0)
+}
+
+type feeHistory struct {
+	oldest   uint64
+	baseFees []*big.Int // len(ratios)+1; last entry is the next block's base fee
+	ratios   []float64
+	rewards  [][]*big.Int // per block: p10, p25, p50 tips
+}
+
+func fetchFeeHistory() (feeHistory, error) {
+	if h, ok := feeHistoryCache.Get("latest"); ok {
+		return h, nil
+	}
+	raw, err := eth.Call("eth_feeHistory", []any{"0x" + strconv.FormatUint(feeHistoryBlocks, 16), "latest", []float64{10, 25, 50}})
+	if err != nil {
+		return feeHistory{}, err
+	}
+	var r struct {
+		OldestBlock   string     `json:"oldestBlock"`
+		BaseFeePerGas []string   `json:"baseFeePerGas"`
+		GasUsedRatio  []float64  `json:"gasUsedRatio"`
+		Reward        [][]string `json:"reward"`
+	}
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return feeHistory{}, err
+	}
+	if len(r.GasUsedRatio) == 0 || len(r.BaseFeePerGas) != len(r.GasUsedRatio)+1 {
+		return feeHistory{}, errors.New("eth_feeHistory returned no blocks")
+	}
+	h := feeHistory{ratios: r.GasUsedRatio}
+	h.oldest, _ = config.ParseHexUint64(r.OldestBlock)
+	for _, b := range r.BaseFeePerGas {
+		h.baseFees = append(h.baseFees, hexBig(&b))
+	}
+	for _, row := range r.Reward {
+		tips := make([]*big.Int, 0, len(row))
+		for _, t := range row {
+			tips = append(tips, hexBig(&t))
+		}
+		h.rewards = append(h.rewards, tips)
+	}
+	for _, b := range h.baseFees {
+		if b == nil {
+			return feeHistory{}, errors.New("eth_feeHistory returned malformed base fees")
+		}
+	}
+	feeHistoryCache.Set("latest", h, false)
+	return h, nil
+}
+
+// effectivePriorityFee is the tip per gas tx would pay the block builder at
+// baseFee; negative when the fee cap is below baseFee (not includable yet).
+// With no base fee known it returns the bid tip (or gasPrice for legacy txs).
+func effectivePriorityFee(tx PendingTx, baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return pendingTxTip(tx)
+	}
+	p := effectiveGasPrice(tx, baseFee)
+	if p == nil {
+		return nil
+	}
+	return new(big.Int).Sub(p, baseFee)
+}
+
+// percentile returns the p-th percentile (0..100, nearest-rank) of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+func feePercentiles(sorted []float64) *FeePercentiles {
+	if len(sorted) == 0 {
+		return nil
+	}
+	return &FeePercentiles{
+		P10: percentile(sorted, 10), P25: percentile(sorted, 25), P50: percentile(sorted, 50),
+		P75: percentile(sorted, 75), P90: percentile(sorted, 90),
+	}
+}
+
+func feeHistogram(tipsGwei []float64) []FeeBucket {
+	buckets := make([]FeeBucket, len(feeBucketEdges))
+	for i, lo := range feeBucketEdges {
+		buckets[i].MinGwei = lo
+		if i+1 < len(feeBucketEdges) {
+			buckets[i].MaxGwei = feeBucketEdges[i+1]
+		}
+	}
+	for _, t := range tipsGwei {
+		i := sort.Search(len(feeBucketEdges), func(i int) bool { return feeBucketEdges[i] > t }) - 1
+		if i < 0 {
+			i = 0
+		}
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// pendingTip is one includable pending tx's effective tip and gas for cutoff math.
+type pendingTip struct {
+	tipGwei float64
+	gas     uint64
+}
+
+// includableTips returns pending txs payable at baseFee, highest tip first.
+func includableTips(txs []PendingTx, baseFee *big.Int) []pendingTip {
+	out := make([]pendingTip, 0, len(txs))
+	for _, tx := range txs {
+		tip := effectivePriorityFee(tx, baseFee)
+		if tip == nil || tip.Sign() < 0 {
+			continue
+		}
+		var gas uint64 = 21000
+		if tx.Gas != nil {
+			if g, err := config.ParseHexUint64(*tx.Gas); err == nil {
+				gas = g
+			}
+		}
+		out = append(out, pendingTip{tipGwei: weiToGwei(tip), gas: gas})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].tipGwei > out[j].tipGwei })
+	return out
+}
+
+// blockInclusionProbability is the fraction of recent blocks whose floor (p10)
+// tip was at or below tipGwei, i.e. the chance one block would have taken it.
+func blockInclusionProbability(blocks []BlockFeeStats, tipGwei float64) float64 {
+	if len(blocks) == 0 {
+		return 0
+	}
+	n := 0
+	for _, b := range blocks {
+		if b.FloorTipGwei <= tipGwei {
+			n++
+		}
+	}
+	return float64(n) / float64(len(blocks))
+}
+
+// withinBlocks turns a per-block probability into P(included within k blocks),
+// treating blocks as independent trials.
+func withinBlocks(p float64, k int) float64 {
+	return round4(1 - math.Pow(1-p, float64(k)))
+}
+
+func round4(f float64) float64 { return math.Round(f*1e4) / 1e4 }
+
+// EstimateFees builds the fee suggestion for the next 1/3/10 blocks. queryTipGwei
+// (< 0 to skip) additionally reports inclusion odds for that tip.
+func EstimateFees(queryTipGwei float64) (*FeeEstimate, error) {
+	h, err := fetchFeeHistory()
+	if err != nil {
+		return nil, err
+	}
+	n := len(h.ratios)
+	out := &FeeEstimate{
+		BaseFeeGwei:     weiToGwei(h.baseFees[n-1]),
+		NextBaseFeeGwei: weiToGwei(h.baseFees[n]),
+		Suggestions:     []FeeSuggestion{},
+		Method:          "tip: max(pending-queue cutoff for k blocks of capacity, recent blocks' included-tip floor); maxFee: base fee projected at +12.5%/block worst case plus tip",
+	}
+	var ratioSum float64
+	for i := 0; i < n; i++ {
+		st := BlockFeeStats{Number: h.oldest + uint64(i), BaseFeeGwei: weiToGwei(h.baseFees[i]), GasUsedRatio: h.ratios[i]}
+		if i < len(h.rewards) && len(h.rewards[i]) == 3 && h.rewards[i][0] != nil && h.rewards[i][1] != nil && h.rewards[i][2] != nil {
+			st.FloorTipGwei = weiToGwei(h.rewards[i][0])
+			st.P25TipGwei = weiToGwei(h.rewards[i][1])
+			st.MedianTipGwei = weiToGwei(h.rewards[i][2])
+		}
+		ratioSum += h.ratios[i]
+		out.RecentBlocks = append(out.RecentBlocks, st)
+	}
+	first, next := weiToGwei(h.baseFees[0]), out.NextBaseFeeGwei
+	switch {
+	case first > 0 && next > first*1.05:
+		out.BaseFeeTrend = "rising"
+	case first > 0 && next < first*0.95:
+		out.BaseFeeTrend = "falling"
+	default:
+		out.BaseFeeTrend = "flat"
+	}
+
+	pending := GetData().PendingTxs
+	tips := includableTips(pending, h.baseFees[n])
+	out.PendingCount = len(tips)
+	tipValues := make([]float64, len(tips))
+	for i := range tips {
+		tipValues[len(tips)-1-i] = tips[i].tipGwei
+	}
+	out.Percentiles = feePercentiles(tipValues)
+
+	floors := make([]float64, 0, n)
+	for _, b := range out.RecentBlocks {
+		floors = append(floors, b.FloorTipGwei)
+	}
+	sort.Float64s(floors)
+	capacity := uint64(float64(currentGasLimit()) * ratioSum / float64(n))
+
+	for _, k := range feeTargets {
+		// Historical floor: faster targets need to beat more of the recent floors.
+		var hist float64
+		switch k {
+		case 1:
+			hist = percentile(floors, 75)
+		case 3:
+			hist = percentile(floors, 50)
+		default:
+			hist = percentile(floors, 10)
+		}
+		// Queue cutoff: pending txs fill blocks in tip order, so to land within
+		// k blocks the suggestion must outbid the first tx that doesn't fit in
+		// them. ahead counts the txs that do fit.
+		var queue float64
+		ahead := 0
+		if capacity > 0 {
+			var used uint64
+			for _, t := range tips {
+				used += t.gas
+				if used > capacity*uint64(k) {
+					queue = t.tipGwei
+					break
+				}
+				ahead++
+			}
+		}
+		tip := math.Max(hist, queue)
+		maxBase := next * math.Pow(1.125, float64(k-1))
+		out.Suggestions = append(out.Suggestions, FeeSuggestion{
+			Blocks:            k,
+			PriorityFeeGwei:   round4(tip),
+			MaxFeePerGasGwei:  round4(maxBase + tip),
+			Probability:       withinBlocks(blockInclusionProbability(out.RecentBlocks, tip), k),
+			PendingAheadCount: ahead,
+		})
+	}
+	if queryTipGwei >= 0 {
+		p := blockInclusionProbability(out.RecentBlocks, queryTipGwei)
+		out.Query = &TipProbability{
+			TipGwei: queryTipGwei, Within1: withinBlocks(p, 1), Within3: withinBlocks(p, 3), Within10: withinBlocks(p, 10),
+		}
+	}
+	return out, nil
+}
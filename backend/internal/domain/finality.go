@@ -0,0 +1,144 @@
+// Package domain: this file watches the beacon chain's finality checkpoints,
+// publishes a finality event whenever the justified or finalized checkpoint moves,
+// and remembers when each checkpoint was first seen so block latencies can be timed.
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/you/eth-tx-lifecycle-backend/internal/clients/beacon"
+)
+
+// Checkpoint is a Casper-FFG checkpoint as reported by the beacon API.
+type Checkpoint struct {
+	Epoch string `json:"epoch"`
+	Root  string `json:"root"`
+}
+
+// FinalityCheckpoints mirrors /eth/v1/beacon/states/head/finality_checkpoints data.
+type FinalityCheckpoints struct {
+	PreviousJustified Checkpoint `json:"previous_justified"`
+	CurrentJustified  Checkpoint `json:"current_justified"`
+	Finalized         Checkpoint `json:"finalized"`
+}
+
+// checkpointSeen records when a justified/finalized checkpoint was first observed
+// and the slot of its root block (see checkpointSlot). Initial marks the value
+// found on the first poll: it was reached at some unknown time before the
+// backend started, so it can't be used for latencies.
+type checkpointSeen struct {
+	Epoch   uint64
+	Slot    uint64
+	At      int64
+	Initial bool
+}
+
+// maxCheckpointSeen bounds the observation lists (~4.5 days of epochs).
+const maxCheckpointSeen = 1024
+
+var (
+	finalityMu     sync.RWMutex
+	lastCheckpoint *FinalityCheckpoints
+	justifiedSeen  []checkpointSeen
+	finalizedSeen  []checkpointSeen
+)
+
+// fetchFinalityCheckpoints reads the head state's checkpoints (beacon.Get caches).
+func fetchFinalityCheckpoints() (*FinalityCheckpoints, error) {
+	raw, status, err := beacon.Get("/eth/v1/beacon/states/head/finality_checkpoints")
+	if err != nil {
+		return nil, err
+	}
+	if status/100 != 2 {
+		return nil, fmt.Errorf("finality_checkpoints: HTTP %d", status)
+	}
+	var resp struct {
+		Data FinalityCheckpoints `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Data, nil
+}
+
+// currentCheckpoints returns the last checkpoints seen by followFinality (nil before the first read).
+func currentCheckpoints() *FinalityCheckpoints {
+	finalityMu.RLock()
+	defer finalityMu.RUnlock()
+	return lastCheckpoint
+}
+
+// followFinality polls once per slot; beacon.Get's cache keeps the real request
+// rate at the configured CACHE_TTL_SECONDS. Checkpoint root slots are resolved
+// before taking finalityMu since they may hit the beacon API.
+func followFinality() {
+	ticker := time.NewTicker(slotDuration())
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		cp, err := fetchFinalityCheckpoints()
+		if err != nil || cp.Finalized.Epoch == "" {
+			continue
+		}
+		jSlot, jOK := checkpointSlot(cp.CurrentJustified)
+		fSlot, fOK := checkpointSlot(cp.Finalized)
+		if !jOK || !fOK {
+			continue
+		}
+		now := time.Now().Unix()
+		finalityMu.Lock()
+		prev := lastCheckpoint
+		lastCheckpoint = cp
+		justifiedSeen = noteCheckpoint(justifiedSeen, cp.CurrentJustified.Epoch, jSlot, now, prev == nil)
+		finalizedSeen = noteCheckpoint(finalizedSeen, cp.Finalized.Epoch, fSlot, now, prev == nil)
+		finalityMu.Unlock()
+		if prev == nil || *prev != *cp {
+			publishEvent(TopicFinality, "checkpoints", cp)
+		}
+	}
+}
+
+// noteCheckpoint appends epoch to seen if it advanced past the last entry. Caller holds finalityMu.
+func noteCheckpoint(seen []checkpointSeen, epochStr string, slot uint64, now int64, initial bool) []checkpointSeen {
+	epoch, err := strconv.ParseUint(epochStr, 10, 64)
+	if err != nil || (len(seen) > 0 && epoch <= seen[len(seen)-1].Epoch) {
+		return seen
+	}
+	seen = append(seen, checkpointSeen{Epoch: epoch, Slot: slot, At: now, Initial: initial})
+	if len(seen) > maxCheckpointSeen {
+		seen = append([]checkpointSeen(nil), seen[len(seen)-maxCheckpointSeen:]...)
+	}
+	return seen
+}
+
+// checkpointReachedAt returns when slot first became covered by a checkpoint in
+// seen, i.e. the first observed checkpoint whose root block is at or after slot
+// (the block is then an ancestor of the checkpoint block). ok is false if that hasn't
+// happened yet or happened before the backend started watching.
+func checkpointReachedAt(seen []checkpointSeen, slot uint64) (int64, bool) {
+	for _, c := range seen {
+		if c.Slot >= slot {
+			if c.Initial {
+				return 0, false
+			}
+			return c.At, true
+		}
+	}
+	return 0, false
+}
+
+// justifiedAt / finalizedAt report when slot was first seen justified / finalized.
+func justifiedAt(slot uint64) (int64, bool) {
+	finalityMu.RLock()
+	defer finalityMu.RUnlock()
+	return checkpointReachedAt(justifiedSeen, slot)
+}
+
+func finalizedAt(slot uint64) (int64, bool) {
+	finalityMu.RLock()
+	defer finalityMu.RUnlock()
+	return checkpointReachedAt(finalizedSeen, slot)
+}
@@ -0,0 +1,195 @@
+// Package domain: this file follows the canonical chain head and hands each new
+// block (with full transaction objects) to listeners such as the mempool history.
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+	"github.com/you/eth-tx-lifecycle-backend/internal/clients/eth"
+)
+
+// headBlock is the subset of a full block that head listeners need.
+type headBlock struct {
+	Number        uint64
+	Hash          string
+	ParentHash    string
+	Timestamp     uint64
+	GasLimit      uint64
+	BaseFeePerGas *big.Int
+	Miner         string // fee recipient; usually the builder under PBS
+	ExtraData     string
+	Transactions  []rpcPendingTx
+}
+
+// headMaxCatchUp caps how many missed blocks are fetched after a stall so a long
+// outage doesn't turn into a burst of hundreds of eth_getBlockByNumber calls.
+const headMaxCatchUp = 8
+
+// headListeners are invoked in registration order from the follower goroutine,
+// one block at a time in ascending order. Register from init via onHead.
+var headListeners []func(*headBlock)
+
+// latestBaseFee and latestGasLimit describe the most recent head (zero until the
+// first head). They are updated before listeners run so they see the block they get.
+var (
+	latestBaseFee  *big.Int
+	latestGasLimit uint64
+	latestHeadMu   sync.RWMutex
+)
+
+// currentBaseFee returns the latest observed base fee, or nil before the first head.
+func currentBaseFee() *big.Int {
+	latestHeadMu.RLock()
+	defer latestHeadMu.RUnlock()
+	return latestBaseFee
+}
+
+// currentGasLimit returns the latest head's gas limit, or 0 before the first head.
+func currentGasLimit() uint64 {
+	latestHeadMu.RLock()
+	defer latestHeadMu.RUnlock()
+	return latestGasLimit
+}
+
+func onHead(fn func(*headBlock)) {
+	headListeners = append(headListeners, fn)
+}
+
+// followHeads polls eth_blockNumber and dispatches every new block to headListeners.
+// Each block is first linked to the canonical window (see linkHead); on a reorg the
+// reorg listeners run, then the replacement blocks are dispatched before the new head.
+// When the height hasn't advanced, the block hash at head is compared with the
+// window so a reorg that replaces the tip at the same height is still seen.
+func followHeads() {
+	ticker := time.NewTicker(4 * time.Second)
+	defer ticker.Stop()
+	var last uint64
+	for range ticker.C {
+		raw, err := eth.Call("eth_blockNumber", []any{})
+		if err != nil {
+			continue
+		}
+		var numHex string
+		if json.Unmarshal(raw, &numHex) != nil {
+			continue
+		}
+		head, err := config.ParseHexUint64(numHex)
+		if err != nil {
+			continue
+		}
+		from := last + 1
+		switch {
+		case last == 0 || (head > last && head-last > headMaxCatchUp):
+			from = head
+		case head <= last:
+			// Same height (or lower): only a reorg replacing the block we hold
+			// at head changes anything, so compare hashes before refetching.
+			hash, err := blockHashAt(head)
+			if err != nil {
+				continue
+			}
+			if stored, ok := reorgs.at(head); !ok || stored.Hash == hash {
+				continue
+			}
+			from = head
+		}
+		for n := from; n <= head; n++ {
+			b, err := fetchHeadBlock(n)
+			if err != nil {
+				log.Printf("heads: failed to fetch block %d: %v\n", n, err)
+				break
+			}
+			if r, replay := linkHead(b); r != nil {
+				for _, fn := range reorgListeners {
+					fn(r)
+				}
+				for _, rb := range replay {
+					dispatchHead(rb)
+				}
+			}
+			dispatchHead(b)
+			last = n
+		}
+	}
+}
+
+// dispatchHead records b as the latest head and runs the head listeners.
+func dispatchHead(b *headBlock) {
+	latestHeadMu.Lock()
+	if b.BaseFeePerGas != nil {
+		latestBaseFee = b.BaseFeePerGas
+	}
+	latestGasLimit = b.GasLimit
+	latestHeadMu.Unlock()
+	for _, fn := range headListeners {
+		fn(b)
+	}
+}
+
+// blockHashAt returns the hash of block n without its transactions.
+func blockHashAt(n uint64) (string, error) {
+	raw, err := eth.Call("eth_getBlockByNumber", []any{fmt.Sprintf("0x%x", n), false})
+	if err != nil {
+		return "", err
+	}
+	var b struct {
+		Hash string `json:"hash"`
+	}
+	if string(raw) == "null" || json.Unmarshal(raw, &b) != nil || b.Hash == "" {
+		return "", fmt.Errorf("block %d not found", n)
+	}
+	return strings.ToLower(b.Hash), nil
+}
+
+func fetchHeadBlock(n uint64) (*headBlock, error) {
+	return fetchHeadBlockWith("eth_getBlockByNumber", fmt.Sprintf("0x%x", n))
+}
+
+// fetchHeadBlockByHash fetches a block by hash, so a reorg walk follows exactly
+// the new head's ancestry even if the node's view moves again meanwhile. It is a
+// variable so tests can serve the ancestry without a node.
+var fetchHeadBlockByHash = func(hash string) (*headBlock, error) {
+	return fetchHeadBlockWith("eth_getBlockByHash", hash)
+}
+
+func fetchHeadBlockWith(method, id string) (*headBlock, error) {
+	raw, err := eth.Call(method, []any{id, true})
+	if err != nil {
+		return nil, err
+	}
+	if string(raw) == "null" {
+		return nil, fmt.Errorf("block %s not found", id)
+	}
+	var b struct {
+		Number        string         `json:"number"`
+		Hash          string         `json:"hash"`
+		ParentHash    string         `json:"parentHash"`
+		Timestamp     string         `json:"timestamp"`
+		GasLimit      string         `json:"gasLimit"`
+		BaseFeePerGas *string        `json:"baseFeePerGas"`
+		Miner         string         `json:"miner"`
+		ExtraData     string         `json:"extraData"`
+		Transactions  []rpcPendingTx `json:"transactions"`
+	}
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return nil, err
+	}
+	n, err := config.ParseHexUint64(b.Number)
+	if err != nil {
+		return nil, fmt.Errorf("block number: %w", err)
+	}
+	ts, _ := config.ParseHexUint64(b.Timestamp)
+	gasLimit, _ := config.ParseHexUint64(b.GasLimit)
+	return &headBlock{
+		Number: n, Hash: strings.ToLower(b.Hash), ParentHash: strings.ToLower(b.ParentHash), Timestamp: ts, GasLimit: gasLimit,
+		BaseFeePerGas: hexBig(b.BaseFeePerGas), Miner: strings.ToLower(b.Miner), ExtraData: b.ExtraData,
+		Transactions: b.Transactions,
+	}, nil
+}
@@ -0,0 +1,418 @@
+// Package domain: this file keeps a bounded, time-indexed history of every pending
+// transaction the mempool monitor has observed, with first/last-seen timestamps and
+// why it left the pool (included, replaced, dropped).
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+)
+
+// Mempool history statuses. A record starts pending and moves to exactly one of the others.
+const (
+	TxStatusPending  = "pending"
+	TxStatusIncluded = "included"
+	TxStatusReplaced = "replaced"
+	TxStatusDropped  = "dropped"
+)
+
+// MempoolTxRecord is one transaction's mempool history. Timestamps are unix seconds.
+type MempoolTxRecord struct {
+	PendingTx
+	FirstSeen     int64  `json:"firstSeen"`
+	LastSeen      int64  `json:"lastSeen"`
+	Status        string `json:"status"`
+	RemovedAt     int64  `json:"removedAt,omitempty"`
+	IncludedBlock uint64 `json:"includedBlock,omitempty"`
+	IncludedAt    int64  `json:"includedAt,omitempty"`
+	ReplacedBy    string `json:"replacedBy,omitempty"`
+	// Replaces links a replacement back to the hash it displaced; ReplacementType
+	// is set on the displaced record (see classifyReplacement).
+	Replaces        string `json:"replaces,omitempty"`
+	ReplacementType string `json:"replacementType,omitempty"`
+}
+
+// MempoolHistory is the /api/mempool/history response.
+type MempoolHistory struct {
+	Records          []MempoolTxRecord `json:"records"`
+	Count            int               `json:"count"`
+	Total            int               `json:"total"`
+	ByStatus         map[string]int    `json:"byStatus"`
+	RetentionSeconds int64             `json:"retentionSeconds"`
+	DropAfterSeconds int64             `json:"dropAfterSeconds"`
+}
+
+// mempoolStore indexes records by hash; order holds hashes by first-seen so
+// eviction of the oldest entries is O(1) amortized. bySlot maps a (from, nonce)
+// key to the most recent hash seen for it, which is how replacements are found.
+type mempoolStore struct {
+	mu        sync.RWMutex
+	byHash    map[string]*MempoolTxRecord
+	bySlot    map[string]string
+	order     []string
+	max       int
+	retention time.Duration
+	dropAfter time.Duration
+	// since is when the first tx was observed (0 until then); blocks older than
+	// that can't say anything about public vs private flow.
+	since int64
+	// checking is set while confirmDrops is asking the node about stale records.
+	checking atomic.Bool
+	// lossAt is the latest time the store lost track of a tx that could still be
+	// included: an eviction, forgetting a replaced/dropped record, or a pool read
+	// that didn't fit. Private-flow stats treat unseen txs after it as unknown.
+	lossAt int64
+}
+
+// dropCheckBatch bounds how many stale records one sweep re-checks with the node.
+const dropCheckBatch = 200
+
+var history *mempoolStore
+
+func init() {
+	history = &mempoolStore{
+		byHash:    make(map[string]*MempoolTxRecord),
+		bySlot:    make(map[string]string),
+		max:       5000,
+		retention: 30 * time.Minute,
+		dropAfter: 10 * time.Minute,
+	}
+	if s := config.EnvOr("MEMPOOL_HISTORY_MAX", ""); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 100 && n <= 100000 {
+			history.max = n
+		}
+	}
+	if s := config.EnvOr("MEMPOOL_HISTORY_RETENTION_MINUTES", ""); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 1440 {
+			history.retention = time.Duration(n) * time.Minute
+		}
+	}
+	if s := config.EnvOr("MEMPOOL_DROP_AFTER_MINUTES", ""); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 1440 {
+			history.dropAfter = time.Duration(n) * time.Minute
+		}
+	}
+	onHead(historyOnBlock)
+	onReorg(historyOnReorg)
+}
+
+// senderNonceKey normalizes (from, nonce) so hex casing and leading zeros don't matter.
+func senderNonceKey(from, nonce string) string {
+	n, err := config.ParseHexUint64(nonce)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(from) + "|" + strconv.FormatUint(n, 10)
+}
+
+// observe records that tx was seen pending at now. It returns the stored record
+// (first-seen preserved) and whether this hash is new to the store. Hashes that
+// already left the pool are returned unchanged so late gossip can't resurrect them.
+// A new hash reusing a pending record's (from, nonce) marks that record replaced.
+func (s *mempoolStore) observe(tx PendingTx, now int64) (MempoolTxRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.observeLocked(tx, now)
+}
+
+// refresh applies a full pool read: tracked pending hashes get LastSeen = now
+// and new hashes are recorded only while the store is below max, so a pool
+// larger than the store never evicts older history. Caller must not hold mu.
+func (s *mempoolStore) refresh(txs []PendingTx, now int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tx := range txs {
+		if _, ok := s.byHash[strings.ToLower(tx.Hash)]; ok || len(s.byHash) < s.max {
+			s.observeLocked(tx, now)
+		} else {
+			s.lossAt = now
+		}
+	}
+}
+
+// observeLocked is observe with mu held.
+func (s *mempoolStore) observeLocked(tx PendingTx, now int64) (MempoolTxRecord, bool) {
+	key := strings.ToLower(tx.Hash)
+	if rec, ok := s.byHash[key]; ok {
+		if rec.Status == TxStatusPending {
+			rec.LastSeen = now
+		}
+		return *rec, false
+	}
+	if s.since == 0 {
+		s.since = now
+	}
+	tx.Timestamp = now
+	rec := &MempoolTxRecord{PendingTx: tx, FirstSeen: now, LastSeen: now, Status: TxStatusPending}
+	if slot := senderNonceKey(tx.From, tx.Nonce); slot != "" {
+		if prev, ok := s.byHash[s.bySlot[slot]]; ok && prev.Status == TxStatusPending {
+			s.markReplacedLocked(prev, tx, now)
+			rec.Replaces = prev.Hash
+		}
+		s.bySlot[slot] = key
+	}
+	s.byHash[key] = rec
+	s.order = append(s.order, key)
+	s.evictLocked()
+	noteMempoolAdded(key)
+	return *rec, true
+}
+
+// markReplacedLocked retires prev in favour of next. Caller holds mu.
+func (s *mempoolStore) markReplacedLocked(prev *MempoolTxRecord, next PendingTx, now int64) {
+	prev.Status = TxStatusReplaced
+	prev.RemovedAt = now
+	prev.ReplacedBy = next.Hash
+	prev.ReplacementType = classifyReplacement(prev.PendingTx, next)
+	noteMempoolRemoved(prev.Hash, TxStatusReplaced)
+}
+
+// forgetLocked removes hash from both indexes. Caller holds mu.
+func (s *mempoolStore) forgetLocked(hash string) {
+	if rec, ok := s.byHash[hash]; ok {
+		if slot := senderNonceKey(rec.From, rec.Nonce); s.bySlot[slot] == hash {
+			delete(s.bySlot, slot)
+		}
+		delete(s.byHash, hash)
+	}
+}
+
+// evictLocked drops the oldest records beyond max. Caller holds mu.
+func (s *mempoolStore) evictLocked() {
+	for len(s.byHash) > s.max && len(s.order) > 0 {
+		s.forgetLocked(s.order[0])
+		s.order = s.order[1:]
+		s.lossAt = time.Now().Unix()
+	}
+}
+
+// sweep forgets finished records older than retention and hands pending records
+// unseen for dropAfter to confirmDrops: a hash-only WebSocket feed announces each
+// tx once, so LastSeen alone can't tell a long-pending tx from a dropped one.
+// Records unseen for the whole retention window are dropped without asking.
+func (s *mempoolStore) sweep(now int64) {
+	s.mu.Lock()
+	dropBefore := now - int64(s.dropAfter.Seconds())
+	forgetBefore := now - int64(s.retention.Seconds())
+	kept := s.order[:0:0]
+	var stale []string
+	for _, h := range s.order {
+		rec, ok := s.byHash[h]
+		if !ok {
+			continue
+		}
+		if rec.Status == TxStatusPending && rec.LastSeen < forgetBefore {
+			s.markDroppedLocked(rec, now)
+		} else if rec.Status == TxStatusPending && rec.LastSeen < dropBefore && len(stale) < dropCheckBatch {
+			stale = append(stale, h)
+		}
+		if rec.Status != TxStatusPending && rec.RemovedAt < forgetBefore {
+			if rec.Status != TxStatusIncluded {
+				s.lossAt = now
+			}
+			s.forgetLocked(h)
+			continue
+		}
+		kept = append(kept, h)
+	}
+	s.order = kept
+	s.mu.Unlock()
+	if len(stale) > 0 && s.checking.CompareAndSwap(false, true) {
+		go s.confirmDrops(stale)
+	}
+}
+
+// confirmDrops asks the node about stale pending records: still pending means
+// LastSeen is refreshed, unknown means dropped, mined means included (a block
+// the head follower missed). RPC failures leave the record for the next sweep.
+func (s *mempoolStore) confirmDrops(hashes []string) {
+	defer s.checking.Store(false)
+	sem := make(chan struct{}, 8)
+	var wg sync.WaitGroup
+	for _, h := range hashes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			found, blockHash, blockNumber, ok := lookupTxState(h)
+			if !ok {
+				return
+			}
+			now := time.Now().Unix()
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			rec, exists := s.byHash[h]
+			if !exists || rec.Status != TxStatusPending {
+				return
+			}
+			switch {
+			case !found:
+				s.markDroppedLocked(rec, now)
+			case blockHash == "":
+				rec.LastSeen = now
+			default:
+				rec.Status = TxStatusIncluded
+				rec.RemovedAt = now
+				rec.IncludedBlock = blockNumber
+				noteMempoolRemoved(h, TxStatusIncluded)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// markDroppedLocked retires rec as dropped. Caller holds mu.
+func (s *mempoolStore) markDroppedLocked(rec *MempoolTxRecord, now int64) {
+	rec.Status = TxStatusDropped
+	rec.RemovedAt = now
+	noteMempoolRemoved(strings.ToLower(rec.Hash), TxStatusDropped)
+}
+
+// incompleteSince reports whether the monitor may have missed or forgotten a
+// public tx at or after t: the store lost records, or the WebSocket fetch
+// backlog dropped notifications.
+func (s *mempoolStore) incompleteSince(t int64) bool {
+	s.mu.RLock()
+	lossAt := s.lossAt
+	s.mu.RUnlock()
+	return lossAt >= t || mempoolLastFetchDrop.Load() >= t
+}
+
+// observingSince returns when the monitor first saw a pending tx (0 if never).
+func (s *mempoolStore) observingSince() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.since
+}
+
+// pending returns pending records' txs by first-seen, newest last, capped at limit.
+func (s *mempoolStore) pending(limit int) []PendingTx {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]PendingTx, 0, limit)
+	for i := len(s.order) - 1; i >= 0 && len(out) < limit; i-- {
+		if rec, ok := s.byHash[s.order[i]]; ok && rec.Status == TxStatusPending {
+			out = append(out, rec.PendingTx)
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// historyOnBlock resolves pending records against a newly canonical block: exact
+// hash matches are included; a different hash consuming the same (from, nonce)
+// means the record was replaced by it (possibly by a tx never seen in the pool).
+func historyOnBlock(b *headBlock) {
+	now := time.Now().Unix()
+	history.mu.Lock()
+	for _, tx := range b.Transactions {
+		key := strings.ToLower(tx.Hash)
+		if rec, ok := history.byHash[key]; ok && rec.Status == TxStatusPending {
+			rec.Status = TxStatusIncluded
+			rec.RemovedAt = now
+			rec.IncludedBlock = b.Number
+			rec.IncludedAt = int64(b.Timestamp)
+			noteMempoolRemoved(key, TxStatusIncluded)
+		}
+		slot := senderNonceKey(tx.From, tx.Nonce)
+		if slot == "" {
+			continue
+		}
+		if prev, ok := history.byHash[history.bySlot[slot]]; ok && prev.Status == TxStatusPending && !strings.EqualFold(prev.Hash, tx.Hash) {
+			history.markReplacedLocked(prev, tx.toPendingTx(now), now)
+		}
+	}
+	history.mu.Unlock()
+	history.sweep(now)
+	publishPending(now)
+}
+
+// historyOnReorg unwinds inclusions in orphaned blocks: reorged-out txs are
+// pending again (the node normally re-adds them to its pool; if not, sweep drops
+// them) and re-included txs move to their new block.
+func historyOnReorg(r *Reorg) {
+	now := time.Now().Unix()
+	newTs := make(map[uint64]uint64, len(r.NewBlocks))
+	for _, b := range r.NewBlocks {
+		newTs[b.Number] = b.Timestamp
+	}
+	history.mu.Lock()
+	for _, h := range r.ReorgedOut {
+		if rec, ok := history.byHash[h]; ok && rec.Status == TxStatusIncluded && rec.IncludedBlock > r.CommonAncestor {
+			rec.Status = TxStatusPending
+			rec.LastSeen = now
+			rec.RemovedAt, rec.IncludedBlock, rec.IncludedAt = 0, 0, 0
+			noteMempoolAdded(h)
+		}
+	}
+	for _, ri := range r.Reincluded {
+		if rec, ok := history.byHash[ri.Hash]; ok && rec.Status == TxStatusIncluded {
+			rec.IncludedBlock = ri.NewBlock
+			rec.IncludedAt = int64(newTs[ri.NewBlock])
+		}
+	}
+	history.mu.Unlock()
+}
+
+// publishPending rebuilds the /api/mempool snapshot from the store's pending set.
+func publishPending(now int64) {
+	txs := history.pending(mempoolMaxTxs)
+	metrics := calculateMempoolMetrics(txs)
+	mempoolMu.Lock()
+	mempoolData.PendingTxs = txs
+	mempoolData.Count = len(txs)
+	mempoolData.LastUpdate = now
+	mempoolData.Metrics = metrics
+	mempoolMu.Unlock()
+}
+
+// LookupMempoolTx returns the history record for hash, if the monitor has seen it.
+func LookupMempoolTx(hash string) (MempoolTxRecord, bool) {
+	history.mu.RLock()
+	defer history.mu.RUnlock()
+	if rec, ok := history.byHash[strings.ToLower(hash)]; ok {
+		return *rec, true
+	}
+	return MempoolTxRecord{}, false
+}
+
+// GetHistory returns up to limit records, newest first-seen first, optionally
+// filtered by status ("" for all).
+func GetHistory(status string, limit int) (MempoolHistory, error) {
+	switch status {
+	case "", TxStatusPending, TxStatusIncluded, TxStatusReplaced, TxStatusDropped:
+	default:
+		return MempoolHistory{}, fmt.Errorf("unknown status %q", status)
+	}
+	history.mu.RLock()
+	defer history.mu.RUnlock()
+	out := MempoolHistory{
+		Records:          []MempoolTxRecord{},
+		ByStatus:         map[string]int{},
+		Total:            len(history.byHash),
+		RetentionSeconds: int64(history.retention.Seconds()),
+		DropAfterSeconds: int64(history.dropAfter.Seconds()),
+	}
+	for _, rec := range history.byHash {
+		out.ByStatus[rec.Status]++
+		if status == "" || rec.Status == status {
+			out.Records = append(out.Records, *rec)
+		}
+	}
+	sort.Slice(out.Records, func(i, j int) bool { return out.Records[i].FirstSeen > out.Records[j].FirstSeen })
+	if len(out.Records) > limit {
+		out.Records = out.Records[:limit]
+	}
+	out.Count = len(out.Records)
+	return out, nil
+}
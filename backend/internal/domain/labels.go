@@ -0,0 +1,414 @@
+// Package domain: this file labels addresses (and builder BLS pubkeys) with a
+// name and category. Labels come, highest priority first, from the CSV/JSON
+// files in ADDRESS_LABELS (re-read when they change), the curated knownContracts
+// and builder fee recipients, contract names from the signature registry's ABI
+// files, and runtime additions (POST /api/labels, which is unauthenticated and so
+// can only name addresses nothing else does). Outputs carry a labels map keyed
+// by address rather than a label field on every address.
+package domain
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+)
+
+// Common label categories; any lowercase [a-z0-9_-] category is accepted.
+const (
+	LabelRouter   = "router"
+	LabelToken    = "token"
+	LabelBuilder  = "builder"
+	LabelSearcher = "searcher"
+	LabelCEX      = "cex"
+	LabelBridge   = "bridge"
+	LabelContract = "contract"
+)
+
+// Label sources in AddressLabel.Source (label files are "file:<path>").
+const (
+	LabelSourceBuiltin = "builtin"
+	LabelSourceRuntime = "runtime"
+	LabelSourceABI     = "abi"
+)
+
+// maxRuntimeLabels bounds the labels added through the API.
+const maxRuntimeLabels = 10000
+
+// ErrInvalidLabel is wrapped by AddLabels validation failures.
+var ErrInvalidLabel = errors.New("invalid label")
+
+// AddressLabel names an address (20 bytes) or builder pubkey (48 bytes).
+type AddressLabel struct {
+	Address  string `json:"address"`
+	Name     string `json:"name"`
+	Category string `json:"category,omitempty"`
+	Source   string `json:"source"`
+}
+
+// LabelList is the /api/labels response: labels ordered by address, counts by
+// category over the whole set, and the label files loaded.
+type LabelList struct {
+	Labels     []AddressLabel   `json:"labels"`
+	Count      int              `json:"count"`
+	Total      int              `json:"total"`
+	ByCategory map[string]int   `json:"byCategory"`
+	Files      []RegistrySource `json:"files"`
+	LoadedAt   int64            `json:"loadedAt,omitempty"`
+}
+
+// builtinLabels are knownContracts (routers, tokens, EntryPoints) plus the fee
+// recipients of the largest block builders.
+var builtinLabels = func() map[string]AddressLabel {
+	m := map[string]AddressLabel{}
+	for addr, name := range knownContracts {
+		cat := LabelToken
+		if strings.Contains(name, "Router") {
+			cat = LabelRouter
+		} else if strings.Contains(name, "EntryPoint") {
+			cat = LabelContract
+		}
+		m[addr] = AddressLabel{Address: addr, Name: name, Category: cat, Source: LabelSourceBuiltin}
+	}
+	for addr, name := range map[string]string{
+		"0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5": "beaverbuild",
+		"0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97": "Titan Builder",
+		"0x1f9090aae28b8a3dceadf281b0f12828e676c326": "rsync-builder",
+		"0xdafea492d9c6733ae3d56b7ed1adb60692c98bc5": "Flashbots Builder",
+	} {
+		m[addr] = AddressLabel{Address: addr, Name: name, Category: LabelBuilder, Source: LabelSourceBuiltin}
+	}
+	return m
+}()
+
+type labelStore struct {
+	mu       sync.RWMutex
+	files    map[string]AddressLabel
+	runtime  map[string]AddressLabel
+	sources  []RegistrySource
+	loadedAt int64
+}
+
+var labels = &labelStore{files: map[string]AddressLabel{}, runtime: map[string]AddressLabel{}}
+
+var (
+	labelKeyPattern      = regexp.MustCompile(`^0x([0-9a-f]{40}|[0-9a-f]{96})$`)
+	labelCategoryPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+)
+
+// normalizeLabelKey lowercases an address or pubkey; ok is false if it is neither.
+func normalizeLabelKey(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return s, labelKeyPattern.MatchString(s)
+}
+
+// lookupLabel returns the highest-priority label for addr.
+func lookupLabel(addr string) (AddressLabel, bool) {
+	addr = strings.ToLower(addr)
+	if addr == "" {
+		return AddressLabel{}, false
+	}
+	labels.mu.RLock()
+	l, ok := labels.files[addr]
+	labels.mu.RUnlock()
+	if ok {
+		return l, true
+	}
+	if l, ok := builtinLabels[addr]; ok {
+		return l, true
+	}
+	if name, ok := sigs().contracts[addr]; ok {
+		return AddressLabel{Address: addr, Name: name, Category: LabelContract, Source: LabelSourceABI}, true
+	}
+	labels.mu.RLock()
+	l, ok = labels.runtime[addr]
+	labels.mu.RUnlock()
+	return l, ok
+}
+
+// LabelsFor returns the labels of the given addresses/pubkeys that have one, or
+// nil. Empty and duplicate keys are ignored.
+func LabelsFor(addrs ...string) map[string]AddressLabel {
+	var out map[string]AddressLabel
+	for _, a := range addrs {
+		a = strings.ToLower(a)
+		if a == "" {
+			continue
+		}
+		if _, done := out[a]; done {
+			continue
+		}
+		if l, ok := lookupLabel(a); ok {
+			if out == nil {
+				out = map[string]AddressLabel{}
+			}
+			out[a] = l
+		}
+	}
+	return out
+}
+
+// LookupLabel returns the label for one address or pubkey.
+func LookupLabel(addr string) (AddressLabel, bool) {
+	key, ok := normalizeLabelKey(addr)
+	if !ok {
+		return AddressLabel{}, false
+	}
+	return lookupLabel(key)
+}
+
+// ListLabels returns every label (optionally one category), at most limit.
+func ListLabels(category string, limit int) LabelList {
+	merged := map[string]AddressLabel{}
+	labels.mu.RLock()
+	for addr, l := range labels.runtime {
+		merged[addr] = l
+	}
+	for addr, name := range sigs().contracts {
+		merged[addr] = AddressLabel{Address: addr, Name: name, Category: LabelContract, Source: LabelSourceABI}
+	}
+	for addr, l := range builtinLabels {
+		merged[addr] = l
+	}
+	for addr, l := range labels.files {
+		merged[addr] = l
+	}
+	out := LabelList{ByCategory: map[string]int{}, Files: append([]RegistrySource{}, labels.sources...), LoadedAt: labels.loadedAt}
+	labels.mu.RUnlock()
+
+	category = strings.ToLower(category)
+	all := make([]AddressLabel, 0, len(merged))
+	for _, l := range merged {
+		out.ByCategory[l.Category]++
+		if category == "" || l.Category == category {
+			all = append(all, l)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Address < all[j].Address })
+	out.Total = len(all)
+	if len(all) > limit {
+		all = all[:limit]
+	}
+	out.Labels, out.Count = all, len(all)
+	return out
+}
+
+// AddLabels validates and stores runtime labels until restart. They rank below
+// every other source, so an address already labelled by a file, the builtin set
+// or an ABI is rejected. Nothing is stored if any entry is invalid.
+func AddLabels(in []AddressLabel) ([]AddressLabel, error) {
+	out := make([]AddressLabel, len(in))
+	for i, l := range in {
+		key, ok := normalizeLabelKey(l.Address)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not a 20-byte address or 48-byte pubkey", ErrInvalidLabel, l.Address)
+		}
+		l, err := cleanLabel(key, l.Name, l.Category)
+		if err != nil {
+			return nil, err
+		}
+		if cur, ok := lookupLabel(key); ok && cur.Source != LabelSourceRuntime {
+			return nil, fmt.Errorf("%w: %s is already labelled %q (%s)", ErrInvalidLabel, key, cur.Name, cur.Source)
+		}
+		l.Source = LabelSourceRuntime
+		out[i] = l
+	}
+	labels.mu.Lock()
+	defer labels.mu.Unlock()
+	added := 0
+	for _, l := range out {
+		if _, ok := labels.runtime[l.Address]; !ok {
+			added++
+		}
+	}
+	if len(labels.runtime)+added > maxRuntimeLabels {
+		return nil, fmt.Errorf("%w: at most %d runtime labels", ErrInvalidLabel, maxRuntimeLabels)
+	}
+	for _, l := range out {
+		labels.runtime[l.Address] = l
+	}
+	return out, nil
+}
+
+// cleanLabel trims and checks a name and category.
+func cleanLabel(key, name, category string) (AddressLabel, error) {
+	name = strings.TrimSpace(name)
+	category = strings.ToLower(strings.TrimSpace(category))
+	if name == "" || len(name) > 64 {
+		return AddressLabel{}, fmt.Errorf("%w: name for %s must be 1-64 characters", ErrInvalidLabel, key)
+	}
+	if category != "" && !labelCategoryPattern.MatchString(category) {
+		return AddressLabel{}, fmt.Errorf("%w: category %q must be lowercase letters, digits, '_' or '-'", ErrInvalidLabel, category)
+	}
+	return AddressLabel{Address: key, Name: name, Category: category}, nil
+}
+
+// startLabels loads ADDRESS_LABELS (comma-separated CSV/JSON files) and
+// re-reads them every LABELS_RELOAD_SECONDS (default 30, 0 disables) when a
+// file's size or modification time changes.
+func startLabels() {
+	var paths []string
+	for _, p := range strings.Split(config.EnvOr("ADDRESS_LABELS", ""), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+	reload := 30 * time.Second
+	if s := config.EnvOr("LABELS_RELOAD_SECONDS", ""); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 3600 {
+			reload = time.Duration(n) * time.Second
+		}
+	}
+	last := labelFilesFingerprint(paths)
+	loadLabelFiles(paths)
+	if reload == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(reload)
+		defer ticker.Stop()
+		for range ticker.C {
+			if fp := labelFilesFingerprint(paths); fp != last {
+				last = fp
+				loadLabelFiles(paths)
+			}
+		}
+	}()
+}
+
+func labelFilesFingerprint(paths []string) uint64 {
+	h := fnv.New64a()
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil {
+			fmt.Fprintf(h, "%s|%d|%d\n", p, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return h.Sum64()
+}
+
+// loadLabelFiles replaces the file labels; later files override earlier ones.
+func loadLabelFiles(paths []string) {
+	files := map[string]AddressLabel{}
+	sources := make([]RegistrySource, 0, len(paths))
+	for _, p := range paths {
+		sources = append(sources, readLabelFile(p, files))
+	}
+	labels.mu.Lock()
+	labels.files, labels.sources, labels.loadedAt = files, sources, time.Now().Unix()
+	labels.mu.Unlock()
+	log.Printf("labels: %d labels from %d files\n", len(files), len(paths))
+}
+
+// readLabelFile adds one file's labels to dst. CSV columns are address, name,
+// category unless a header row names them (address/addr, name/label,
+// category/type/tag). JSON is an array of {address, name|label, category} or an
+// object keyed by address whose values are a name or {name|label, category}.
+func readLabelFile(path string, dst map[string]AddressLabel) RegistrySource {
+	src := RegistrySource{Path: path, Kind: "labels"}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		src.Error = err.Error()
+		return src
+	}
+	source := "file:" + path
+	add := func(addr, name, category string) {
+		key, ok := normalizeLabelKey(addr)
+		if !ok {
+			src.Skipped++
+			return
+		}
+		l, err := cleanLabel(key, name, category)
+		if err != nil {
+			src.Skipped++
+			return
+		}
+		l.Source = source
+		dst[key] = l
+		src.Entries++
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		type jsonLabel struct {
+			Address  string `json:"address"`
+			Name     string `json:"name"`
+			Label    string `json:"label"`
+			Category string `json:"category"`
+		}
+		var list []jsonLabel
+		if json.Unmarshal(raw, &list) == nil {
+			for _, l := range list {
+				add(l.Address, firstNonEmpty(l.Name, l.Label), l.Category)
+			}
+			return src
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			src.Error = "invalid label JSON: " + err.Error()
+			return src
+		}
+		for addr, v := range obj {
+			var name string
+			var l jsonLabel
+			if json.Unmarshal(v, &name) == nil {
+				add(addr, name, "")
+			} else if json.Unmarshal(v, &l) == nil {
+				add(addr, firstNonEmpty(l.Name, l.Label), l.Category)
+			} else {
+				src.Skipped++
+			}
+		}
+		return src
+	}
+	r := csv.NewReader(strings.NewReader(string(raw)))
+	r.FieldsPerRecord, r.Comment, r.TrimLeadingSpace = -1, '#', true
+	cols := map[string]int{"address": 0, "name": 1, "category": 2}
+	for first := true; ; first = false {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			src.Error = err.Error()
+			break
+		}
+		if first {
+			if _, ok := normalizeLabelKey(rec[0]); !ok {
+				cols = map[string]int{"address": -1, "name": -1, "category": -1}
+				for i, h := range rec {
+					switch strings.ToLower(strings.TrimSpace(h)) {
+					case "address", "addr":
+						cols["address"] = i
+					case "name", "label":
+						cols["name"] = i
+					case "category", "type", "tag":
+						cols["category"] = i
+					}
+				}
+				continue
+			}
+		}
+		field := func(k string) string {
+			if i := cols[k]; i >= 0 && i < len(rec) {
+				return rec[i]
+			}
+			return ""
+		}
+		add(field("address"), field("name"), field("category"))
+	}
+	return src
+}
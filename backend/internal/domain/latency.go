@@ -0,0 +1,146 @@
+// Package domain: this file turns the timestamps the backend already collects
+// (mempool first-seen, block time, checkpoint observations) into per-tx lifecycle
+// timings for TrackTx and latency distributions over recent blocks.
+package domain
+
+import (
+	"math"
+	"sort"
+)
+
+// TxTimings are unix-second timestamps for each lifecycle stage plus the deltas
+// between them. A nil field means the stage hasn't happened yet or happened
+// outside what the backend observed (e.g. before startup).
+type TxTimings struct {
+	FirstSeen          *int64 `json:"first_seen"`
+	Included           *int64 `json:"included"`
+	Justified          *int64 `json:"justified"`
+	Finalized          *int64 `json:"finalized"`
+	TimeToInclusion    *int64 `json:"time_to_inclusion_s"`
+	InclusionToJustify *int64 `json:"inclusion_to_justified_s"`
+	InclusionToFinal   *int64 `json:"inclusion_to_finalized_s"`
+	TimeToFinality     *int64 `json:"time_to_finality_s"`
+}
+
+// LatencyDistribution summarizes one stage's latencies in seconds.
+type LatencyDistribution struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+	Mean  float64 `json:"mean"`
+}
+
+// LatencyStats is the /api/stats/latency response.
+type LatencyStats struct {
+	Blocks               int                 `json:"blocks"`
+	FromBlock            uint64              `json:"fromBlock,omitempty"`
+	ToBlock              uint64              `json:"toBlock,omitempty"`
+	MempoolToInclusion   LatencyDistribution `json:"mempoolToInclusion"`
+	InclusionToJustified LatencyDistribution `json:"inclusionToJustified"`
+	InclusionToFinalized LatencyDistribution `json:"inclusionToFinalized"`
+	ObservingSince       int64               `json:"observingSince"`
+	Note                 string              `json:"note"`
+}
+
+func int64Ptr(v int64) *int64 { return &v }
+
+// txTimings assembles timings for hash. blockTs and slot are only used when
+// included is true.
+func txTimings(hash string, included bool, blockTs, slot uint64) *TxTimings {
+	t := &TxTimings{}
+	if rec, ok := LookupMempoolTx(hash); ok {
+		t.FirstSeen = int64Ptr(rec.FirstSeen)
+	}
+	if !included {
+		return t
+	}
+	inc := int64(blockTs)
+	t.Included = int64Ptr(inc)
+	if t.FirstSeen != nil && *t.FirstSeen <= inc+privateFlowSeenSlack {
+		t.TimeToInclusion = int64Ptr(max(0, inc-*t.FirstSeen))
+	}
+	if at, ok := justifiedAt(slot); ok {
+		t.Justified = int64Ptr(at)
+		t.InclusionToJustify = int64Ptr(at - inc)
+	}
+	if at, ok := finalizedAt(slot); ok {
+		t.Finalized = int64Ptr(at)
+		t.InclusionToFinal = int64Ptr(at - inc)
+		if t.TimeToInclusion != nil {
+			t.TimeToFinality = int64Ptr(at - *t.FirstSeen)
+		}
+	}
+	return t
+}
+
+// latencyDistribution sorts vals in place and summarizes them.
+func latencyDistribution(vals []float64) LatencyDistribution {
+	if len(vals) == 0 {
+		return LatencyDistribution{}
+	}
+	sort.Float64s(vals)
+	sum := 0.0
+	for _, v := range vals {
+		sum += v
+	}
+	return LatencyDistribution{
+		Count: len(vals),
+		Min:   vals[0],
+		P50:   percentile(vals, 50),
+		P90:   percentile(vals, 90),
+		P99:   percentile(vals, 99),
+		Max:   vals[len(vals)-1],
+		Mean:  math.Round(sum/float64(len(vals))*100) / 100,
+	}
+}
+
+// GetLatencyStats computes stage latencies over the last n classified blocks.
+// Mempool waits come from covered blocks only (see privateflow.go); consensus
+// stages use the first time the backend saw each checkpoint, so their resolution
+// is the finality poll interval.
+func GetLatencyStats(n int) LatencyStats {
+	privateFlow.mu.RLock()
+	start := max(0, len(privateFlow.blocks)-n)
+	blocks := make([]BlockPrivateFlow, 0, len(privateFlow.blocks)-start)
+	for _, bf := range privateFlow.blocks[start:] {
+		blocks = append(blocks, *bf)
+	}
+	privateFlow.mu.RUnlock()
+
+	st := LatencyStats{
+		Blocks:         len(blocks),
+		ObservingSince: history.observingSince(),
+		Note:           "Seconds. Justified/finalized times are when the backend first observed the covering checkpoint.",
+	}
+	if len(blocks) == 0 {
+		return st
+	}
+	st.FromBlock, st.ToBlock = blocks[0].Number, blocks[len(blocks)-1].Number
+	var waits, toJustified, toFinalized []float64
+	for _, bf := range blocks {
+		if bf.Covered {
+			for _, t := range bf.Txs {
+				if t.Public {
+					waits = append(waits, float64(t.WaitSeconds))
+				}
+			}
+		}
+		slot, ok := slotAt(bf.Timestamp)
+		if !ok {
+			continue
+		}
+		if at, ok := justifiedAt(slot); ok {
+			toJustified = append(toJustified, float64(at-int64(bf.Timestamp)))
+		}
+		if at, ok := finalizedAt(slot); ok {
+			toFinalized = append(toFinalized, float64(at-int64(bf.Timestamp)))
+		}
+	}
+	st.MempoolToInclusion = latencyDistribution(waits)
+	st.InclusionToJustified = latencyDistribution(toJustified)
+	st.InclusionToFinalized = latencyDistribution(toFinalized)
+	return st
+}
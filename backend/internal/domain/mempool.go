@@ -1,14 +1,20 @@
 // Package domain provides feature logic: mempool, track, txdecode, sandwich (MEV), snapshot.
-// This file: mempool monitoring via HTTP polling of the execution layer.
+// This file: mempool monitoring via eth_subscribe("newPendingTransactions") over
+// RPC_WS_URL, falling back to HTTP polling of the pending block when no WebSocket
+// endpoint is configured or while the subscription is down.
 package domain
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/you/eth-tx-lifecycle-backend/config"
@@ -16,25 +22,81 @@ import (
 	"github.com/you/eth-tx-lifecycle-backend/internal/pkg"
 )
 
+// Transaction envelope types (EIP-2718) as reported in the RPC "type" field.
+const (
+	TxTypeLegacy     = "0x0"
+	TxTypeAccessList = "0x1" // EIP-2930
+	TxTypeDynamicFee = "0x2" // EIP-1559
+	TxTypeBlob       = "0x3" // EIP-4844
+	TxTypeSetCode    = "0x4" // EIP-7702
+)
+
+// txTypeNames maps envelope types to the labels used in MempoolMetrics.TypeCounts.
+var txTypeNames = map[string]string{
+	TxTypeLegacy:     "legacy",
+	TxTypeAccessList: "access_list",
+	TxTypeDynamicFee: "dynamic_fee",
+	TxTypeBlob:       "blob",
+	TxTypeSetCode:    "set_code",
+}
+
+// AccessTuple is one EIP-2930 access list entry.
+type AccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+// SetCodeAuthorization is one EIP-7702 authorization tuple.
+type SetCodeAuthorization struct {
+	ChainID string `json:"chainId"`
+	Address string `json:"address"`
+	Nonce   string `json:"nonce"`
+	YParity string `json:"yParity"`
+	R       string `json:"r"`
+	S       string `json:"s"`
+}
+
 // PendingTx is a simplified view of a transaction before it's included in a block.
+// Fee fields beyond GasPrice are only set for the envelope types that carry them.
 type PendingTx struct {
-	Hash      string  `json:"hash"`
-	From      string  `json:"from"`
-	To        *string `json:"to"`
-	Value     string  `json:"value"`
-	GasPrice  *string `json:"gasPrice"`
-	Gas       *string `json:"gas"`
-	Nonce     string  `json:"nonce"`
-	Input     string  `json:"input"`
-	Timestamp int64   `json:"timestamp"`
-}
-
-// MempoolMetrics provides aggregated stats about pending transactions.
+	Hash                 string                 `json:"hash"`
+	Type                 string                 `json:"type"`
+	From                 string                 `json:"from"`
+	To                   *string                `json:"to"`
+	Value                string                 `json:"value"`
+	GasPrice             *string                `json:"gasPrice"`
+	MaxFeePerGas         *string                `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *string                `json:"maxPriorityFeePerGas,omitempty"`
+	MaxFeePerBlobGas     *string                `json:"maxFeePerBlobGas,omitempty"`
+	BlobVersionedHashes  []string               `json:"blobVersionedHashes,omitempty"`
+	AccessList           []AccessTuple          `json:"accessList,omitempty"`
+	AuthorizationList    []SetCodeAuthorization `json:"authorizationList,omitempty"`
+	Gas                  *string                `json:"gas"`
+	Nonce                string                 `json:"nonce"`
+	Input                string                 `json:"input"`
+	Timestamp            int64                  `json:"timestamp"`
+}
+
+// MempoolMetrics provides aggregated stats about pending transactions. Gas price
+// figures are in gwei; AvgGasPrice is the effective price each tx would pay at
+// the latest base fee (min(maxFee, baseFee+tip) for EIP-1559-style txs).
+// HighPriorityCount counts txs whose effective tip is at least HighPriorityTipGwei.
 type MempoolMetrics struct {
-	TotalGasRequested uint64  `json:"totalGasRequested"`
-	TotalValueWei     string  `json:"totalValueWei"`
-	AvgGasPrice       float64 `json:"avgGasPrice"`
-	HighPriorityCount int     `json:"highPriorityCount"`
+	TotalGasRequested   uint64          `json:"totalGasRequested"`
+	TotalValueWei       string          `json:"totalValueWei"`
+	AvgGasPrice         float64         `json:"avgGasPrice"`
+	HighPriorityCount   int             `json:"highPriorityCount"`
+	HighPriorityTipGwei float64         `json:"highPriorityTipGwei"`
+	PriorityFees        *FeePercentiles `json:"priorityFeePercentiles,omitempty"`
+	PriorityFeeBuckets  []FeeBucket     `json:"priorityFeeHistogram,omitempty"`
+	BaseFeeGwei         float64         `json:"baseFeeGwei,omitempty"`
+	TypeCounts          map[string]int  `json:"typeCounts"`
+	AvgMaxFeePerGas     float64         `json:"avgMaxFeePerGas,omitempty"`
+	AvgMaxPriorityFee   float64         `json:"avgMaxPriorityFee,omitempty"`
+	BlobTxCount         int             `json:"blobTxCount"`
+	BlobCount           int             `json:"blobCount"`
+	AvgMaxFeePerBlobGas float64         `json:"avgMaxFeePerBlobGas,omitempty"`
+	SetCodeAuthCount    int             `json:"setCodeAuthCount"`
 }
 
 // MempoolData holds the current snapshot of pending transactions.
@@ -44,27 +106,153 @@ type MempoolData struct {
 	LastUpdate int64           `json:"lastUpdate"`
 	Source     string          `json:"source"`
 	Metrics    *MempoolMetrics `json:"metrics,omitempty"`
+	TxPool     *TxPoolSummary  `json:"txpool,omitempty"`
+	// FetchDropped counts hash-only notifications dropped because the
+	// eth_getTransactionByHash backlog was full; those txs are missing from history.
+	FetchDropped uint64                  `json:"fetchDropped"`
+	Labels       map[string]AddressLabel `json:"labels,omitempty"`
+}
+
+// rpcPendingTx is the JSON-RPC transaction object shape shared by the pending
+// block, eth_getTransactionByHash and full-object subscription notifications.
+type rpcPendingTx struct {
+	Hash                 string                 `json:"hash"`
+	Type                 string                 `json:"type"`
+	From                 string                 `json:"from"`
+	To                   *string                `json:"to"`
+	Value                string                 `json:"value"`
+	GasPrice             *string                `json:"gasPrice"`
+	MaxFeePerGas         *string                `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *string                `json:"maxPriorityFeePerGas"`
+	MaxFeePerBlobGas     *string                `json:"maxFeePerBlobGas"`
+	BlobVersionedHashes  []string               `json:"blobVersionedHashes"`
+	AccessList           []AccessTuple          `json:"accessList"`
+	AuthorizationList    []SetCodeAuthorization `json:"authorizationList"`
+	Gas                  *string                `json:"gas"`
+	Nonce                string                 `json:"nonce"`
+	Input                string                 `json:"input"`
 }
 
+func (tx rpcPendingTx) toPendingTx(seen int64) PendingTx {
+	txType := tx.Type
+	if txType == "" {
+		txType = TxTypeLegacy
+	}
+	return PendingTx{
+		Hash:                 tx.Hash,
+		Type:                 txType,
+		From:                 tx.From,
+		To:                   tx.To,
+		Value:                tx.Value,
+		GasPrice:             tx.GasPrice,
+		MaxFeePerGas:         tx.MaxFeePerGas,
+		MaxPriorityFeePerGas: tx.MaxPriorityFeePerGas,
+		MaxFeePerBlobGas:     tx.MaxFeePerBlobGas,
+		BlobVersionedHashes:  tx.BlobVersionedHashes,
+		AccessList:           tx.AccessList,
+		AuthorizationList:    tx.AuthorizationList,
+		Gas:                  tx.Gas,
+		Nonce:                tx.Nonce,
+		Input:                tx.Input,
+		Timestamp:            seen,
+	}
+}
+
+const (
+	mempoolFetchWorkers = 8
+	mempoolFetchBacklog = 4096
+)
+
 var (
 	mempoolData   = MempoolData{PendingTxs: make([]PendingTx, 0), Source: "ws"}
 	mempoolMu     sync.RWMutex
 	mempoolHealth *pkg.BaseDataSource
+	// mempoolMaxTxs bounds the rolling window kept from the WebSocket subscription.
+	mempoolMaxTxs int
+	// mempoolFetchQueue holds hashes from hash-only notifications waiting for
+	// eth_getTransactionByHash; mempoolFetchWorkers drain it. When the backlog is
+	// full new hashes are dropped, counted in mempoolFetchDrops, and the time of
+	// the latest drop is kept so private-flow stats can tell they're incomplete.
+	mempoolFetchQueue    chan string
+	mempoolFetchDrops    atomic.Uint64
+	mempoolLastFetchDrop atomic.Int64
+	// mempoolHighTipGwei is the effective tip at which a tx counts as high priority.
+	mempoolHighTipGwei float64
 )
 
 func init() {
 	mempoolHealth = pkg.NewBaseDataSource("mempool", "mempool_health", 30*time.Second)
+	mempoolMaxTxs = 500
+	if s := config.EnvOr("MEMPOOL_MAX_TXS", ""); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 10 && n <= 10000 {
+			mempoolMaxTxs = n
+		}
+	}
+	mempoolFetchQueue = make(chan string, mempoolFetchBacklog)
+	mempoolHighTipGwei = 2
+	if s := config.EnvOr("MEMPOOL_HIGH_PRIORITY_TIP_GWEI", ""); s != "" {
+		if f, err := strconv.ParseFloat(s, 64); err == nil && f > 0 && f <= 1000 {
+			mempoolHighTipGwei = f
+		}
+	}
 }
 
-// GetData returns the current mempool snapshot.
+func hexBig(h *string) *big.Int {
+	if h == nil || *h == "" {
+		return nil
+	}
+	v, ok := config.ParseHexBigInt(*h)
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+// effectiveGasPrice is what tx would pay per gas if included at baseFee: gasPrice
+// for legacy/2930 txs, min(maxFeePerGas, baseFee+maxPriorityFeePerGas) otherwise.
+// With no base fee known it falls back to the fee cap. Returns nil if unpriced.
+func effectiveGasPrice(tx PendingTx, baseFee *big.Int) *big.Int {
+	maxFee, tip := hexBig(tx.MaxFeePerGas), hexBig(tx.MaxPriorityFeePerGas)
+	if maxFee == nil || tip == nil {
+		return hexBig(tx.GasPrice)
+	}
+	if baseFee == nil {
+		return maxFee
+	}
+	p := new(big.Int).Add(baseFee, tip)
+	if p.Cmp(maxFee) > 0 {
+		return maxFee
+	}
+	return p
+}
+
+// GetData returns the current mempool snapshot, with labels for its senders and recipients.
 func GetData() MempoolData {
 	mempoolMu.RLock()
-	defer mempoolMu.RUnlock()
-	return mempoolData
+	d := mempoolData
+	mempoolMu.RUnlock()
+	d.FetchDropped = mempoolFetchDrops.Load()
+	d.Labels = LabelsFor(pendingAddresses(d.PendingTxs)...)
+	return d
+}
+
+func pendingAddresses(txs []PendingTx) []string {
+	out := make([]string, 0, 2*len(txs))
+	for _, tx := range txs {
+		out = append(out, tx.From)
+		if tx.To != nil {
+			out = append(out, *tx.To)
+		}
+	}
+	return out
 }
 
 // Start begins mempool monitoring in the background.
 func Start() {
+	startRegistry()
+	startLabels()
+	go flushMempoolDeltas()
+	go followFinality()
 	if d := strings.ToLower(config.EnvOr("MEMPOOL_DISABLE", "")); d == "1" || d == "true" || d == "yes" || d == "on" {
 		log.Println("mempool WS: disabled via MEMPOOL_DISABLE env")
 		mempoolMu.Lock()
@@ -86,19 +274,41 @@ func Start() {
 		mempoolMu.Unlock()
 		return
 	}
+	go followHeads()
+	go txpoolPoll()
+	if eth.WSConfigured() {
+		log.Println("mempool: starting WebSocket subscription for pending transactions")
+		for range mempoolFetchWorkers {
+			go mempoolFetchWorker()
+		}
+		go mempoolSubscribe()
+		return
+	}
 	log.Println("mempool: starting HTTP polling for pending transactions")
-	go mempoolPoll()
+	go mempoolPoll(nil)
 }
 
 func calculateMempoolMetrics(txs []PendingTx) *MempoolMetrics {
 	if len(txs) == 0 {
 		return nil
 	}
-	metrics := &MempoolMetrics{}
-	var totalGasPrice uint64
+	baseFee := currentBaseFee()
+	metrics := &MempoolMetrics{TypeCounts: map[string]int{}, HighPriorityTipGwei: mempoolHighTipGwei}
+	if baseFee != nil {
+		metrics.BaseFeeGwei = weiToGwei(baseFee)
+	}
+	totalGasPrice := new(big.Int)
 	var gasPriceCount int
+	tipsGwei := make([]float64, 0, len(txs))
+	totalMaxFee, totalTip, totalBlobFee := new(big.Int), new(big.Int), new(big.Int)
+	var dynamicCount, blobFeeCount int
 	totalValue := big.NewInt(0)
 	for _, tx := range txs {
+		name, ok := txTypeNames[tx.Type]
+		if !ok {
+			name = "other"
+		}
+		metrics.TypeCounts[name]++
 		if tx.Gas != nil {
 			if gas, err := config.ParseHexUint64(*tx.Gas); err == nil {
 				metrics.TotalGasRequested += gas
@@ -109,32 +319,76 @@ func calculateMempoolMetrics(txs []PendingTx) *MempoolMetrics {
 				totalValue.Add(totalValue, val)
 			}
 		}
-		var gasPrice uint64
-		if tx.GasPrice != nil && *tx.GasPrice != "" {
-			if gp, err := config.ParseHexUint64(*tx.GasPrice); err == nil {
-				gasPrice = gp
+		if maxFee, tip := hexBig(tx.MaxFeePerGas), hexBig(tx.MaxPriorityFeePerGas); maxFee != nil && tip != nil {
+			totalMaxFee.Add(totalMaxFee, maxFee)
+			totalTip.Add(totalTip, tip)
+			dynamicCount++
+		}
+		if tx.Type == TxTypeBlob {
+			metrics.BlobTxCount++
+			metrics.BlobCount += len(tx.BlobVersionedHashes)
+			if bf := hexBig(tx.MaxFeePerBlobGas); bf != nil {
+				totalBlobFee.Add(totalBlobFee, bf)
+				blobFeeCount++
 			}
 		}
-		if gasPrice > 0 {
-			totalGasPrice += gasPrice
+		metrics.SetCodeAuthCount += len(tx.AuthorizationList)
+		if p := effectiveGasPrice(tx, baseFee); p != nil && p.Sign() > 0 {
+			totalGasPrice.Add(totalGasPrice, p)
 			gasPriceCount++
-			if gasPrice > 50_000_000_000 {
+		}
+		if tip := effectivePriorityFee(tx, baseFee); tip != nil && tip.Sign() >= 0 {
+			g := weiToGwei(tip)
+			tipsGwei = append(tipsGwei, g)
+			if g >= mempoolHighTipGwei {
 				metrics.HighPriorityCount++
 			}
 		}
 	}
 	metrics.TotalValueWei = "0x" + totalValue.Text(16)
 	if gasPriceCount > 0 {
-		metrics.AvgGasPrice = float64(totalGasPrice/uint64(gasPriceCount)) / 1e9
+		avg := new(big.Float).Quo(new(big.Float).SetInt(totalGasPrice), big.NewFloat(float64(gasPriceCount)))
+		metrics.AvgGasPrice, _ = new(big.Float).Quo(avg, big.NewFloat(1e9)).Float64()
+	}
+	sort.Float64s(tipsGwei)
+	metrics.PriorityFees = feePercentiles(tipsGwei)
+	if len(tipsGwei) > 0 {
+		metrics.PriorityFeeBuckets = feeHistogram(tipsGwei)
+	}
+	if dynamicCount > 0 {
+		n := big.NewInt(int64(dynamicCount))
+		metrics.AvgMaxFeePerGas = weiToGwei(totalMaxFee.Quo(totalMaxFee, n))
+		metrics.AvgMaxPriorityFee = weiToGwei(totalTip.Quo(totalTip, n))
+	}
+	if blobFeeCount > 0 {
+		metrics.AvgMaxFeePerBlobGas = weiToGwei(totalBlobFee.Quo(totalBlobFee, big.NewInt(int64(blobFeeCount))))
 	}
 	return metrics
 }
 
-func mempoolPoll() {
+// weiToGwei converts a wei amount to a float gwei value for display metrics.
+func weiToGwei(wei *big.Int) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e9)).Float64()
+	return f
+}
+
+// mempoolPoll polls the pending block every 5s until stop is closed. A nil stop
+// polls forever (no WebSocket endpoint configured). Ticks are skipped while
+// txpoolPoll is reading the real pool via txpool_content.
+func mempoolPoll(stop <-chan struct{}) {
 	log.Println("mempool HTTP: starting polling of pending block")
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
-	for range ticker.C {
+	for {
+		select {
+		case <-stop:
+			log.Println("mempool HTTP: polling stopped")
+			return
+		case <-ticker.C:
+		}
+		if txpoolContentAvailable() {
+			continue
+		}
 		raw, err := eth.Call("eth_getBlockByNumber", []any{"pending", true})
 		if err != nil {
 			log.Printf("mempool HTTP: failed to fetch pending block: %v\n", err)
@@ -142,16 +396,7 @@ func mempoolPoll() {
 			continue
 		}
 		var block struct {
-			Transactions []struct {
-				Hash     string  `json:"hash"`
-				From     string  `json:"from"`
-				To       *string `json:"to"`
-				Value    string  `json:"value"`
-				GasPrice *string `json:"gasPrice"`
-				Gas      *string `json:"gas"`
-				Nonce    string  `json:"nonce"`
-				Input    string  `json:"input"`
-			} `json:"transactions"`
+			Transactions []rpcPendingTx `json:"transactions"`
 		}
 		if err := json.Unmarshal(raw, &block); err != nil {
 			log.Printf("mempool HTTP: failed to parse pending block: %v\n", err)
@@ -161,32 +406,139 @@ func mempoolPoll() {
 			continue
 		}
 		now := time.Now().Unix()
-		pendingTxs := make([]PendingTx, len(block.Transactions))
 		for i := range block.Transactions {
-			tx := block.Transactions[i]
-			pendingTxs[i] = PendingTx{
-				Hash:      tx.Hash,
-				From:      tx.From,
-				To:        tx.To,
-				Value:     tx.Value,
-				GasPrice:  tx.GasPrice,
-				Gas:       tx.Gas,
-				Nonce:     tx.Nonce,
-				Input:     tx.Input,
-				Timestamp: now,
-			}
+			history.observe(block.Transactions[i].toPendingTx(now), now)
 		}
-		metrics := calculateMempoolMetrics(pendingTxs)
+		publishPending(now)
 		mempoolMu.Lock()
-		mempoolData.PendingTxs = pendingTxs
-		mempoolData.Count = len(pendingTxs)
-		mempoolData.LastUpdate = now
 		mempoolData.Source = "http-polling"
-		mempoolData.Metrics = metrics
+		metrics := mempoolData.Metrics
+		count := mempoolData.Count
 		mempoolMu.Unlock()
 		mempoolHealth.SetSuccess()
-		log.Printf("mempool HTTP: fetched %d pending transactions (avg gas: %.2f gwei)\n", len(pendingTxs), metrics.AvgGasPrice)
+		if metrics != nil {
+			log.Printf("mempool HTTP: fetched %d pending transactions, tracking %d (avg gas: %.2f gwei)\n", len(block.Transactions), count, metrics.AvgGasPrice)
+		}
+	}
+}
+
+// mempoolSubscribe keeps an eth_subscribe("newPendingTransactions") stream open,
+// resubscribing with exponential backoff. While the subscription is down it runs
+// mempoolPoll so /api/mempool keeps updating; polling stops once WS is back.
+func mempoolSubscribe() {
+	backoff := time.Second
+	fullTxs := true
+	var stopPoll chan struct{}
+	for {
+		// Geth/Reth/Erigon accept a second `true` param to push full tx objects;
+		// providers that reject it get a hash-only subscription on the next attempt.
+		params := []any{"newPendingTransactions"}
+		if fullTxs {
+			params = append(params, true)
+		}
+		sub, err := eth.Subscribe(params)
+		if err != nil {
+			log.Printf("mempool WS: subscribe failed (full=%v): %v\n", fullTxs, err)
+			mempoolHealth.SetError(err)
+			if stopPoll == nil {
+				log.Println("mempool WS: falling back to HTTP polling until resubscribed")
+				stopPoll = make(chan struct{})
+				go mempoolPoll(stopPoll)
+			}
+			// Only a JSON-RPC error response means the node rejected the `true`
+			// param; dial and network failures retry with the same params.
+			var rpcErr *eth.RPCError
+			if fullTxs && errors.As(err, &rpcErr) {
+				log.Println("mempool WS: node rejected full-tx subscriptions, using hash-only notifications")
+				fullTxs = false
+			}
+			time.Sleep(backoff)
+			if backoff < time.Minute {
+				backoff *= 2
+			}
+			continue
+		}
+		if stopPoll != nil {
+			close(stopPoll)
+			stopPoll = nil
+		}
+		backoff = time.Second
+		log.Printf("mempool WS: subscribed (id %s, full=%v)\n", sub.ID, fullTxs)
+		mempoolMu.Lock()
+		mempoolData.Source = "ws"
+		mempoolMu.Unlock()
+		for {
+			raw, err := sub.Next()
+			if err != nil {
+				log.Printf("mempool WS: subscription lost: %v\n", err)
+				mempoolHealth.SetError(err)
+				break
+			}
+			handlePendingNotification(raw)
+		}
+		sub.Close()
+	}
+}
+
+// handlePendingNotification accepts either a full tx object or a bare hash. Hashes
+// are queued for mempoolFetchWorker; when the backlog is full they're dropped and counted.
+func handlePendingNotification(raw json.RawMessage) {
+	var hash string
+	if json.Unmarshal(raw, &hash) == nil {
+		if hash == "" {
+			return
+		}
+		select {
+		case mempoolFetchQueue <- hash:
+		default:
+			if n := mempoolFetchDrops.Add(1); n == 1 || n%1000 == 0 {
+				log.Printf("mempool WS: fetch backlog full, %d notifications dropped so far\n", n)
+			}
+			mempoolLastFetchDrop.Store(time.Now().Unix())
+		}
+		return
+	}
+	var tx rpcPendingTx
+	if json.Unmarshal(raw, &tx) == nil && tx.Hash != "" {
+		addPendingTx(tx.toPendingTx(time.Now().Unix()))
+	}
+}
+
+// mempoolFetchWorker resolves queued hashes with eth_getTransactionByHash.
+func mempoolFetchWorker() {
+	for hash := range mempoolFetchQueue {
+		rawTx, err := eth.Call("eth_getTransactionByHash", []any{hash})
+		if err != nil || string(rawTx) == "null" {
+			continue
+		}
+		var tx rpcPendingTx
+		if json.Unmarshal(rawTx, &tx) == nil && tx.Hash != "" {
+			addPendingTx(tx.toPendingTx(time.Now().Unix()))
+		}
+	}
+}
+
+// addPendingTx records a newly gossiped tx in the history store and appends it
+// to the rolling window, skipping hashes already seen and trimming the oldest
+// entries beyond mempoolMaxTxs.
+func addPendingTx(tx PendingTx) {
+	rec, isNew := history.observe(tx, tx.Timestamp)
+	if !isNew {
+		return
+	}
+	mempoolMu.Lock()
+	defer mempoolMu.Unlock()
+	txs := append(mempoolData.PendingTxs, rec.PendingTx)
+	if len(txs) > mempoolMaxTxs {
+		// Copy rather than reslice so snapshots handed out by GetData never alias the live window.
+		txs = append([]PendingTx(nil), txs[len(txs)-mempoolMaxTxs:]...)
 	}
+	mempoolData.PendingTxs = txs
+	mempoolData.Count = len(txs)
+	mempoolData.LastUpdate = tx.Timestamp
+	mempoolData.Source = "ws"
+	mempoolData.Metrics = calculateMempoolMetrics(txs)
+	mempoolHealth.SetSuccess()
 }
 
 // CheckHealth returns health status based on recent mempool data.
@@ -0,0 +1,292 @@
+// Package domain: this file answers filtered, sorted, cursor-paginated queries
+// over the current pending set (e.g. "pending swaps over 10 ETH sorted by tip").
+// Each tx is run through DecodeTransactionInput so callers can filter on what it does.
+package domain
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Sort keys accepted by MempoolQuery.Sort.
+const (
+	MempoolSortSeen  = "seen"  // first-seen timestamp (default)
+	MempoolSortTip   = "tip"   // effective priority fee at the latest base fee
+	MempoolSortValue = "value" // ETH value
+	MempoolSortFee   = "fee"   // fee cap (maxFeePerGas, or gasPrice for legacy)
+	MempoolSortGas   = "gas"   // gas limit
+)
+
+// MempoolQuery selects and orders pending txs. Empty fields don't filter.
+// MinValueWei and MinTipWei are inclusive lower bounds; Desc defaults to true
+// in the handler so "sorted by tip" means highest first.
+type MempoolQuery struct {
+	From         string
+	To           string
+	Method       string // 4-byte selector ("0x38ed1739") or method name prefix ("swapExact")
+	Action       string // DecodedTx.ActionType ("swap", "transfer", ...)
+	Type         string // envelope type ("0x2") or its name ("dynamic_fee", "blob")
+	MinValueWei  *big.Int
+	MinTipWei    *big.Int
+	Sort         string
+	Desc         bool
+	Cursor       string
+	Limit        int
+	IncludeInput bool
+}
+
+// MempoolTxView is one query result: the pending tx (Input blanked unless asked
+// for), what it decodes to, and the tip it would pay at the current base fee.
+type MempoolTxView struct {
+	PendingTx
+	Decoded *DecodedTx `json:"decoded,omitempty"`
+	TipGwei float64    `json:"tipGwei"`
+}
+
+// MempoolPage is one page of a MempoolQuery. Matched counts every tx passing the
+// filters; NextCursor is empty on the last page. Labels covers the page's
+// senders, recipients and every address in their decoded calls.
+type MempoolPage struct {
+	Txs         []MempoolTxView         `json:"txs"`
+	Matched     int                     `json:"matched"`
+	Total       int                     `json:"total"`
+	NextCursor  string                  `json:"nextCursor,omitempty"`
+	Sort        string                  `json:"sort"`
+	Order       string                  `json:"order"`
+	LastUpdate  int64                   `json:"lastUpdate"`
+	Source      string                  `json:"source"`
+	BaseFeeGwei float64                 `json:"baseFeeGwei,omitempty"`
+	Labels      map[string]AddressLabel `json:"labels,omitempty"`
+}
+
+// mempoolMatch carries a filtered tx with its sort key so sorting and cursor
+// comparison use the same value.
+type mempoolMatch struct {
+	view MempoolTxView
+	key  *big.Int
+	hash string
+}
+
+// normalizeTxType accepts "0x2", "2" or a txTypeNames label and returns the RPC form.
+func normalizeTxType(t string) (string, bool) {
+	t = strings.ToLower(strings.TrimSpace(t))
+	if _, ok := txTypeNames[t]; ok {
+		return t, true
+	}
+	if _, ok := txTypeNames["0x"+t]; ok {
+		return "0x" + t, true
+	}
+	for typ, name := range txTypeNames {
+		if name == t {
+			return typ, true
+		}
+	}
+	return "", false
+}
+
+// matchesMethod compares a selector or method-name prefix against tx input.
+func matchesMethod(input, method string) bool {
+	if len(input) < 10 {
+		return false
+	}
+	sel := strings.ToLower(input[:10])
+	if strings.HasPrefix(method, "0x") {
+		return sel == method
+	}
+	for _, c := range sigs().candidates(sel, "", SigFunction) {
+		if strings.HasPrefix(strings.ToLower(c.Signature), method) {
+			return true
+		}
+	}
+	return false
+}
+
+// mempoolSortKey returns the big.Int a tx is ordered by (missing values sort as 0).
+func mempoolSortKey(tx PendingTx, tip *big.Int, sortBy string) *big.Int {
+	var k *big.Int
+	switch sortBy {
+	case MempoolSortTip:
+		k = tip
+	case MempoolSortValue:
+		v := tx.Value
+		k = hexBig(&v)
+	case MempoolSortFee:
+		k = pendingTxFee(tx)
+	case MempoolSortGas:
+		k = hexBig(tx.Gas)
+	default:
+		k = big.NewInt(tx.Timestamp)
+	}
+	if k == nil {
+		return new(big.Int)
+	}
+	return k
+}
+
+// encodeMempoolCursor / decodeMempoolCursor wrap "key:hash" of the last row
+// returned, so the next page resumes after it even if the pool changed meanwhile.
+func encodeMempoolCursor(key *big.Int, hash string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key.String() + ":" + hash))
+}
+
+func decodeMempoolCursor(c string) (*big.Int, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid cursor")
+	}
+	k, h, ok := strings.Cut(string(raw), ":")
+	key, okKey := new(big.Int).SetString(k, 10)
+	if !ok || !okKey || h == "" {
+		return nil, "", fmt.Errorf("invalid cursor")
+	}
+	return key, h, nil
+}
+
+// mempoolLess orders by key then hash (ascending), reversed when desc.
+func mempoolLess(aKey *big.Int, aHash string, bKey *big.Int, bHash string, desc bool) bool {
+	c := aKey.Cmp(bKey)
+	if c == 0 {
+		c = strings.Compare(aHash, bHash)
+	}
+	if desc {
+		return c > 0
+	}
+	return c < 0
+}
+
+// QueryMempool filters the current pending snapshot, sorts it and returns the
+// page after q.Cursor. Unknown sort keys, tx types or malformed cursors are errors.
+func QueryMempool(q MempoolQuery) (*MempoolPage, error) {
+	sortBy := strings.ToLower(q.Sort)
+	switch sortBy {
+	case "":
+		sortBy = MempoolSortSeen
+	case MempoolSortSeen, MempoolSortTip, MempoolSortValue, MempoolSortFee, MempoolSortGas:
+	default:
+		return nil, fmt.Errorf("unknown sort %q", q.Sort)
+	}
+	txType := ""
+	if q.Type != "" {
+		t, ok := normalizeTxType(q.Type)
+		if !ok {
+			return nil, fmt.Errorf("unknown tx type %q", q.Type)
+		}
+		txType = t
+	}
+	var afterKey *big.Int
+	var afterHash string
+	if q.Cursor != "" {
+		k, h, err := decodeMempoolCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		afterKey, afterHash = k, h
+	}
+	if q.Limit <= 0 {
+		q.Limit = 50
+	}
+	from, to := strings.ToLower(q.From), strings.ToLower(q.To)
+	method, action := strings.ToLower(q.Method), strings.ToLower(q.Action)
+
+	data := GetData()
+	baseFee := currentBaseFee()
+	matches := make([]mempoolMatch, 0, len(data.PendingTxs))
+	for _, tx := range data.PendingTxs {
+		if from != "" && strings.ToLower(tx.From) != from {
+			continue
+		}
+		if to != "" && (tx.To == nil || strings.ToLower(*tx.To) != to) {
+			continue
+		}
+		if txType != "" && tx.Type != txType {
+			continue
+		}
+		if method != "" && !matchesMethod(tx.Input, method) {
+			continue
+		}
+		if q.MinValueWei != nil {
+			v := tx.Value
+			if val := hexBig(&v); val == nil || val.Cmp(q.MinValueWei) < 0 {
+				continue
+			}
+		}
+		tip := effectivePriorityFee(tx, baseFee)
+		if tip == nil {
+			tip = new(big.Int)
+		}
+		if q.MinTipWei != nil && tip.Cmp(q.MinTipWei) < 0 {
+			continue
+		}
+		// Decoding is the costly step: only an action filter needs it for every
+		// tx; otherwise just the returned page is decoded below.
+		var decoded *DecodedTx
+		if action != "" {
+			decoded = DecodeTransactionInput(tx.Input, tx.To, tx.Value, nil)
+			if decoded == nil || strings.ToLower(decoded.ActionType) != action {
+				continue
+			}
+		}
+		view := MempoolTxView{PendingTx: tx, Decoded: decoded, TipGwei: weiToGwei(tip)}
+		hash := strings.ToLower(tx.Hash)
+		matches = append(matches, mempoolMatch{view: view, key: mempoolSortKey(tx, tip, sortBy), hash: hash})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return mempoolLess(matches[i].key, matches[i].hash, matches[j].key, matches[j].hash, q.Desc)
+	})
+
+	start := 0
+	if afterKey != nil {
+		start = sort.Search(len(matches), func(i int) bool {
+			return mempoolLess(afterKey, afterHash, matches[i].key, matches[i].hash, q.Desc)
+		})
+	}
+	end := min(start+q.Limit, len(matches))
+	page := &MempoolPage{
+		Txs:        make([]MempoolTxView, 0, end-start),
+		Matched:    len(matches),
+		Total:      len(data.PendingTxs),
+		Sort:       sortBy,
+		Order:      "asc",
+		LastUpdate: data.LastUpdate,
+		Source:     data.Source,
+	}
+	if q.Desc {
+		page.Order = "desc"
+	}
+	if baseFee != nil {
+		page.BaseFeeGwei = weiToGwei(baseFee)
+	}
+	pageTxs := make([]PendingTx, 0, end-start)
+	for _, m := range matches[start:end] {
+		pageTxs = append(pageTxs, m.view.PendingTx)
+	}
+	page.Labels = LabelsFor(pendingAddresses(pageTxs)...)
+	for _, m := range matches[start:end] {
+		view := m.view
+		if view.Decoded == nil {
+			view.Decoded = DecodeTransactionInput(view.Input, view.To, view.Value, nil)
+		}
+		if view.Decoded != nil {
+			// One label map per page: fold each decode's labels into it.
+			for k, l := range view.Decoded.Labels {
+				if page.Labels == nil {
+					page.Labels = map[string]AddressLabel{}
+				}
+				page.Labels[k] = l
+			}
+			view.Decoded.Labels = nil
+		}
+		if !q.IncludeInput {
+			view.Input = ""
+		}
+		page.Txs = append(page.Txs, view)
+	}
+	if end < len(matches) && end > start {
+		last := matches[end-1]
+		page.NextCursor = encodeMempoolCursor(last.key, last.hash)
+	}
+	return page, nil
+}
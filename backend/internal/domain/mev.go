@@ -87,19 +87,20 @@ type MEVEvent struct {
 
 // MEVAnalysis is the complete MEV analysis result for a block.
 type MEVAnalysis struct {
-	Block            string         `json:"block"`
-	BlockHash        string         `json:"blockHash"`
-	TxScanned        int            `json:"txScanned"`
-	TotalTx          int            `json:"totalTx"`
-	SwapCount        int            `json:"swapCount"`
-	Sandwiches       []Sandwich     `json:"sandwiches"`
-	Arbitrages       []Arbitrage    `json:"arbitrages"`
-	Liquidations     []Liquidation  `json:"liquidations"`
-	JITLiquidity     []JITLiquidity `json:"jitLiquidity"`
-	SandwichCount    int            `json:"sandwichCount"`
-	ArbitrageCount   int            `json:"arbitrageCount"`
-	LiquidationCount int            `json:"liquidationCount"`
-	JITCount         int            `json:"jitCount"`
+	Block            string                  `json:"block"`
+	BlockHash        string                  `json:"blockHash"`
+	TxScanned        int                     `json:"txScanned"`
+	TotalTx          int                     `json:"totalTx"`
+	SwapCount        int                     `json:"swapCount"`
+	Sandwiches       []Sandwich              `json:"sandwiches"`
+	Arbitrages       []Arbitrage             `json:"arbitrages"`
+	Liquidations     []Liquidation           `json:"liquidations"`
+	JITLiquidity     []JITLiquidity          `json:"jitLiquidity"`
+	SandwichCount    int                     `json:"sandwichCount"`
+	ArbitrageCount   int                     `json:"arbitrageCount"`
+	LiquidationCount int                     `json:"liquidationCount"`
+	JITCount         int                     `json:"jitCount"`
+	Labels           map[string]AddressLabel `json:"labels,omitempty"`
 }
 
 func keccakTopic(signature string) string {
@@ -110,22 +111,48 @@ func keccakTopic(signature string) string {
 	return "0x" + hex.EncodeToString(out[:])
 }
 
-// Event topic signatures
-var (
+// mevEventSignatures are the curated events the registry always knows. Logs
+// are matched by resolving topic0 through the registry and looking up the full
+// signature in mevEventTypes, so pool events from ABI files or the signature
+// dump count only when they are on its allowlist.
+var mevEventSignatures = []string{
 	// Uniswap V2/V3 Swap events
-	swapTopicV2 = strings.ToLower(keccakTopic("Swap(address,uint256,uint256,uint256,uint256,address)"))
-	swapTopicV3 = strings.ToLower(keccakTopic("Swap(address,address,int256,int256,uint160,uint128,int24)"))
+	"Swap(address,uint256,uint256,uint256,uint256,address)",
+	"Swap(address,address,int256,int256,uint160,uint128,int24)",
 	// Uniswap V2/V3 Mint events for JIT liquidity detection
-	mintTopicV2 = strings.ToLower(keccakTopic("Mint(address,uint256,uint256)"))
-	mintTopicV3 = strings.ToLower(keccakTopic("Mint(address,address,int24,int24,uint128,uint256,uint256)"))
+	"Mint(address,uint256,uint256)",
+	"Mint(address,address,int24,int24,uint128,uint256,uint256)",
 	// Uniswap V2/V3 Burn events
-	burnTopicV2 = strings.ToLower(keccakTopic("Burn(address,uint256,uint256,address)"))
-	burnTopicV3 = strings.ToLower(keccakTopic("Burn(address,int24,int24,uint128,uint256,uint256)"))
+	"Burn(address,uint256,uint256,address)",
+	"Burn(address,int24,int24,uint128,uint256,uint256)",
 	// Aave V2/V3 LiquidationCall
-	liquidationAave = strings.ToLower(keccakTopic("LiquidationCall(address,address,address,uint256,uint256,address,bool)"))
+	"LiquidationCall(address,address,address,uint256,uint256,address,bool)",
 	// Compound V2 LiquidateBorrow
-	liquidationCompound = strings.ToLower(keccakTopic("LiquidateBorrow(address,address,uint256,address,uint256)"))
+	"LiquidateBorrow(address,address,uint256,address,uint256)",
+}
+
+// mevEventTypes maps full event signatures to MEVEvent.Type (and liquidation
+// protocol): every curated mevEventSignatures entry plus an allowlist of other
+// pool events. Matching by signature rather than name keeps an ERC-20
+// Mint(address,uint256) or an aggregator's Swap(...) from posing as a pool.
+var mevEventTypes = map[string][2]string{
+	"Swap(address,uint256,uint256,uint256,uint256,address)":                     {"swap", ""},
+	"Swap(address,address,int256,int256,uint160,uint128,int24)":                 {"swap", ""},
+	"Mint(address,uint256,uint256)":                                             {"mint", ""},
+	"Mint(address,address,int24,int24,uint128,uint256,uint256)":                 {"mint", ""},
+	"Burn(address,uint256,uint256,address)":                                     {"burn", ""},
+	"Burn(address,int24,int24,uint128,uint256,uint256)":                         {"burn", ""},
+	"LiquidationCall(address,address,address,uint256,uint256,address,bool)":     {"liquidation", "aave"},
+	"LiquidateBorrow(address,address,uint256,address,uint256)":                  {"liquidation", "compound"},
+	"TokenExchange(address,int128,uint256,int128,uint256)":                      {"swap", ""}, // Curve stableswap
+	"TokenExchangeUnderlying(address,int128,uint256,int128,uint256)":            {"swap", ""}, // Curve lending pools
+	"TokenExchange(address,uint256,uint256,uint256,uint256)":                    {"swap", ""}, // Curve cryptoswap
+	"Swap(bytes32,address,int128,int128,uint160,uint128,int24,uint24)":          {"swap", ""}, // Uniswap V4 PoolManager
+	"Swap(bytes32,address,address,uint256,uint256)":                             {"swap", ""}, // Balancer V2 Vault
+	"Swap(address,address,int256,int256,uint160,uint128,int24,uint128,uint128)": {"swap", ""}, // PancakeSwap V3
+}
 
+var (
 	mevMaxTx   int
 	mevWorkers int
 )
@@ -251,7 +278,14 @@ func CollectMEVEvents(b *Block) ([]MEVEvent, error) {
 				if len(lg.Topics) == 0 {
 					continue
 				}
-				topic := strings.ToLower(lg.Topics[0])
+				ev, ok := eventSignature(lg.Topics[0], lg.Address)
+				if !ok {
+					continue
+				}
+				kind, ok := mevEventTypes[ev.Signature]
+				if !ok {
+					continue
+				}
 				evt := MEVEvent{
 					TxHash:   strings.ToLower(rcpt.TxHash),
 					TxIndex:  i,
@@ -259,29 +293,19 @@ func CollectMEVEvents(b *Block) ([]MEVEvent, error) {
 					Pool:     strings.ToLower(lg.Address),
 					LogIndex: lg.LogIndex,
 				}
-				switch topic {
-				case swapTopicV2, swapTopicV3:
-					evt.Type = "swap"
-					local = append(local, evt)
-				case mintTopicV2, mintTopicV3:
-					evt.Type = "mint"
-					local = append(local, evt)
-				case burnTopicV2, burnTopicV3:
-					evt.Type = "burn"
-					local = append(local, evt)
-				case liquidationAave:
-					evt.Type = "liquidation"
-					evt.Data = "aave"
+				evt.Type = kind[0]
+				// Singleton pools (Uniswap V4, Balancer) emit the pool id as the first topic.
+				if strings.HasPrefix(ev.Signature, "Swap(bytes32,") && len(lg.Topics) > 1 {
+					evt.Pool = strings.ToLower(lg.Topics[1])
+				}
+				if evt.Type == "liquidation" {
+					evt.Data = kind[1]
 					// Extract borrower from topics[3] if available
-					if len(lg.Topics) > 3 {
+					if kind[1] == "aave" && len(lg.Topics) > 3 {
 						evt.Data = "aave:" + strings.ToLower(lg.Topics[3])
 					}
-					local = append(local, evt)
-				case liquidationCompound:
-					evt.Type = "liquidation"
-					evt.Data = "compound"
-					local = append(local, evt)
 				}
+				local = append(local, evt)
 			}
 			results[i] = local
 			return nil
@@ -501,6 +525,24 @@ func AnalyzeBlockMEV(b *Block) (*MEVAnalysis, error) {
 		maxN = mevMaxTx
 	}
 
+	var addrs []string
+	for _, s := range sandwiches {
+		addrs = append(addrs, s.Attacker, s.Victim, s.Pool)
+	}
+	for _, a := range arbitrages {
+		addrs = append(addrs, a.Searcher)
+		addrs = append(addrs, a.Pools...)
+	}
+	for _, l := range liquidations {
+		addrs = append(addrs, l.Liquidator)
+		if len(l.Borrower) == 66 {
+			addrs = append(addrs, "0x"+l.Borrower[26:])
+		}
+	}
+	for _, j := range jits {
+		addrs = append(addrs, j.Provider, j.Pool)
+	}
+
 	return &MEVAnalysis{
 		Block:            b.Number,
 		BlockHash:        b.Hash,
@@ -515,5 +557,6 @@ func AnalyzeBlockMEV(b *Block) (*MEVAnalysis, error) {
 		ArbitrageCount:   len(arbitrages),
 		LiquidationCount: len(liquidations),
 		JITCount:         len(jits),
+		Labels:           LabelsFor(addrs...),
 	}, nil
 }
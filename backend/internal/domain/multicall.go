@@ -0,0 +1,236 @@
+// Package domain: this file decodes batched calldata. multicall(bytes[]),
+// multicall(uint256,bytes[]), aggregate((address,bytes)[]), Safe's
+// execTransaction and wallet execute() wrap the real actions; each wrapped call
+// is decoded again with decodeTransactionInput, giving DecodedTx.Calls as a tree
+// bounded by DECODE_MAX_DEPTH and by one decodeBudget shared across the tree.
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+)
+
+// decodeMaxDepth is how many levels of nested calls are decoded; calls below
+// it are listed with their selector only (InnerCall.Truncated).
+var decodeMaxDepth = 3
+
+// decodeMaxCalls and decodeMaxBytes bound the work of decoding one transaction:
+// the inner calls and router commands decoded anywhere in its tree, and the
+// calldata bytes handed to them.
+const (
+	decodeMaxCalls = 1024
+	decodeMaxBytes = 4 << 20
+)
+
+// decodeBudget is the work left for one DecodeTransactionInput call. Every level
+// of the tree draws from the same budget, so wide batches can't multiply it.
+type decodeBudget struct {
+	calls     int
+	bytes     int
+	exhausted bool
+}
+
+func newDecodeBudget() *decodeBudget {
+	return &decodeBudget{calls: decodeMaxCalls, bytes: decodeMaxBytes}
+}
+
+// take charges one decode of n bytes. Once a charge doesn't fit, everything
+// after it is refused too and the tree is reported as truncated.
+func (b *decodeBudget) take(n int) bool {
+	if b.exhausted || b.calls < 1 || n > b.bytes {
+		b.exhausted = true
+		return false
+	}
+	b.calls--
+	b.bytes -= n
+	return true
+}
+
+// payloadSet remembers the payload offset of each decoded entry of a batch.
+// Encoders give every entry its own encoding; entries whose offsets alias an
+// earlier one only serve to multiply decoding work, so they are not decoded.
+type payloadSet map[int]int
+
+// alias records v as entry index, returning the earlier entry it aliases.
+func (s payloadSet) alias(v ABIValue, index int) (int, bool) {
+	if v.off == 0 {
+		return 0, false
+	}
+	if i, ok := s[v.off]; ok {
+		return i, true
+	}
+	s[v.off] = index
+	return 0, false
+}
+
+func init() {
+	if s := config.EnvOr("DECODE_MAX_DEPTH", ""); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 8 {
+			decodeMaxDepth = n
+		}
+	}
+}
+
+// InnerCall is one call wrapped by a batching or wallet method. Target is the
+// contract it runs against (the batching contract itself for multicall(bytes[])).
+type InnerCall struct {
+	Index     int        `json:"index"`
+	Target    string     `json:"target,omitempty"`
+	Value     string     `json:"value,omitempty"`
+	Operation string     `json:"operation,omitempty"` // "delegatecall" for Safe operation 1
+	Selector  string     `json:"selector,omitempty"`
+	Decoded   *DecodedTx `json:"decoded,omitempty"`
+	Truncated bool       `json:"truncated,omitempty"` // deeper than DECODE_MAX_DEPTH or over the decode budget
+	AliasOf   *int       `json:"alias_of,omitempty"`  // shares the calldata encoding of this earlier entry; not decoded
+}
+
+// decodeInner decodes data as a call to target and appends it to decoded.Calls.
+func decodeInner(decoded *DecodedTx, target, data string, value *big.Int, depth int, b *decodeBudget) *InnerCall {
+	decoded.Calls = append(decoded.Calls, innerCall(len(decoded.Calls), target, data, value, depth, b))
+	return &decoded.Calls[len(decoded.Calls)-1]
+}
+
+// decodeBatchEntry is decodeInner for entry v of a batch, unless its payload
+// aliases an earlier entry's (see payloadSet).
+func decodeBatchEntry(decoded *DecodedTx, seen payloadSet, target string, v ABIValue, depth int, b *decodeBudget) {
+	index := len(decoded.Calls)
+	if of, ok := seen.alias(v, index); ok {
+		decoded.Calls = append(decoded.Calls, InnerCall{Index: index, Target: strings.ToLower(target), AliasOf: &of})
+		return
+	}
+	decodeInner(decoded, target, abiStr(v), nil, depth, b)
+}
+
+// innerCall decodes data as call number index to target, made from depth.
+func innerCall(index int, target, data string, value *big.Int, depth int, b *decodeBudget) InnerCall {
+	ic := InnerCall{Index: index, Target: strings.ToLower(target)}
+	if value != nil && value.Sign() > 0 {
+		ic.Value = hexAmount(value)
+	}
+	if len(data) >= 10 {
+		ic.Selector = strings.ToLower(data[:10])
+	}
+	if depth >= decodeMaxDepth || !b.take(len(data)/2) {
+		ic.Truncated = true
+	} else {
+		var to *string
+		if ic.Target != "" {
+			to = &ic.Target
+		}
+		ic.Decoded = decodeTransactionInput(data, to, firstNonEmpty(ic.Value, "0x0"), nil, depth+1, b)
+	}
+	return ic
+}
+
+// decodeMulticall handles multicall(bytes[]) / multicall(uint256,bytes[]),
+// where every entry is a call back into the same contract.
+func decodeMulticall(decoded *DecodedTx, call *DecodedCall, to string, receipt json.RawMessage, depth int, b *decodeBudget) {
+	decoded.Details["type"] = "multicall"
+	if d, ok := call.argBig("deadline"); ok && d.IsInt64() {
+		decoded.Details["deadline"] = d.Int64()
+	}
+	if data := call.arg("data"); data != nil {
+		seen := payloadSet{}
+		for _, v := range abiList(*data) {
+			decodeBatchEntry(decoded, seen, to, v, depth, b)
+		}
+	}
+	summarizeBatch(decoded, "Multicall", receipt)
+}
+
+// decodeAggregate handles Multicall2's aggregate((address target, bytes callData)[]).
+func decodeAggregate(decoded *DecodedTx, call *DecodedCall, receipt json.RawMessage, depth int, b *decodeBudget) {
+	decoded.Details["type"] = "multicall"
+	if calls := call.arg("calls"); calls != nil {
+		seen := payloadSet{}
+		for _, c := range abiList(*calls) {
+			if f := abiList(c); len(f) == 2 {
+				decodeBatchEntry(decoded, seen, abiStr(f[0]), f[1], depth, b)
+			}
+		}
+	}
+	summarizeBatch(decoded, "Aggregate", receipt)
+}
+
+// decodeExecTransaction handles a Safe execTransaction: one inner call, made
+// as a delegatecall when operation is 1.
+func decodeExecTransaction(decoded *DecodedTx, call *DecodedCall, depth int, b *decodeBudget) {
+	decoded.Action = "Safe Transaction"
+	decoded.Details["type"] = "safe_exec"
+	target, _ := call.argString("to")
+	value, _ := call.argBig("value")
+	data, _ := call.argString("data")
+	if target == "" {
+		decoded.Details["description"] = "Safe multisig transaction (arguments could not be decoded)"
+		return
+	}
+	decoded.Details["target"] = target
+	if value != nil && value.Sign() > 0 {
+		decoded.Details["value_wei"] = hexAmount(value)
+	}
+	ic := decodeInner(decoded, target, data, value, depth, b)
+	if op, ok := call.argBig("operation"); ok && op.Sign() != 0 {
+		ic.Operation = "delegatecall"
+		decoded.Details["operation"] = "delegatecall"
+	}
+	if sigs, ok := call.argString("signatures"); ok {
+		decoded.Details["signature_count"] = (len(sigs) - 2) / 130 // 65 bytes each
+	}
+	decoded.Details["description"] = fmt.Sprintf("Safe multisig executes %s on %s", innerAction(ic), firstNonEmpty(contractName(target), shortenHash(target)))
+}
+
+// summarizeBatch sets the action and description of a batch from its inner
+// calls. A batch containing a swap is reported as the swap, like the routers'
+// own swap methods.
+func summarizeBatch(decoded *DecodedTx, kind string, receipt json.RawMessage) {
+	decoded.Action = kind
+	decoded.Details["call_count"] = len(decoded.Calls)
+	actions := make([]string, len(decoded.Calls))
+	var swap *DecodedTx
+	for i := range decoded.Calls {
+		actions[i] = innerAction(&decoded.Calls[i])
+		if d := decoded.Calls[i].Decoded; d != nil && d.ActionType == "swap" && swap == nil {
+			swap = d
+		}
+	}
+	decoded.Details["description"] = fmt.Sprintf("%s of %d calls: %s", kind, len(decoded.Calls), strings.Join(actions, ", "))
+	if swap != nil {
+		decoded.ActionType = "swap"
+		decoded.Action = "Token Swap"
+		decoded.Details["type"] = "dex_swap"
+		for _, k := range []string{"token_in", "token_out", "amount_in", "amount_out_min", "amount_out", "amount_in_max"} {
+			if v, ok := swap.Details[k]; ok {
+				decoded.Details[k] = v
+			}
+		}
+	}
+	if receipt != nil {
+		extractTransferEvents(decoded, receipt)
+		if swap != nil {
+			calculateSwapPrice(decoded)
+		}
+	}
+}
+
+// innerAction names an inner call for descriptions, looking through wallet
+// calls that wrap a single call.
+func innerAction(ic *InnerCall) string {
+	switch {
+	case ic.Decoded != nil && len(ic.Decoded.Calls) == 1 && (ic.Decoded.ActionType == "execute" || ic.Decoded.ActionType == "safe_exec"):
+		return innerAction(&ic.Decoded.Calls[0])
+	case ic.Decoded != nil && ic.Decoded.MethodName != "" && ic.Decoded.ActionType == "contract_call":
+		return ic.Decoded.MethodName[:strings.IndexByte(ic.Decoded.MethodName, '(')]
+	case ic.Decoded != nil && ic.Decoded.MethodName == "" && ic.Selector != "":
+		return ic.Selector // not in the registry
+	case ic.Decoded != nil && ic.Decoded.Action != "":
+		return ic.Decoded.Action
+	case ic.Selector != "":
+		return ic.Selector
+	}
+	return "call"
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+const mcTarget = "0x2222222222222222222222222222222222222222"
+
+// mcApprove returns approve(spender, amount) calldata; distinct amounts give
+// distinct entries.
+func mcApprove(t testing.TB, amount int) string {
+	t.Helper()
+	return calldata(t, "approve(address,uint256)", mcTarget, fmt.Sprint(amount))
+}
+
+// mcBatch encodes multicall(bytes[]) over entries.
+func mcBatch(t testing.TB, entries ...string) string {
+	t.Helper()
+	in := make([]any, len(entries))
+	for i, s := range entries {
+		in[i] = s
+	}
+	return calldata(t, "multicall(bytes[])", in)
+}
+
+// mcAliasedBatch encodes multicall(bytes[]) with n heads pointing at a single
+// encoding of inner.
+func mcAliasedBatch(t testing.TB, inner string, n int) string {
+	t.Helper()
+	payload := abiHexBytes(t, inner)
+	words := []*big.Int{big.NewInt(32), big.NewInt(int64(n))}
+	for i := 0; i < n; i++ {
+		words = append(words, big.NewInt(int64(n*32)))
+	}
+	words = append(words, big.NewInt(int64(len(payload))))
+	body := make([]byte, (len(payload)+31)/32*32)
+	copy(body, payload)
+	return keccakTopic("multicall(bytes[])")[:10] + hex.EncodeToString(append(abiWords(words...), body...))
+}
+
+func TestDecodeMulticallAliased(t *testing.T) {
+	to := mcTarget
+	d := DecodeTransactionInput(mcAliasedBatch(t, mcApprove(t, 1), 3), &to, "0x0", nil)
+	if d == nil || len(d.Calls) != 3 {
+		t.Fatalf("want 3 calls, got %+v", d)
+	}
+	if d.Calls[0].Decoded == nil || d.Calls[0].AliasOf != nil {
+		t.Fatalf("first entry should be decoded: %+v", d.Calls[0])
+	}
+	for _, c := range d.Calls[1:] {
+		if c.AliasOf == nil || *c.AliasOf != 0 || c.Decoded != nil {
+			t.Errorf("call %d: want alias_of 0 and no decode, got %+v", c.Index, c)
+		}
+	}
+
+	// Identical but separately encoded entries are decoded each time.
+	d = DecodeTransactionInput(mcBatch(t, mcApprove(t, 1), mcApprove(t, 1)), &to, "0x0", nil)
+	for _, c := range d.Calls {
+		if c.AliasOf != nil || c.Decoded == nil {
+			t.Errorf("call %d: want decoded, got %+v", c.Index, c)
+		}
+	}
+}
+
+func TestDecodeBudget(t *testing.T) {
+	to := mcTarget
+	wide := make([]string, decodeMaxCalls+10)
+	for i := range wide {
+		wide[i] = mcApprove(t, i)
+	}
+
+	t.Run("wide batch", func(t *testing.T) {
+		d := DecodeTransactionInput(mcBatch(t, wide...), &to, "0x0", nil)
+		if len(d.Calls) != len(wide) {
+			t.Fatalf("want %d calls, got %d", len(wide), len(d.Calls))
+		}
+		for i, c := range d.Calls {
+			if over := i >= decodeMaxCalls; c.Truncated != over || (c.Decoded == nil) != over {
+				t.Fatalf("call %d: truncated=%v decoded=%v", i, c.Truncated, c.Decoded != nil)
+			}
+		}
+		if d.Details["decode_truncated"] != true {
+			t.Errorf("decode_truncated not set: %v", d.Details)
+		}
+	})
+
+	t.Run("shared across levels", func(t *testing.T) {
+		half := len(wide) / 2
+		d := DecodeTransactionInput(mcBatch(t, mcBatch(t, wide[:half]...), mcBatch(t, wide[half:]...)), &to, "0x0", nil)
+		decoded := 0
+		var walk func(*DecodedTx)
+		walk = func(d *DecodedTx) {
+			for _, c := range d.Calls {
+				if c.Decoded != nil {
+					decoded++
+					walk(c.Decoded)
+				}
+			}
+		}
+		walk(d)
+		if decoded != decodeMaxCalls {
+			t.Errorf("decoded %d calls, want the budget of %d", decoded, decodeMaxCalls)
+		}
+		if d.Details["decode_truncated"] != true {
+			t.Errorf("decode_truncated not set: %v", d.Details)
+		}
+	})
+
+	t.Run("within budget", func(t *testing.T) {
+		d := DecodeTransactionInput(mcBatch(t, wide[:3]...), &to, "0x0", nil)
+		if _, ok := d.Details["decode_truncated"]; ok {
+			t.Errorf("unexpected decode_truncated: %v", d.Details)
+		}
+		for _, c := range d.Calls {
+			if c.Truncated || c.Decoded == nil {
+				t.Errorf("call %d not decoded within budget: %+v", c.Index, c)
+			}
+		}
+	})
+}
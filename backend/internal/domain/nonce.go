@@ -0,0 +1,251 @@
+// Package domain: this file resolves an (account, nonce) slot to the transaction
+// that currently occupies it. Wallet users know the nonce, but the hash changes on
+// every speed-up, so we combine eth_getTransactionCount at several block tags with
+// the mempool history, the txpool view and, for mined nonces, a search of recent
+// blocks for the one that consumed the nonce.
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+	"github.com/you/eth-tx-lifecycle-backend/internal/clients/eth"
+)
+
+// Slot states reported in NonceResult.State.
+const (
+	NonceMined   = "mined"   // nonce < latest count
+	NoncePending = "pending" // in the node's executable pool
+	NonceQueued  = "queued"  // in the pool behind a nonce gap
+	NonceUnused  = "unused"  // nothing known for this nonce yet
+)
+
+// nonceSearchMaxProbes bounds the block search for a mined nonce. Probes walk back
+// exponentially from head, so recent nonces need few calls and no archive state.
+const nonceSearchMaxProbes = 64
+
+// NonceCounts are eth_getTransactionCount results per block tag (nil if the RPC
+// rejected the tag).
+type NonceCounts struct {
+	Latest    *uint64 `json:"latest"`
+	Pending   *uint64 `json:"pending"`
+	Safe      *uint64 `json:"safe,omitempty"`
+	Finalized *uint64 `json:"finalized,omitempty"`
+}
+
+// NonceResult is the /api/track/account/{address}/nonce/{n} response. Seen lists
+// every hash the mempool monitor observed for the slot, oldest first; Track is
+// the TrackTx view of Hash.
+type NonceResult struct {
+	Address string            `json:"address"`
+	Nonce   uint64            `json:"nonce"`
+	State   string            `json:"state"`
+	Hash    string            `json:"hash,omitempty"`
+	Source  string            `json:"source,omitempty"`
+	Counts  NonceCounts       `json:"counts"`
+	Seen    []MempoolTxRecord `json:"seen,omitempty"`
+	Track   *TrackResult      `json:"track,omitempty"`
+}
+
+// slotRecords returns every history record for (from, nonce), oldest first.
+func (s *mempoolStore) slotRecords(from string, nonce uint64) []MempoolTxRecord {
+	key := senderNonceKey(from, fmt.Sprintf("0x%x", nonce))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []MempoolTxRecord
+	for _, rec := range s.byHash {
+		if senderNonceKey(rec.From, rec.Nonce) == key {
+			out = append(out, *rec)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FirstSeen < out[j].FirstSeen })
+	return out
+}
+
+// txCountAt calls eth_getTransactionCount(address, tag).
+func txCountAt(address, tag string) (uint64, error) {
+	raw, err := eth.Call("eth_getTransactionCount", []any{address, tag})
+	if err != nil {
+		return 0, err
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, err
+	}
+	return config.ParseHexUint64(s)
+}
+
+// fetchNonceCounts queries the four tags in parallel.
+func fetchNonceCounts(address string) NonceCounts {
+	var c NonceCounts
+	var g errgroup.Group
+	for tag, dst := range map[string]**uint64{"latest": &c.Latest, "pending": &c.Pending, "safe": &c.Safe, "finalized": &c.Finalized} {
+		g.Go(func() error {
+			if n, err := txCountAt(address, tag); err == nil {
+				*dst = &n
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return c
+}
+
+// findMinedNonce locates the block where address's count went from nonce to
+// nonce+1 and returns the tx hash with that nonce from it.
+func findMinedNonce(address string, nonce uint64) (string, error) {
+	head, err := currentBlockNumber()
+	if err != nil {
+		return "", err
+	}
+	probes := 0
+	countAt := func(n uint64) (uint64, error) {
+		probes++
+		if probes > nonceSearchMaxProbes {
+			return 0, fmt.Errorf("nonce search exceeded %d probes", nonceSearchMaxProbes)
+		}
+		return txCountAt(address, fmt.Sprintf("0x%x", n))
+	}
+	// Invariant: count(hi) > nonce; find lo with count(lo) <= nonce.
+	hi, step := head, uint64(1)
+	lo := uint64(0)
+	for {
+		if step > hi {
+			lo = 0
+			break
+		}
+		c, err := countAt(hi - step)
+		if err != nil {
+			return "", fmt.Errorf("nonce search at block %d (older state may need an archive node): %w", hi-step, err)
+		}
+		if c <= nonce {
+			lo = hi - step
+			break
+		}
+		hi -= step
+		step *= 2
+	}
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		c, err := countAt(mid)
+		if err != nil {
+			return "", fmt.Errorf("nonce search at block %d: %w", mid, err)
+		}
+		if c <= nonce {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	raw, err := eth.Call("eth_getBlockByNumber", []any{fmt.Sprintf("0x%x", hi), true})
+	if err != nil {
+		return "", err
+	}
+	var b struct {
+		Transactions []rpcPendingTx `json:"transactions"`
+	}
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return "", err
+	}
+	for _, tx := range b.Transactions {
+		if n, err := config.ParseHexUint64(tx.Nonce); err == nil && n == nonce && strings.EqualFold(tx.From, address) {
+			return tx.Hash, nil
+		}
+	}
+	return "", fmt.Errorf("nonce %d not found in block %d", nonce, hi)
+}
+
+// currentBlockNumber returns eth_blockNumber.
+func currentBlockNumber() (uint64, error) {
+	raw, err := eth.Call("eth_blockNumber", []any{})
+	if err != nil {
+		return 0, err
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, err
+	}
+	return config.ParseHexUint64(s)
+}
+
+// TrackNonce resolves the tx occupying (address, nonce) and tracks it. Errors
+// wrap ErrInvalidAddress for a malformed address (checked before any RPC call),
+// ErrTxNotFound when no hash is known for the slot and ErrRPCFailure when
+// the account's counts can't be read or a mined nonce can't be located (res is
+// then returned with State NonceMined and no Hash).
+func TrackNonce(address string, nonce uint64) (*NonceResult, error) {
+	if !isAddress(address) {
+		return nil, fmt.Errorf("%w: %q (expected 0x followed by 40 hex digits)", ErrInvalidAddress, address)
+	}
+	address = strings.ToLower(address)
+	res := &NonceResult{Address: address, Nonce: nonce, Counts: fetchNonceCounts(address), Seen: history.slotRecords(address, nonce)}
+	if res.Counts.Latest == nil {
+		return nil, fmt.Errorf("%w: eth_getTransactionCount(%s, latest)", ErrRPCFailure, address)
+	}
+
+	// The newest history record for the slot that hasn't been displaced is the
+	// best hint in every state: it's what the monitor last saw using the nonce.
+	var hinted *MempoolTxRecord
+	for i := len(res.Seen) - 1; i >= 0; i-- {
+		if res.Seen[i].Status != TxStatusReplaced {
+			hinted = &res.Seen[i]
+			break
+		}
+	}
+
+	switch {
+	case nonce < *res.Counts.Latest:
+		res.State = NonceMined
+		for _, rec := range res.Seen {
+			if rec.Status == TxStatusIncluded {
+				res.Hash, res.Source = rec.Hash, "mempool_history"
+			}
+		}
+		if res.Hash == "" {
+			h, err := findMinedNonce(address, nonce)
+			if err != nil {
+				return res, fmt.Errorf("%w: locating mined nonce: %v", ErrRPCFailure, err)
+			}
+			res.Hash, res.Source = h, "block_search"
+		}
+	case res.Counts.Pending != nil && nonce < *res.Counts.Pending:
+		res.State = NoncePending
+		if hinted != nil {
+			res.Hash, res.Source = hinted.Hash, "mempool_history"
+		}
+	default:
+		// Beyond the node's pending count: either gapped or not (or no longer) in the pool.
+		res.State = NonceUnused
+		if hinted != nil {
+			res.Hash, res.Source = hinted.Hash, "mempool_history"
+			if hinted.Status == TxStatusPending {
+				res.State = NonceQueued
+			}
+		}
+	}
+	if res.Hash == "" && res.State != NonceMined {
+		if snd, err := GetTxPoolSender(address); err == nil {
+			for state, txs := range map[string][]PendingTx{NoncePending: snd.Pending, NonceQueued: snd.Queued} {
+				for _, tx := range txs {
+					if n, err := config.ParseHexUint64(tx.Nonce); err == nil && n == nonce && tx.Hash != "" {
+						res.State, res.Hash, res.Source = state, tx.Hash, "txpool"
+					}
+				}
+			}
+		}
+	}
+	if res.Hash == "" {
+		return res, fmt.Errorf("%w: no transaction known for %s nonce %d", ErrTxNotFound, address, nonce)
+	}
+	tr, err := TrackTx(res.Hash)
+	if err != nil {
+		return res, err
+	}
+	res.Track = tr
+	return res, nil
+}
@@ -0,0 +1,328 @@
+// Package domain: this file measures private orderflow. For each new block it
+// checks every included tx against the mempool history: txs the monitor saw
+// pending are public (with how long they waited), the rest never touched the
+// public mempool we can see and count as private — unless the history may have
+// lost txs around that block (evictions, dropped notifications), in which case
+// they are unknown. Shares are over classified txs, per block and per builder
+// (relay builder_pubkey, else extraData / fee recipient).
+package domain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+	"github.com/you/eth-tx-lifecycle-backend/internal/clients/relay"
+)
+
+// TxFlow classifies one included tx. WaitSeconds is block time minus first seen
+// (public txs only). Unknown txs were not in the history, but the history was
+// incomplete for the block's window, so they may be public.
+type TxFlow struct {
+	Hash        string `json:"hash"`
+	Index       int    `json:"index"`
+	Public      bool   `json:"public"`
+	Unknown     bool   `json:"unknown,omitempty"`
+	WaitSeconds int64  `json:"waitSeconds,omitempty"`
+}
+
+// BlockPrivateFlow is the classification of one block. Covered is false for blocks
+// mined before the monitor had been watching for privateFlowWarmup seconds; those
+// are kept for display but left out of aggregates.
+type BlockPrivateFlow struct {
+	Number        uint64   `json:"number"`
+	Hash          string   `json:"hash"`
+	Timestamp     uint64   `json:"timestamp"`
+	Builder       string   `json:"builder,omitempty"`
+	BuilderPubkey string   `json:"builderPubkey,omitempty"`
+	Relay         string   `json:"relay,omitempty"`
+	FeeRecipient  string   `json:"feeRecipient"`
+	TxCount       int      `json:"txCount"`
+	PublicCount   int      `json:"publicCount"`
+	PrivateCount  int      `json:"privateCount"`
+	UnknownCount  int      `json:"unknownCount"`
+	PrivateShare  float64  `json:"privateShare"`
+	MedianWait    float64  `json:"medianWaitSeconds"`
+	P90Wait       float64  `json:"p90WaitSeconds"`
+	Covered       bool     `json:"covered"`
+	Txs           []TxFlow `json:"txs,omitempty"`
+}
+
+// BuilderPrivateFlow aggregates covered blocks by builder.
+type BuilderPrivateFlow struct {
+	Builder       string  `json:"builder"`
+	BuilderPubkey string  `json:"builderPubkey,omitempty"`
+	FeeRecipient  string  `json:"feeRecipient,omitempty"`
+	Blocks        int     `json:"blocks"`
+	TxCount       int     `json:"txCount"`
+	PrivateCount  int     `json:"privateCount"`
+	UnknownCount  int     `json:"unknownCount"`
+	PrivateShare  float64 `json:"privateShare"`
+	MedianWait    float64 `json:"medianWaitSeconds"`
+}
+
+// PrivateFlowReport is the /api/privateflow response.
+type PrivateFlowReport struct {
+	Blocks         []BlockPrivateFlow   `json:"blocks"`
+	Builders       []BuilderPrivateFlow `json:"builders"`
+	CoveredBlocks  int                  `json:"coveredBlocks"`
+	TxCount        int                  `json:"txCount"`
+	PrivateCount   int                  `json:"privateCount"`
+	UnknownCount   int                  `json:"unknownCount"`
+	PrivateShare   float64              `json:"privateShare"`
+	MedianWait     float64              `json:"medianWaitSeconds"`
+	ObservingSince int64                `json:"observingSince"`
+	Note           string               `json:"note"`
+}
+
+// privateFlowWarmup is how long the monitor must have been watching before a
+// block's "never seen" txs are trusted as private rather than just predating us.
+const privateFlowWarmup = 60
+
+// privateFlowSeenSlack tolerates clock skew and propagation: a tx first seen up
+// to one slot after the block timestamp still counts as public.
+const privateFlowSeenSlack = 12
+
+// privateFlowStore is a ring of the most recent classified blocks, oldest first.
+type privateFlowStore struct {
+	mu     sync.RWMutex
+	blocks []*BlockPrivateFlow
+	max    int
+}
+
+var privateFlow = &privateFlowStore{max: 300}
+
+func init() {
+	if s := config.EnvOr("PRIVATE_FLOW_BLOCKS", ""); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 10 && n <= 10000 {
+			privateFlow.max = n
+		}
+	}
+	onHead(privateFlowOnBlock)
+	onReorg(privateFlowOnReorg)
+}
+
+// extraDataLabel renders a block's extraData as text when it is printable ASCII
+// (builders commonly put their name there, e.g. "beaverbuild.org").
+func extraDataLabel(extra string) string {
+	b, err := hex.DecodeString(strings.TrimPrefix(extra, "0x"))
+	if err != nil {
+		return ""
+	}
+	out := make([]byte, 0, len(b))
+	for _, c := range b {
+		if c >= 0x20 && c < 0x7f {
+			out = append(out, c)
+		}
+	}
+	if len(out) < len(b)/2 {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// waitPercentile returns the nearest-rank p-th percentile of sorted seconds.
+func waitPercentile(sorted []int64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted))*p/100+0.5) - 1
+	idx = max(0, min(idx, len(sorted)-1))
+	return float64(sorted[idx])
+}
+
+// privateShare is private / classified, 0 when nothing was classified.
+func privateShare(private, classified int) float64 {
+	if classified <= 0 {
+		return 0
+	}
+	return round4(float64(private) / float64(classified))
+}
+
+func sortedWaits(waits []int64) []int64 {
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	return waits
+}
+
+// privateFlowOnBlock classifies b against the mempool history and queues the
+// relay lookup for its builder.
+func privateFlowOnBlock(b *headBlock) {
+	since := history.observingSince()
+	bf := &BlockPrivateFlow{
+		Number:       b.Number,
+		Hash:         b.Hash,
+		Timestamp:    b.Timestamp,
+		Builder:      extraDataLabel(b.ExtraData),
+		FeeRecipient: b.Miner,
+		TxCount:      len(b.Transactions),
+		Covered:      since != 0 && int64(b.Timestamp) >= since+privateFlowWarmup,
+		Txs:          make([]TxFlow, 0, len(b.Transactions)),
+	}
+	// A tx included in b was broadcast at most a retention window earlier; if
+	// the history lost anything since then, absence doesn't prove privacy.
+	incomplete := history.incompleteSince(int64(b.Timestamp) - int64(history.retention.Seconds()))
+	var waits []int64
+	for i, tx := range b.Transactions {
+		f := TxFlow{Hash: strings.ToLower(tx.Hash), Index: i}
+		if rec, ok := LookupMempoolTx(tx.Hash); ok && rec.FirstSeen <= int64(b.Timestamp)+privateFlowSeenSlack {
+			f.Public = true
+			f.WaitSeconds = max(0, int64(b.Timestamp)-rec.FirstSeen)
+			waits = append(waits, f.WaitSeconds)
+			bf.PublicCount++
+		} else if incomplete {
+			f.Unknown = true
+			bf.UnknownCount++
+		} else {
+			bf.PrivateCount++
+		}
+		bf.Txs = append(bf.Txs, f)
+	}
+	bf.PrivateShare = privateShare(bf.PrivateCount, bf.TxCount-bf.UnknownCount)
+	waits = sortedWaits(waits)
+	bf.MedianWait = waitPercentile(waits, 50)
+	bf.P90Wait = waitPercentile(waits, 90)
+
+	privateFlow.mu.Lock()
+	privateFlow.blocks = append(privateFlow.blocks, bf)
+	if over := len(privateFlow.blocks) - privateFlow.max; over > 0 {
+		privateFlow.blocks = append([]*BlockPrivateFlow(nil), privateFlow.blocks[over:]...)
+	}
+	privateFlow.mu.Unlock()
+	go resolveBlockBuilder(bf)
+}
+
+// resolveBlockBuilder fills in the relay's builder_pubkey for bf. It runs off the
+// head follower so a slow relay can't delay the next block.
+func resolveBlockBuilder(bf *BlockPrivateFlow) {
+	raw, err := relay.Get("/relay/v1/data/bidtraces/proposer_payload_delivered?block_number=" + strconv.FormatUint(bf.Number, 10))
+	if err != nil {
+		return
+	}
+	var entries []struct {
+		BlockHash     string `json:"block_hash"`
+		BuilderPubkey string `json:"builder_pubkey"`
+		Relay         string `json:"relay"`
+	}
+	if json.Unmarshal(raw, &entries) != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.BlockHash != "" && !strings.EqualFold(e.BlockHash, bf.Hash) {
+			continue
+		}
+		privateFlow.mu.Lock()
+		bf.BuilderPubkey = e.BuilderPubkey
+		bf.Relay = e.Relay
+		privateFlow.mu.Unlock()
+		return
+	}
+}
+
+// builderKey groups blocks by relay pubkey when known, else by label or fee recipient.
+func builderKey(bf *BlockPrivateFlow) string {
+	switch {
+	case bf.BuilderPubkey != "":
+		return bf.BuilderPubkey
+	case bf.Builder != "":
+		return bf.Builder
+	default:
+		return bf.FeeRecipient
+	}
+}
+
+// privateFlowOnReorg forgets orphaned blocks; their replacements are dispatched
+// to privateFlowOnBlock right after.
+func privateFlowOnReorg(r *Reorg) {
+	privateFlow.mu.Lock()
+	defer privateFlow.mu.Unlock()
+	kept := privateFlow.blocks[:0]
+	for _, bf := range privateFlow.blocks {
+		if bf.Number <= r.CommonAncestor {
+			kept = append(kept, bf)
+		}
+	}
+	privateFlow.blocks = kept
+}
+
+// GetPrivateFlowBlock returns the full per-tx classification for one block.
+func GetPrivateFlowBlock(number uint64) (*BlockPrivateFlow, error) {
+	privateFlow.mu.RLock()
+	defer privateFlow.mu.RUnlock()
+	for _, bf := range privateFlow.blocks {
+		if bf.Number == number {
+			out := *bf
+			out.Txs = append([]TxFlow(nil), bf.Txs...)
+			return &out, nil
+		}
+	}
+	return nil, fmt.Errorf("block %d not classified (only the last %d blocks since startup are kept)", number, privateFlow.max)
+}
+
+// GetPrivateFlow summarizes the last limit blocks (newest first, without per-tx
+// detail) and aggregates every covered block in the window by builder.
+func GetPrivateFlow(limit int) PrivateFlowReport {
+	privateFlow.mu.RLock()
+	defer privateFlow.mu.RUnlock()
+	rep := PrivateFlowReport{
+		Blocks:         make([]BlockPrivateFlow, 0, limit),
+		Builders:       []BuilderPrivateFlow{},
+		ObservingSince: history.observingSince(),
+		Note:           "Private means the monitor never saw the tx pending. Unknown means it wasn't seen but the history was incomplete around that block (evictions or dropped notifications); shares exclude unknown txs. Coverage depends on the RPC's view of the mempool; a single node misses some public flow.",
+	}
+	type agg struct {
+		row   BuilderPrivateFlow
+		waits []int64
+	}
+	byBuilder := map[string]*agg{}
+	var allWaits []int64
+	for i := len(privateFlow.blocks) - 1; i >= 0; i-- {
+		bf := privateFlow.blocks[i]
+		if len(rep.Blocks) < limit {
+			row := *bf
+			row.Txs = nil
+			rep.Blocks = append(rep.Blocks, row)
+		}
+		if !bf.Covered {
+			continue
+		}
+		rep.CoveredBlocks++
+		rep.TxCount += bf.TxCount
+		rep.PrivateCount += bf.PrivateCount
+		rep.UnknownCount += bf.UnknownCount
+		key := builderKey(bf)
+		a, ok := byBuilder[key]
+		if !ok {
+			a = &agg{row: BuilderPrivateFlow{Builder: bf.Builder, BuilderPubkey: bf.BuilderPubkey, FeeRecipient: bf.FeeRecipient}}
+			byBuilder[key] = a
+		}
+		a.row.Blocks++
+		a.row.TxCount += bf.TxCount
+		a.row.PrivateCount += bf.PrivateCount
+		a.row.UnknownCount += bf.UnknownCount
+		for _, t := range bf.Txs {
+			if t.Public {
+				a.waits = append(a.waits, t.WaitSeconds)
+				allWaits = append(allWaits, t.WaitSeconds)
+			}
+		}
+	}
+	for _, a := range byBuilder {
+		a.row.PrivateShare = privateShare(a.row.PrivateCount, a.row.TxCount-a.row.UnknownCount)
+		a.row.MedianWait = waitPercentile(sortedWaits(a.waits), 50)
+		rep.Builders = append(rep.Builders, a.row)
+	}
+	sort.Slice(rep.Builders, func(i, j int) bool {
+		if rep.Builders[i].Blocks != rep.Builders[j].Blocks {
+			return rep.Builders[i].Blocks > rep.Builders[j].Blocks
+		}
+		return rep.Builders[i].Builder < rep.Builders[j].Builder
+	})
+	rep.PrivateShare = privateShare(rep.PrivateCount, rep.TxCount-rep.UnknownCount)
+	rep.MedianWait = waitPercentile(sortedWaits(allWaits), 50)
+	return rep
+}
@@ -0,0 +1,592 @@
+// Package domain: this file is the signature registry behind txdecode, revert and
+// MEV. It starts from the curated tables (methodSignatures, errorSignatures,
+// knownContracts, mevEventSignatures) and adds function, event and error
+// signatures and contract names from a directory of JSON ABI files (ABI_DIR) and
+// a 4byte-style signature dump (SIGNATURE_DB), re-reading them when they change.
+// A selector shared by several signatures keeps every candidate: the target
+// contract's own ABI comes first, then curated, ABI-file and dump entries, and
+// callers take the first candidate that strictly decodes the input.
+package domain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+)
+
+// Signature kinds in SignatureEntry.Kind.
+const (
+	SigFunction = "function"
+	SigEvent    = "event"
+	SigError    = "error"
+)
+
+// Signature sources in SignatureEntry.Source (ABI files are "abi:<path>").
+const (
+	SourceBuiltin     = "builtin"
+	SourceSignatureDB = "signature_db"
+)
+
+// SignatureEntry is one known signature. Selector is the 4-byte selector for
+// functions and errors and the 32-byte topic0 for events; Contract is set when
+// the entry came from an ABI file bound to an address. Dump entries carry no
+// argument names and may be either a function or an error.
+type SignatureEntry struct {
+	Kind      string   `json:"kind"`
+	Selector  string   `json:"selector"`
+	Signature string   `json:"signature"`
+	Names     []string `json:"names,omitempty"`
+	Source    string   `json:"source"`
+	Contract  string   `json:"contract,omitempty"`
+}
+
+// RegistrySource is one loaded ABI file or signature dump.
+type RegistrySource struct {
+	Path     string `json:"path"`
+	Kind     string `json:"kind"` // abi | signature_db
+	Entries  int    `json:"entries"`
+	Skipped  int    `json:"skipped,omitempty"`
+	Contract string `json:"contract,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// RegistryStatus is the /api/registry summary. Collisions counts selectors with
+// more than one candidate signature.
+type RegistryStatus struct {
+	ABIDir        string           `json:"abiDir,omitempty"`
+	SignatureDB   string           `json:"signatureDb,omitempty"`
+	ReloadSeconds int              `json:"reloadSeconds"`
+	LoadedAt      int64            `json:"loadedAt"`
+	Reloads       int              `json:"reloads"`
+	Functions     int              `json:"functions"`
+	Events        int              `json:"events"`
+	Errors        int              `json:"errors"`
+	Contracts     int              `json:"contracts"`
+	Collisions    int              `json:"collisions"`
+	Sources       []RegistrySource `json:"sources"`
+}
+
+// SignatureLookup is the /api/registry?selector= response.
+type SignatureLookup struct {
+	Selector   string           `json:"selector"`
+	Candidates []SignatureEntry `json:"candidates"`
+	Collision  bool             `json:"collision"`
+}
+
+// sigRegistry is an immutable snapshot; reloads build a new one and swap it in.
+type sigRegistry struct {
+	selectors map[string][]SignatureEntry // 4-byte selector → functions and errors, in priority order
+	events    map[string][]SignatureEntry // topic0 → events
+	scoped    map[string][]SignatureEntry // address+selector → entries from that contract's ABI
+	contracts map[string]string           // address → name
+	status    RegistryStatus
+}
+
+var registry atomic.Pointer[sigRegistry]
+
+func init() {
+	registry.Store(newSigRegistry())
+}
+
+// sigs returns the current registry snapshot.
+func sigs() *sigRegistry { return registry.Load() }
+
+// newSigRegistry returns a registry holding only the curated tables.
+func newSigRegistry() *sigRegistry {
+	r := &sigRegistry{
+		selectors: make(map[string][]SignatureEntry),
+		events:    make(map[string][]SignatureEntry),
+		scoped:    make(map[string][]SignatureEntry),
+		contracts: make(map[string]string, len(knownContracts)),
+	}
+	for sel, sig := range methodSignatures {
+		r.add(SignatureEntry{Kind: SigFunction, Selector: sel, Signature: sig, Names: methodParamNames[sel], Source: SourceBuiltin})
+	}
+	for sel, sig := range errorSignatures {
+		r.add(SignatureEntry{Kind: SigError, Selector: sel, Signature: sig, Source: SourceBuiltin})
+	}
+	for _, sig := range mevEventSignatures {
+		r.add(SignatureEntry{Kind: SigEvent, Selector: keccakTopic(sig), Signature: sig, Source: SourceBuiltin})
+	}
+	for addr, name := range knownContracts {
+		r.contracts[addr] = name
+	}
+	r.status.Sources = []RegistrySource{}
+	r.count()
+	return r
+}
+
+// count fills the status totals.
+func (r *sigRegistry) count() {
+	r.status.Functions, r.status.Errors, r.status.Events, r.status.Collisions = 0, 0, 0, 0
+	for _, list := range r.selectors {
+		if len(list) > 1 {
+			r.status.Collisions++
+		}
+		for _, e := range list {
+			if e.Kind == SigError {
+				r.status.Errors++
+			} else {
+				r.status.Functions++
+			}
+		}
+	}
+	for _, list := range r.events {
+		r.status.Events += len(list)
+	}
+	r.status.Contracts = len(r.contracts)
+}
+
+// add appends e to its selector's candidates unless that signature is already
+// there (the earlier, higher-priority entry wins).
+func (r *sigRegistry) add(e SignatureEntry) bool {
+	m, key := r.selectors, e.Selector
+	if e.Kind == SigEvent {
+		m = r.events
+	}
+	if e.Contract != "" {
+		r.scoped[e.Contract+e.Selector] = appendUnique(r.scoped[e.Contract+e.Selector], e)
+	}
+	before := len(m[key])
+	m[key] = appendUnique(m[key], e)
+	return len(m[key]) > before
+}
+
+func appendUnique(list []SignatureEntry, e SignatureEntry) []SignatureEntry {
+	for _, x := range list {
+		if x.Signature == e.Signature && x.Kind == e.Kind {
+			return list
+		}
+	}
+	return append(list, e)
+}
+
+// candidates returns the entries for selector of the given kinds, the contract
+// at to first. Dump entries match both functions and errors.
+func (r *sigRegistry) candidates(selector, to string, kind string) []SignatureEntry {
+	var out []SignatureEntry
+	keep := func(list []SignatureEntry) {
+		for _, e := range list {
+			if e.Kind == kind || (e.Source == SourceSignatureDB && kind != SigEvent) {
+				out = appendUnique(out, e)
+			}
+		}
+	}
+	if to != "" {
+		keep(r.scoped[to+selector])
+	}
+	if kind == SigEvent {
+		keep(r.events[selector])
+	} else {
+		keep(r.selectors[selector])
+	}
+	return out
+}
+
+// resolveCall decodes input against the function candidates for its selector,
+// preferring the first that decodes strictly. entry is nil when the selector is
+// unknown; if no candidate decodes strictly, the preferred one is decoded
+// leniently and its error returned.
+func resolveCall(input, to string) (call *DecodedCall, entry *SignatureEntry, cands []SignatureEntry, err error) {
+	if len(input) < 10 {
+		return nil, nil, nil, nil
+	}
+	cands = sigs().candidates(strings.ToLower(input[:10]), strings.ToLower(to), SigFunction)
+	if len(cands) == 0 {
+		return nil, nil, nil, nil
+	}
+	for i := range cands {
+		if c, err := decodeCall(cands[i].Signature, input, cands[i].Names, true); err == nil {
+			return c, &cands[i], cands, nil
+		}
+	}
+	call, err = decodeCall(cands[0].Signature, input, cands[0].Names, false)
+	return call, &cands[0], cands, err
+}
+
+// functionSignature returns the preferred function signature for input's
+// selector (the first candidate that decodes it), or "".
+func functionSignature(input, to string) string {
+	_, e, _, _ := resolveCall(input, to)
+	if e == nil {
+		return ""
+	}
+	return e.Signature
+}
+
+// eventSignature returns the event whose topic0 is topic.
+func eventSignature(topic, emitter string) (SignatureEntry, bool) {
+	c := sigs().candidates(strings.ToLower(topic), strings.ToLower(emitter), SigEvent)
+	if len(c) == 0 {
+		return SignatureEntry{}, false
+	}
+	return c[0], true
+}
+
+// contractName returns addr's label name (user labels, curated contracts, ABI
+// files), or "".
+func contractName(addr string) string {
+	l, _ := lookupLabel(addr)
+	return l.Name
+}
+
+// LookupSignature returns every candidate for a 4-byte selector or 32-byte topic.
+func LookupSignature(selector string) (SignatureLookup, bool) {
+	selector = strings.ToLower(selector)
+	if !strings.HasPrefix(selector, "0x") || (len(selector) != 10 && len(selector) != 66) || !isHex(selector[2:]) {
+		return SignatureLookup{}, false
+	}
+	r := sigs()
+	var list []SignatureEntry
+	if len(selector) == 66 {
+		list = r.events[selector]
+	} else {
+		list = r.selectors[selector]
+	}
+	out := SignatureLookup{Selector: selector, Candidates: append([]SignatureEntry{}, list...)}
+	out.Collision = len(out.Candidates) > 1
+	return out, true
+}
+
+// GetRegistryStatus returns what the registry has loaded.
+func GetRegistryStatus() RegistryStatus {
+	return sigs().status
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// registryConfig is read in startRegistry (after .env.local is loaded).
+type registryConfig struct {
+	abiDir string
+	sigDB  string
+	reload time.Duration
+}
+
+// startRegistry loads ABI_DIR and SIGNATURE_DB in the background (the curated
+// tables serve until then) and polls them every REGISTRY_RELOAD_SECONDS
+// (default 30, 0 disables reloading) for changes.
+func startRegistry() {
+	cfg := registryConfig{abiDir: config.EnvOr("ABI_DIR", ""), sigDB: config.EnvOr("SIGNATURE_DB", ""), reload: 30 * time.Second}
+	if s := config.EnvOr("REGISTRY_RELOAD_SECONDS", ""); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 3600 {
+			cfg.reload = time.Duration(n) * time.Second
+		}
+	}
+	if cfg.abiDir == "" && cfg.sigDB == "" {
+		return
+	}
+	go func() {
+		last := registryFingerprint(cfg)
+		loadRegistry(cfg, 0)
+		if cfg.reload == 0 {
+			return
+		}
+		ticker := time.NewTicker(cfg.reload)
+		defer ticker.Stop()
+		for range ticker.C {
+			if fp := registryFingerprint(cfg); fp != last {
+				last = fp
+				loadRegistry(cfg, sigs().status.Reloads+1)
+			}
+		}
+	}()
+}
+
+// registryFingerprint hashes the names, sizes and modification times of the
+// configured files so the poller only rebuilds when something changed.
+func registryFingerprint(cfg registryConfig) uint64 {
+	h := fnv.New64a()
+	note := func(path string, info fs.FileInfo) {
+		fmt.Fprintf(h, "%s|%d|%d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	if cfg.abiDir != "" {
+		_ = filepath.WalkDir(cfg.abiDir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".json") {
+				if info, err := d.Info(); err == nil {
+					note(path, info)
+				}
+			}
+			return nil
+		})
+	}
+	if cfg.sigDB != "" {
+		if info, err := os.Stat(cfg.sigDB); err == nil {
+			note(cfg.sigDB, info)
+		}
+	}
+	return h.Sum64()
+}
+
+// loadRegistry builds a new snapshot from the curated tables plus the files and
+// swaps it in. Unreadable files are reported in the status and skipped.
+func loadRegistry(cfg registryConfig, reloads int) {
+	r := newSigRegistry()
+	r.status = RegistryStatus{ABIDir: cfg.abiDir, SignatureDB: cfg.sigDB, ReloadSeconds: int(cfg.reload / time.Second), Reloads: reloads, Sources: []RegistrySource{}}
+	if cfg.abiDir != "" {
+		var paths []string
+		err := filepath.WalkDir(cfg.abiDir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".json") {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			r.status.Sources = append(r.status.Sources, RegistrySource{Path: cfg.abiDir, Kind: "abi", Error: err.Error()})
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			r.status.Sources = append(r.status.Sources, r.loadABIFile(p))
+		}
+	}
+	if cfg.sigDB != "" {
+		r.status.Sources = append(r.status.Sources, r.loadSignatureDB(cfg.sigDB))
+	}
+	r.count()
+	r.status.LoadedAt = time.Now().Unix()
+	registry.Store(r)
+	log.Printf("registry: %d functions, %d events, %d errors, %d contracts (%d colliding selectors) from %d sources\n",
+		r.status.Functions, r.status.Events, r.status.Errors, r.status.Contracts, r.status.Collisions, len(r.status.Sources))
+}
+
+// abiJSONParam and abiJSONEntry are the Solidity JSON ABI format.
+type abiJSONParam struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Components []abiJSONParam `json:"components"`
+}
+
+type abiJSONEntry struct {
+	Type      string         `json:"type"`
+	Name      string         `json:"name"`
+	Inputs    []abiJSONParam `json:"inputs"`
+	Anonymous bool           `json:"anonymous"`
+}
+
+// canonical renders p as it appears in a signature: tuples expanded, uint/int
+// widened to 256 bits.
+func (p abiJSONParam) canonical() string {
+	if rest, ok := strings.CutPrefix(p.Type, "tuple"); ok {
+		parts := make([]string, len(p.Components))
+		for i, c := range p.Components {
+			parts[i] = c.canonical()
+		}
+		return "(" + strings.Join(parts, ",") + ")" + rest
+	}
+	base, suffix := p.Type, ""
+	if i := strings.IndexByte(base, '['); i >= 0 {
+		base, suffix = base[:i], base[i:]
+	}
+	switch base {
+	case "uint":
+		base = "uint256"
+	case "int":
+		base = "int256"
+	}
+	return base + suffix
+}
+
+var addressFileName = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// loadABIFile reads a bare ABI array or an artifact object ({"abi": [...] or a
+// JSON string, optional "address" and "name"/"contractName"}). A file named
+// after an address (0x….json) binds its ABI to that address.
+func (r *sigRegistry) loadABIFile(path string) RegistrySource {
+	src := RegistrySource{Path: path, Kind: "abi"}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		src.Error = err.Error()
+		return src
+	}
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var entries []abiJSONEntry
+	name, addr := "", ""
+	if addressFileName.MatchString(base) {
+		addr = strings.ToLower(base)
+	} else {
+		name = base
+	}
+	if raw = bytes.TrimSpace(raw); len(raw) > 0 && raw[0] == '[' {
+		err = json.Unmarshal(raw, &entries)
+	} else {
+		var obj struct {
+			ABI          json.RawMessage `json:"abi"`
+			Address      string          `json:"address"`
+			Name         string          `json:"name"`
+			ContractName string          `json:"contractName"`
+		}
+		if err = json.Unmarshal(raw, &obj); err == nil {
+			var s string
+			if json.Unmarshal(obj.ABI, &s) == nil {
+				obj.ABI = json.RawMessage(s)
+			}
+			err = json.Unmarshal(obj.ABI, &entries)
+			if addressFileName.MatchString(obj.Address) {
+				addr = strings.ToLower(obj.Address)
+			}
+			name = firstNonEmpty(obj.ContractName, obj.Name, name)
+		}
+	}
+	if err != nil {
+		src.Error = "invalid ABI JSON: " + err.Error()
+		return src
+	}
+	src.Contract = addr
+	if addr != "" && name != "" {
+		if _, curated := knownContracts[addr]; !curated {
+			r.contracts[addr] = name
+		}
+	}
+	for _, e := range entries {
+		kind := e.Type
+		if kind == "" {
+			kind = SigFunction
+		}
+		if (kind != SigFunction && kind != SigEvent && kind != SigError) || e.Name == "" || (kind == SigEvent && e.Anonymous) {
+			continue
+		}
+		types := make([]string, len(e.Inputs))
+		names := make([]string, len(e.Inputs))
+		for i, in := range e.Inputs {
+			types[i], names[i] = in.canonical(), in.Name
+		}
+		sig := e.Name + "(" + strings.Join(types, ",") + ")"
+		if _, _, err := parseSignature(sig); err != nil {
+			src.Skipped++
+			continue
+		}
+		sel := keccakTopic(sig)
+		if kind != SigEvent {
+			sel = sel[:10]
+			// The action decoders read curated argument names, so those win.
+			if curated, ok := methodParamNames[sel]; ok && methodSignatures[sel] == sig {
+				names = curated
+			}
+		}
+		r.add(SignatureEntry{Kind: kind, Selector: sel, Signature: sig, Names: names, Source: "abi:" + path, Contract: addr})
+		src.Entries++
+	}
+	return src
+}
+
+// dumpSignature finds a function/event signature in a line of a signature dump.
+var dumpSignature = regexp.MustCompile(`[A-Za-z_$][A-Za-z0-9_$]*\([A-Za-z0-9_$,()\[\]]*\)`)
+
+// loadSignatureDB reads a 4byte-style dump: a text/CSV file with one signature
+// per line (selector columns are optional; a line carrying the 32-byte topic
+// marks an event), a JSON object of selector → signature(s), or a directory of
+// files named by selector holding ';'-separated signatures. Selectors are always
+// recomputed from the canonical signature, so wrong or bogus rows can't alias.
+func (r *sigRegistry) loadSignatureDB(path string) RegistrySource {
+	src := RegistrySource{Path: path, Kind: "signature_db"}
+	addLine := func(line string) {
+		m := dumpSignature.FindString(line)
+		if m == "" {
+			return
+		}
+		name, args, err := parseSignature(m)
+		if err != nil {
+			src.Skipped++
+			return
+		}
+		sig := name + args.Canonical
+		topic := keccakTopic(sig)
+		e := SignatureEntry{Kind: SigFunction, Selector: topic[:10], Signature: sig, Source: SourceSignatureDB}
+		if strings.Contains(strings.ToLower(line), topic[2:]) {
+			e.Kind, e.Selector = SigEvent, topic
+		}
+		if r.add(e) {
+			src.Entries++
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		src.Error = err.Error()
+		return src
+	}
+	switch {
+	case info.IsDir():
+		files, err := os.ReadDir(path)
+		if err != nil {
+			src.Error = err.Error()
+			return src
+		}
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			raw, err := os.ReadFile(filepath.Join(path, f.Name()))
+			if err != nil {
+				continue
+			}
+			for _, sig := range strings.FieldsFunc(string(raw), func(c rune) bool { return c == ';' || c == '\n' }) {
+				addLine(f.Name() + " " + sig)
+			}
+		}
+	case strings.EqualFold(filepath.Ext(path), ".json"):
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			src.Error = err.Error()
+			return src
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			src.Error = "invalid signature JSON: " + err.Error()
+			return src
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			var one string
+			var many []string
+			if json.Unmarshal(obj[k], &one) == nil {
+				many = []string{one}
+			} else if json.Unmarshal(obj[k], &many) != nil {
+				src.Skipped++
+				continue
+			}
+			for _, sig := range many {
+				addLine(k + " " + sig)
+			}
+		}
+	default:
+		f, err := os.Open(path)
+		if err != nil {
+			src.Error = err.Error()
+			return src
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		for sc.Scan() {
+			addLine(sc.Text())
+		}
+		if err := sc.Err(); err != nil {
+			src.Error = err.Error()
+		}
+	}
+	return src
+}
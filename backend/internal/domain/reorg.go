@@ -0,0 +1,306 @@
+// Package domain: this file detects chain reorganizations. Every new head is
+// linked to its parent against a window of recent canonical blocks; when the
+// parent doesn't match, the follower walks the new head's ancestry back to the
+// common ancestor, records the orphaned and replacement blocks, and remembers
+// which transactions were reorged out and where they were re-included.
+package domain
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+)
+
+// Per-transaction reorg states reported in TxReorgStatus.Status.
+const (
+	ReorgStatusOut        = "reorged_out" // its block was orphaned and no canonical block includes it yet
+	ReorgStatusReincluded = "reincluded"  // orphaned, then included again in NewBlock
+)
+
+// maxReorgTxs bounds the per-transaction reorg statuses kept for TrackTx.
+const maxReorgTxs = 5000
+
+// ReorgBlock is one block on either side of a reorg.
+type ReorgBlock struct {
+	Number    uint64 `json:"number"`
+	Hash      string `json:"hash"`
+	Timestamp uint64 `json:"timestamp"`
+	TxCount   int    `json:"txCount"`
+}
+
+// ReorgReinclusion is a tx from an orphaned block that the new chain also includes.
+type ReorgReinclusion struct {
+	Hash         string `json:"hash"`
+	OldBlock     uint64 `json:"oldBlock"`
+	NewBlock     uint64 `json:"newBlock"`
+	NewBlockHash string `json:"newBlockHash"`
+}
+
+// Reorg describes one detected reorganization. Depth is the number of orphaned
+// blocks; OldBlocks and NewBlocks are ascending from CommonAncestor+1.
+type Reorg struct {
+	DetectedAt     int64              `json:"detectedAt"`
+	Depth          int                `json:"depth"`
+	CommonAncestor uint64             `json:"commonAncestor"`
+	OldBlocks      []ReorgBlock       `json:"oldBlocks"`
+	NewBlocks      []ReorgBlock       `json:"newBlocks"`
+	ReorgedOut     []string           `json:"reorgedOut"`
+	Reincluded     []ReorgReinclusion `json:"reincluded"`
+}
+
+// TxReorgStatus is what the follower knows about a tx whose block was orphaned.
+// NewBlock/NewBlockHash are set once it is re-included.
+type TxReorgStatus struct {
+	Status            string `json:"status"`
+	OrphanedBlock     uint64 `json:"orphanedBlock"`
+	OrphanedBlockHash string `json:"orphanedBlockHash"`
+	ReorgedAt         int64  `json:"reorgedAt"`
+	NewBlock          uint64 `json:"newBlock,omitempty"`
+	NewBlockHash      string `json:"newBlockHash,omitempty"`
+}
+
+// ReorgReport is the /api/reorgs response (newest reorg first).
+type ReorgReport struct {
+	Reorgs        []Reorg `json:"reorgs"`
+	Count         int     `json:"count"`
+	Total         int     `json:"total"`
+	Window        int     `json:"window"`
+	CanonicalHead uint64  `json:"canonicalHead"`
+	CanonicalTail uint64  `json:"canonicalTail"`
+}
+
+// chainBlock is a canonical block as kept in the window.
+type chainBlock struct {
+	Number     uint64
+	Hash       string
+	ParentHash string
+	Timestamp  uint64
+	Txs        []string
+}
+
+// reorgStore holds the canonical window (contiguous, ascending), the detected
+// reorgs and the per-tx statuses. Only the head follower writes chain.
+type reorgStore struct {
+	mu        sync.RWMutex
+	chain     []chainBlock
+	window    int
+	reorgs    []Reorg
+	maxReorgs int
+	total     int
+	txs       map[string]*TxReorgStatus
+	txOrder   []string
+}
+
+var reorgs = &reorgStore{window: 64, maxReorgs: 100, txs: make(map[string]*TxReorgStatus)}
+
+// reorgListeners run from the head follower after a reorg is recorded and before
+// the replacement blocks are dispatched to headListeners. Register from init via onReorg.
+var reorgListeners []func(*Reorg)
+
+func onReorg(fn func(*Reorg)) {
+	reorgListeners = append(reorgListeners, fn)
+}
+
+func init() {
+	if s := config.EnvOr("REORG_WINDOW", ""); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 8 && n <= 1024 {
+			reorgs.window = n
+		}
+	}
+	if s := config.EnvOr("REORG_HISTORY", ""); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 10 && n <= 10000 {
+			reorgs.maxReorgs = n
+		}
+	}
+}
+
+func toChainBlock(b *headBlock) chainBlock {
+	cb := chainBlock{Number: b.Number, Hash: b.Hash, ParentHash: b.ParentHash, Timestamp: b.Timestamp, Txs: make([]string, len(b.Transactions))}
+	for i, tx := range b.Transactions {
+		cb.Txs[i] = strings.ToLower(tx.Hash)
+	}
+	return cb
+}
+
+func (cb chainBlock) summary() ReorgBlock {
+	return ReorgBlock{Number: cb.Number, Hash: cb.Hash, Timestamp: cb.Timestamp, TxCount: len(cb.Txs)}
+}
+
+// at returns the canonical block at number if it is in the window.
+func (s *reorgStore) at(number uint64) (chainBlock, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.chain) == 0 || number < s.chain[0].Number || number > s.chain[len(s.chain)-1].Number {
+		return chainBlock{}, false
+	}
+	return s.chain[number-s.chain[0].Number], true
+}
+
+// appendLocked extends the window with b, re-including any reorged-out txs it
+// carries. Caller holds mu.
+func (s *reorgStore) appendLocked(b chainBlock) {
+	s.chain = append(s.chain, b)
+	if over := len(s.chain) - s.window; over > 0 {
+		s.chain = append([]chainBlock(nil), s.chain[over:]...)
+	}
+	if len(s.txs) == 0 {
+		return
+	}
+	for _, h := range b.Txs {
+		if st, ok := s.txs[h]; ok && st.Status == ReorgStatusOut {
+			st.Status = ReorgStatusReincluded
+			st.NewBlock, st.NewBlockHash = b.Number, b.Hash
+		}
+	}
+}
+
+// noteTxLocked records a tx's reorg status, evicting the oldest beyond maxReorgTxs. Caller holds mu.
+func (s *reorgStore) noteTxLocked(hash string, st *TxReorgStatus) {
+	if _, ok := s.txs[hash]; !ok {
+		s.txOrder = append(s.txOrder, hash)
+	}
+	s.txs[hash] = st
+	for len(s.txs) > maxReorgTxs && len(s.txOrder) > 0 {
+		delete(s.txs, s.txOrder[0])
+		s.txOrder = s.txOrder[1:]
+	}
+}
+
+// linkHead adds b to the canonical window. If b doesn't extend the window's tip
+// it walks b's ancestry (by parent hash) back to a block in the window, records
+// the reorg and returns it with the replacement blocks between the common
+// ancestor and b, ascending. A gap in the follower (catch-up skipped blocks) or a
+// reorg deeper than the window restarts the window at b.
+func linkHead(b *headBlock) (*Reorg, []*headBlock) {
+	if b.Number == 0 {
+		reorgs.restart(b)
+		return nil, nil
+	}
+	tip, ok := reorgs.at(b.Number - 1)
+	if !ok {
+		reorgs.restart(b)
+		return nil, nil
+	}
+	if tip.Hash == b.ParentHash && reorgs.isTip(tip.Number) {
+		reorgs.mu.Lock()
+		reorgs.appendLocked(toChainBlock(b))
+		reorgs.mu.Unlock()
+		return nil, nil
+	}
+
+	// Walk back until the child's parent is the canonical block at that height.
+	var replay []*headBlock
+	child := b
+	var ancestor uint64
+	for {
+		stored, ok := reorgs.at(child.Number - 1)
+		if !ok {
+			log.Printf("heads: reorg at block %d is deeper than the %d-block window; restarting it\n", b.Number, reorgs.window)
+			reorgs.restart(b)
+			return nil, nil
+		}
+		if stored.Hash == child.ParentHash {
+			ancestor = stored.Number
+			break
+		}
+		parent, err := fetchHeadBlockByHash(child.ParentHash)
+		if err != nil {
+			log.Printf("heads: reorg walk failed at %s: %v\n", child.ParentHash, err)
+			reorgs.restart(b)
+			return nil, nil
+		}
+		replay = append([]*headBlock{parent}, replay...)
+		child = parent
+	}
+
+	now := time.Now().Unix()
+	r := &Reorg{DetectedAt: now, CommonAncestor: ancestor, ReorgedOut: []string{}, Reincluded: []ReorgReinclusion{}}
+	newChain := make([]chainBlock, 0, len(replay)+1)
+	included := map[string]chainBlock{}
+	for _, nb := range append(replay, b) {
+		cb := toChainBlock(nb)
+		newChain = append(newChain, cb)
+		r.NewBlocks = append(r.NewBlocks, cb.summary())
+		for _, h := range cb.Txs {
+			included[h] = cb
+		}
+	}
+
+	reorgs.mu.Lock()
+	keep := int(ancestor-reorgs.chain[0].Number) + 1
+	orphaned := reorgs.chain[keep:]
+	for _, ob := range orphaned {
+		r.OldBlocks = append(r.OldBlocks, ob.summary())
+		for _, h := range ob.Txs {
+			st := &TxReorgStatus{Status: ReorgStatusOut, OrphanedBlock: ob.Number, OrphanedBlockHash: ob.Hash, ReorgedAt: now}
+			if nb, ok := included[h]; ok {
+				st.Status, st.NewBlock, st.NewBlockHash = ReorgStatusReincluded, nb.Number, nb.Hash
+				r.Reincluded = append(r.Reincluded, ReorgReinclusion{Hash: h, OldBlock: ob.Number, NewBlock: nb.Number, NewBlockHash: nb.Hash})
+			} else {
+				r.ReorgedOut = append(r.ReorgedOut, h)
+			}
+			reorgs.noteTxLocked(h, st)
+		}
+	}
+	r.Depth = len(orphaned)
+	reorgs.chain = append([]chainBlock(nil), reorgs.chain[:keep]...)
+	for _, cb := range newChain {
+		reorgs.appendLocked(cb)
+	}
+	reorgs.reorgs = append(reorgs.reorgs, *r)
+	if over := len(reorgs.reorgs) - reorgs.maxReorgs; over > 0 {
+		reorgs.reorgs = append([]Reorg(nil), reorgs.reorgs[over:]...)
+	}
+	reorgs.total++
+	reorgs.mu.Unlock()
+
+	log.Printf("heads: reorg of depth %d at block %d (%d txs reorged out, %d re-included)\n", r.Depth, ancestor+1, len(r.ReorgedOut), len(r.Reincluded))
+	publishEvent(TopicHeads, "reorg", r)
+	return r, replay
+}
+
+// restart discards the window and starts it again at b.
+func (s *reorgStore) restart(b *headBlock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chain = nil
+	s.appendLocked(toChainBlock(b))
+}
+
+// isTip reports whether number is the newest block in the window.
+func (s *reorgStore) isTip(number uint64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.chain) > 0 && s.chain[len(s.chain)-1].Number == number
+}
+
+// TxReorgStatusFor returns the reorg status of hash, if one of its blocks was orphaned.
+func TxReorgStatusFor(hash string) (TxReorgStatus, bool) {
+	reorgs.mu.RLock()
+	defer reorgs.mu.RUnlock()
+	st, ok := reorgs.txs[strings.ToLower(hash)]
+	if !ok {
+		return TxReorgStatus{}, false
+	}
+	return *st, true
+}
+
+// GetReorgs returns the last limit detected reorgs, newest first.
+func GetReorgs(limit int) ReorgReport {
+	reorgs.mu.RLock()
+	defer reorgs.mu.RUnlock()
+	rep := ReorgReport{Reorgs: make([]Reorg, 0, min(limit, len(reorgs.reorgs))), Total: reorgs.total, Window: reorgs.window}
+	for i := len(reorgs.reorgs) - 1; i >= 0 && len(rep.Reorgs) < limit; i-- {
+		rep.Reorgs = append(rep.Reorgs, reorgs.reorgs[i])
+	}
+	rep.Count = len(rep.Reorgs)
+	if n := len(reorgs.chain); n > 0 {
+		rep.CanonicalTail = reorgs.chain[0].Number
+		rep.CanonicalHead = reorgs.chain[n-1].Number
+	}
+	return rep
+}
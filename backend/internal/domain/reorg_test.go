@@ -0,0 +1,198 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// reorgBlock returns block n of fork (e.g. "a"); its hash is derived from both.
+func reorgBlock(fork string, n uint64, parent string, txs ...string) *headBlock {
+	b := &headBlock{Number: n, Hash: fmt.Sprintf("0x%s%02d", fork, n), ParentHash: parent, Timestamp: 1000 + 12*n}
+	for _, h := range txs {
+		b.Transactions = append(b.Transactions, rpcPendingTx{Hash: h})
+	}
+	return b
+}
+
+// withReorgStore runs the test against an empty window of the given size and
+// serves fetchHeadBlockByHash from blocks.
+func withReorgStore(t *testing.T, window int, blocks ...*headBlock) {
+	t.Helper()
+	prevStore, prevFetch := reorgs, fetchHeadBlockByHash
+	reorgs = &reorgStore{window: window, maxReorgs: 100, txs: make(map[string]*TxReorgStatus)}
+	byHash := map[string]*headBlock{}
+	for _, b := range blocks {
+		byHash[b.Hash] = b
+	}
+	fetchHeadBlockByHash = func(hash string) (*headBlock, error) {
+		if b, ok := byHash[hash]; ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("block %s not found", hash)
+	}
+	t.Cleanup(func() { reorgs, fetchHeadBlockByHash = prevStore, prevFetch })
+}
+
+// linkChain links blocks in order, failing on any reorg.
+func linkChain(t *testing.T, blocks ...*headBlock) {
+	t.Helper()
+	for _, b := range blocks {
+		if r, _ := linkHead(b); r != nil {
+			t.Fatalf("unexpected reorg linking block %d: %+v", b.Number, r)
+		}
+	}
+}
+
+// canonicalHashes lists the window's hashes, ascending.
+func canonicalHashes() []string {
+	reorgs.mu.RLock()
+	defer reorgs.mu.RUnlock()
+	out := make([]string, len(reorgs.chain))
+	for i, cb := range reorgs.chain {
+		out[i] = cb.Hash
+	}
+	return out
+}
+
+// forkA is a five-block chain; block 4 carries tx1 and tx2.
+func forkA() []*headBlock {
+	a1 := reorgBlock("a", 1, "0xgenesis")
+	a2 := reorgBlock("a", 2, a1.Hash)
+	a3 := reorgBlock("a", 3, a2.Hash)
+	a4 := reorgBlock("a", 4, a3.Hash, "0xtx1", "0xtx2")
+	a5 := reorgBlock("a", 5, a4.Hash, "0xtx3")
+	return []*headBlock{a1, a2, a3, a4, a5}
+}
+
+func TestLinkHeadExtends(t *testing.T) {
+	withReorgStore(t, 8)
+	a := forkA()
+	linkChain(t, a...)
+	if got, want := canonicalHashes(), []string{"0xa01", "0xa02", "0xa03", "0xa04", "0xa05"}; !slices.Equal(got, want) {
+		t.Errorf("window = %v, want %v", got, want)
+	}
+	if rep := GetReorgs(10); rep.Total != 0 || rep.CanonicalTail != 1 || rep.CanonicalHead != 5 {
+		t.Errorf("report = %+v", rep)
+	}
+
+	// The window keeps only the newest blocks.
+	linkChain(t, reorgBlock("a", 6, "0xa05"), reorgBlock("a", 7, "0xa06"), reorgBlock("a", 8, "0xa07"), reorgBlock("a", 9, "0xa08"))
+	if rep := GetReorgs(10); rep.CanonicalTail != 2 || rep.CanonicalHead != 9 {
+		t.Errorf("after sliding: tail %d head %d, want 2..9", rep.CanonicalTail, rep.CanonicalHead)
+	}
+}
+
+func TestLinkHeadSameHeight(t *testing.T) {
+	withReorgStore(t, 8)
+	a := forkA()
+	linkChain(t, a...)
+	b5 := reorgBlock("b", 5, a[3].Hash, "0xtx4")
+	r, replay := linkHead(b5)
+	if r == nil {
+		t.Fatal("no reorg for a replacement at the same height")
+	}
+	if r.Depth != 1 || r.CommonAncestor != 4 || len(replay) != 0 {
+		t.Errorf("depth %d ancestor %d replay %d, want 1, 4, 0", r.Depth, r.CommonAncestor, len(replay))
+	}
+	if len(r.OldBlocks) != 1 || r.OldBlocks[0].Hash != "0xa05" || len(r.NewBlocks) != 1 || r.NewBlocks[0].Hash != "0xb05" {
+		t.Errorf("old %+v new %+v", r.OldBlocks, r.NewBlocks)
+	}
+	if !slices.Equal(r.ReorgedOut, []string{"0xtx3"}) || len(r.Reincluded) != 0 {
+		t.Errorf("reorged out %v, reincluded %v", r.ReorgedOut, r.Reincluded)
+	}
+	if got := canonicalHashes(); got[len(got)-1] != "0xb05" {
+		t.Errorf("tip = %s, want 0xb05", got[len(got)-1])
+	}
+	if st, ok := TxReorgStatusFor("0xTX3"); !ok || st.Status != ReorgStatusOut || st.OrphanedBlockHash != "0xa05" {
+		t.Errorf("tx3 status = %+v, %v", st, ok)
+	}
+}
+
+func TestLinkHeadDepthTwo(t *testing.T) {
+	a := forkA()
+	b4 := reorgBlock("b", 4, a[2].Hash)
+	b5 := reorgBlock("b", 5, b4.Hash, "0xtx1")
+	b6 := reorgBlock("b", 6, b5.Hash)
+	withReorgStore(t, 8, b4, b5, b6)
+	linkChain(t, a...)
+
+	r, replay := linkHead(b6)
+	if r == nil {
+		t.Fatal("no reorg")
+	}
+	if r.Depth != 2 || r.CommonAncestor != 3 {
+		t.Errorf("depth %d ancestor %d, want 2 and 3", r.Depth, r.CommonAncestor)
+	}
+	if len(replay) != 2 || replay[0] != b4 || replay[1] != b5 {
+		t.Errorf("replay = %v, want b4, b5", replay)
+	}
+	if got, want := canonicalHashes(), []string{"0xa01", "0xa02", "0xa03", "0xb04", "0xb05", "0xb06"}; !slices.Equal(got, want) {
+		t.Errorf("window = %v, want %v", got, want)
+	}
+	wantIn := []ReorgReinclusion{{Hash: "0xtx1", OldBlock: 4, NewBlock: 5, NewBlockHash: "0xb05"}}
+	if !slices.Equal(r.Reincluded, wantIn) {
+		t.Errorf("reincluded = %+v, want %+v", r.Reincluded, wantIn)
+	}
+	if !slices.Equal(r.ReorgedOut, []string{"0xtx2", "0xtx3"}) {
+		t.Errorf("reorged out = %v", r.ReorgedOut)
+	}
+	if st, _ := TxReorgStatusFor("0xtx1"); st.Status != ReorgStatusReincluded || st.NewBlockHash != "0xb05" {
+		t.Errorf("tx1 status = %+v", st)
+	}
+	if st, _ := TxReorgStatusFor("0xtx2"); st.Status != ReorgStatusOut || st.OrphanedBlock != 4 {
+		t.Errorf("tx2 status = %+v", st)
+	}
+
+	// A later canonical block carrying tx2 re-includes it.
+	linkChain(t, reorgBlock("b", 7, b6.Hash, "0xtx2"))
+	if st, _ := TxReorgStatusFor("0xtx2"); st.Status != ReorgStatusReincluded || st.NewBlock != 7 || st.NewBlockHash != "0xb07" {
+		t.Errorf("tx2 after b7 = %+v", st)
+	}
+	if rep := GetReorgs(10); rep.Total != 1 || rep.Count != 1 || rep.Reorgs[0].Depth != 2 {
+		t.Errorf("report = %+v", rep)
+	}
+}
+
+func TestLinkHeadRestart(t *testing.T) {
+	t.Run("gap", func(t *testing.T) {
+		withReorgStore(t, 8)
+		linkChain(t, forkA()...)
+		linkChain(t, reorgBlock("a", 20, "0xa19"))
+		if got := canonicalHashes(); !slices.Equal(got, []string{"0xa20"}) {
+			t.Errorf("window = %v, want a restart at block 20", got)
+		}
+	})
+
+	t.Run("deeper than the window", func(t *testing.T) {
+		// forkA's window of 4 holds blocks 2..5; fork b branches off block 1.
+		a := forkA()
+		var bs []*headBlock
+		parent := a[0].Hash
+		for n := uint64(2); n <= 6; n++ {
+			b := reorgBlock("b", n, parent)
+			bs = append(bs, b)
+			parent = b.Hash
+		}
+		withReorgStore(t, 4, bs...)
+		linkChain(t, a...)
+		if r, replay := linkHead(bs[len(bs)-1]); r != nil || replay != nil {
+			t.Errorf("reorg past the window reported: %+v", r)
+		}
+		if got := canonicalHashes(); !slices.Equal(got, []string{"0xb06"}) {
+			t.Errorf("window = %v, want a restart at 0xb06", got)
+		}
+		if rep := GetReorgs(10); rep.Total != 0 {
+			t.Errorf("total = %d, want 0", rep.Total)
+		}
+	})
+
+	t.Run("ancestry unavailable", func(t *testing.T) {
+		withReorgStore(t, 8)
+		linkChain(t, forkA()...)
+		linkChain(t, reorgBlock("c", 6, "0xc05"))
+		if got := canonicalHashes(); !slices.Equal(got, []string{"0xc06"}) {
+			t.Errorf("window = %v, want a restart at 0xc06", got)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+// Package domain: this file classifies replace-by-fee events detected by the
+// mempool history (same sender + nonce, new hash) and resolves replacement chains
+// so TrackTx can say "this hash was replaced by 0x…".
+package domain
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Replacement types set on the displaced MempoolTxRecord.
+const (
+	ReplacementSpeedUp = "speed_up" // same call, higher fee
+	ReplacementCancel  = "cancel"   // 0-value self-send with no calldata
+	ReplacementReplace = "replace"  // anything else reusing the nonce
+)
+
+// maxReplacementChain bounds chain walks in case of a cycle from inconsistent data.
+const maxReplacementChain = 16
+
+// pendingTxFee returns the fee cap the sender bid, in wei (0 if unknown):
+// maxFeePerGas for EIP-1559-style txs, gasPrice for legacy ones.
+func pendingTxFee(tx PendingTx) *big.Int {
+	if v := hexBig(tx.MaxFeePerGas); v != nil {
+		return v
+	}
+	if v := hexBig(tx.GasPrice); v != nil {
+		return v
+	}
+	return new(big.Int)
+}
+
+// pendingTxTip returns the priority fee bid in wei (gasPrice for legacy txs).
+func pendingTxTip(tx PendingTx) *big.Int {
+	if v := hexBig(tx.MaxPriorityFeePerGas); v != nil {
+		return v
+	}
+	return pendingTxFee(tx)
+}
+
+func isEmptyHex(h string) bool {
+	return h == "" || h == "0x" || strings.TrimLeft(strings.TrimPrefix(h, "0x"), "0") == ""
+}
+
+// classifyReplacement decides how next relates to the prev tx it displaced.
+func classifyReplacement(prev, next PendingTx) string {
+	if isEmptyHex(next.Value) && isEmptyHex(next.Input) && next.To != nil && strings.EqualFold(*next.To, next.From) {
+		return ReplacementCancel
+	}
+	sameTarget := (prev.To == nil && next.To == nil) || (prev.To != nil && next.To != nil && strings.EqualFold(*prev.To, *next.To))
+	bumped := pendingTxFee(next).Cmp(pendingTxFee(prev)) > 0 || pendingTxTip(next).Cmp(pendingTxTip(prev)) > 0
+	if sameTarget && strings.EqualFold(prev.Input, next.Input) && bumped {
+		return ReplacementSpeedUp
+	}
+	return ReplacementReplace
+}
+
+// ReplacementInfo is hash's place in a replacement chain. Replaces is set on a
+// replacement; ReplacedBy, Type, FinalHash and Chain on a displaced tx.
+type ReplacementInfo struct {
+	Replaces   string            `json:"replaces,omitempty"`
+	ReplacedBy string            `json:"replaced_by,omitempty"`
+	Type       string            `json:"type,omitempty"`
+	FinalHash  string            `json:"final_hash,omitempty"`
+	Chain      []ReplacementStep `json:"chain,omitempty"`
+}
+
+// ReplacementStep is one hash in a chain. Status is empty for hashes the
+// monitor only knows by reference (e.g. a replacement mined without being seen).
+type ReplacementStep struct {
+	Hash          string `json:"hash"`
+	Type          string `json:"type,omitempty"`
+	Status        string `json:"status,omitempty"`
+	IncludedBlock uint64 `json:"included_block,omitempty"`
+}
+
+// replacementInfo describes hash's place in a replacement chain for TrackTx, or
+// nil if the mempool monitor never saw it replaced or replacing anything.
+func replacementInfo(hash string) *ReplacementInfo {
+	rec, ok := LookupMempoolTx(hash)
+	if !ok || (rec.ReplacedBy == "" && rec.Replaces == "") {
+		return nil
+	}
+	info := &ReplacementInfo{Replaces: rec.Replaces}
+	if rec.ReplacedBy == "" {
+		return info
+	}
+	info.ReplacedBy = rec.ReplacedBy
+	info.Type = rec.ReplacementType
+	info.Chain = []ReplacementStep{{Hash: rec.Hash, Type: rec.ReplacementType}}
+	final := rec.ReplacedBy
+	for range maxReplacementChain {
+		next, ok := LookupMempoolTx(final)
+		if !ok {
+			info.Chain = append(info.Chain, ReplacementStep{Hash: final})
+			break
+		}
+		info.Chain = append(info.Chain, ReplacementStep{Hash: next.Hash, Type: next.ReplacementType, Status: next.Status, IncludedBlock: next.IncludedBlock})
+		if next.ReplacedBy == "" {
+			break
+		}
+		final = next.ReplacedBy
+	}
+	info.FinalHash = final
+	return info
+}
@@ -0,0 +1,260 @@
+// Package domain: this file explains why a mined transaction failed. The tx is
+// replayed with eth_call against its parent block's state and the revert data is
+// decoded as Error(string), Panic(uint256) or a custom error known to the registry.
+package domain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+	"github.com/you/eth-tx-lifecycle-backend/internal/clients/eth"
+)
+
+// Revert kinds reported in RevertInfo.Kind.
+const (
+	RevertErrorString   = "error_string"   // require(cond, "reason") / revert("reason")
+	RevertPanic         = "panic"          // assert, overflow, division by zero, ...
+	RevertCustomError   = "custom_error"   // error Foo(...) with a known selector
+	RevertUnknownError  = "unknown_error"  // revert data with an unknown selector
+	RevertNoData        = "no_data"        // bare revert() or a node that drops the data
+	RevertOutOfGas      = "out_of_gas"     // used the whole gas limit and the replay reverted without data
+	RevertNotReproduced = "not_reproduced" // the replay succeeded
+)
+
+const (
+	errorStringSelector = "0x08c379a0" // Error(string)
+	panicSelector       = "0x4e487b71" // Panic(uint256)
+)
+
+// panicReasons are the Solidity compiler's Panic(uint256) codes.
+var panicReasons = map[uint64]string{
+	0x00: "generic compiler panic",
+	0x01: "assertion failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "corrupt storage byte array",
+	0x31: "pop() on an empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory (allocation too large)",
+	0x51: "call to a zero-initialized function pointer",
+}
+
+// errorSignatures maps custom error selectors to their signatures: OpenZeppelin,
+// Uniswap (routers, Permit2) and ERC-4337 errors seen on popular contracts. It is
+// built during variable initialization so the registry's init can read it.
+var errorSignatures = func() map[string]string {
+	m := map[string]string{}
+	for _, sig := range []string{
+		"ERC20InsufficientBalance(address,uint256,uint256)",
+		"ERC20InsufficientAllowance(address,uint256,uint256)",
+		"ERC20InvalidSender(address)",
+		"ERC20InvalidReceiver(address)",
+		"ERC20InvalidApprover(address)",
+		"ERC20InvalidSpender(address)",
+		"ERC721NonexistentToken(uint256)",
+		"ERC721IncorrectOwner(address,uint256,address)",
+		"ERC721InsufficientApproval(address,uint256)",
+		"OwnableUnauthorizedAccount(address)",
+		"SafeERC20FailedOperation(address)",
+		"AddressInsufficientBalance(address)",
+		"FailedInnerCall()",
+		"ReentrancyGuardReentrantCall()",
+		"EnforcedPause()",
+		"V2TooLittleReceived()",
+		"V2TooMuchRequested()",
+		"V2InvalidPath()",
+		"V3TooLittleReceived()",
+		"V3TooMuchRequested()",
+		"V3InvalidSwap()",
+		"V3InvalidAmountOut()",
+		"TransactionDeadlinePassed()",
+		"ExecutionFailed(uint256,bytes)",
+		"InsufficientETH()",
+		"InsufficientToken()",
+		"InvalidCommandType(uint256)",
+		"LengthMismatch()",
+		"AllowanceExpired(uint256)",
+		"InsufficientAllowance(uint256)",
+		"InvalidNonce()",
+		"SignatureExpired(uint256)",
+		"InvalidSignature()",
+		"InvalidSigner()",
+		"FailedOp(uint256,string)",
+		"FailedOpWithRevert(uint256,string,bytes)",
+		"SignatureValidationFailed(address)",
+	} {
+		m[keccakTopic(sig)[:10]] = sig
+	}
+	return m
+}()
+
+// RevertInfo is TrackResult.Failure: the decoded reason a mined tx failed.
+// ReplayBlock is the block whose state the replay ran on (the parent). The
+// replay can't see txs earlier in the same block, hence not_reproduced.
+type RevertInfo struct {
+	Kind        string     `json:"kind"`
+	Reason      string     `json:"reason"`
+	Selector    string     `json:"selector,omitempty"`
+	Signature   string     `json:"signature,omitempty"`
+	Args        []ABIValue `json:"args,omitempty"`
+	PanicCode   string     `json:"panic_code,omitempty"`
+	Data        string     `json:"data,omitempty"`
+	ReplayBlock uint64     `json:"replay_block"`
+}
+
+// replayRevert re-executes t at its parent block and decodes the outcome. It
+// returns nil when the replay itself couldn't be made: transport errors, and
+// node errors that aren't a revert (code 3 / "execution reverted") or out of
+// gas, such as missing state or rate limits. Those are logged since they may
+// name the provider; gasUsed is from the receipt.
+func replayRevert(t trackTx, gasUsed string) *RevertInfo {
+	if t.BlockNumber == nil {
+		return nil
+	}
+	n, err := config.ParseHexUint64(*t.BlockNumber)
+	if err != nil || n == 0 {
+		return nil
+	}
+	call := map[string]any{"from": t.From, "gas": t.Gas, "value": t.Value, "data": t.Input}
+	if t.To != nil {
+		call["to"] = *t.To
+	}
+	info := &RevertInfo{ReplayBlock: n - 1}
+	_, err = eth.Call("eth_call", []any{call, fmt.Sprintf("0x%x", n-1)})
+	if err == nil {
+		info.Kind = RevertNotReproduced
+		info.Reason = "the replay on the parent block's state succeeded; the failure depended on transactions earlier in the same block"
+		if gasUsed != "" && strings.EqualFold(gasUsed, t.Gas) {
+			info.Kind = RevertOutOfGas
+			info.Reason = "out of gas: the transaction used its entire gas limit"
+		}
+		return info
+	}
+	var rpcErr *eth.RPCError
+	if !errors.As(err, &rpcErr) {
+		log.Printf("revert: replay of %s failed: %v\n", t.Hash, err)
+		return nil
+	}
+	data := revertData(rpcErr)
+	if data == "" || data == "0x" {
+		msg := strings.ToLower(rpcErr.Message)
+		switch {
+		case rpcErr.Code == 3 || strings.HasPrefix(msg, "execution reverted"):
+			info.Kind = RevertNoData
+			info.Reason = rpcErr.Message
+			if reason, ok := strings.CutPrefix(rpcErr.Message, "execution reverted: "); ok {
+				info.Kind, info.Reason = RevertErrorString, reason
+			} else if gasUsed != "" && strings.EqualFold(gasUsed, t.Gas) {
+				info.Kind = RevertOutOfGas
+				info.Reason = "out of gas: the transaction used its entire gas limit"
+			}
+		case strings.Contains(msg, "out of gas"):
+			info.Kind = RevertOutOfGas
+			info.Reason = "out of gas: the transaction used its entire gas limit"
+		default:
+			// Not an execution result (missing state, rate limit, ...).
+			log.Printf("revert: replay of %s failed: %d %s\n", t.Hash, rpcErr.Code, rpcErr.Message)
+			return nil
+		}
+		return info
+	}
+	to := ""
+	if t.To != nil {
+		to = *t.To
+	}
+	decodeRevertData(info, data, to)
+	return info
+}
+
+// revertData extracts the hex revert payload from a JSON-RPC error. Nodes send
+// it as a string ("0x..."), some providers wrap it as {"data": "0x..."}.
+func revertData(e *eth.RPCError) string {
+	if len(e.Data) == 0 {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(e.Data, &s) == nil {
+		return strings.ToLower(s)
+	}
+	var obj struct {
+		Data string `json:"data"`
+	}
+	if json.Unmarshal(e.Data, &obj) == nil {
+		return strings.ToLower(obj.Data)
+	}
+	return ""
+}
+
+// decodeRevertData fills info from ABI-encoded revert data; to (the called
+// contract) lets its own ABI resolve the error first.
+func decodeRevertData(info *RevertInfo, data, to string) {
+	info.Data = data
+	if len(data) < 10 {
+		info.Kind, info.Reason = RevertUnknownError, "revert data too short to carry a selector"
+		return
+	}
+	info.Selector = data[:10]
+	args, _ := hex.DecodeString(data[10:])
+	switch info.Selector {
+	case errorStringSelector:
+		info.Kind = RevertErrorString
+		info.Signature = "Error(string)"
+		info.Reason = "malformed Error(string) payload"
+		if vals, err := decodeABIArgs(info.Signature, args, nil); err == nil {
+			info.Reason, _ = vals[0].Value.(string)
+		}
+	case panicSelector:
+		info.Kind = RevertPanic
+		info.Signature = "Panic(uint256)"
+		code := new(big.Int)
+		if len(args) >= 32 {
+			code.SetBytes(args[:32])
+		}
+		info.PanicCode = fmt.Sprintf("0x%02x", code)
+		reason, ok := panicReasons[code.Uint64()]
+		if !ok || !code.IsUint64() {
+			reason = "unknown panic code"
+		}
+		info.Reason = "panic: " + reason
+	default:
+		cands := sigs().candidates(info.Selector, strings.ToLower(to), SigError)
+		if len(cands) == 0 {
+			info.Kind = RevertUnknownError
+			info.Reason = "reverted with unknown custom error " + info.Selector
+			return
+		}
+		// With colliding selectors, take the first error that decodes strictly.
+		var vals []ABIValue
+		err := ErrABIDecode
+		sig := cands[0].Signature
+		for _, c := range cands {
+			d := newABIDecoder(true)
+			if vals, err = d.args(c.Signature, args, c.Names); err == nil {
+				sig = c.Signature
+				break
+			}
+		}
+		info.Kind = RevertCustomError
+		info.Signature = sig
+		info.Reason = sig[:strings.IndexByte(sig, '(')]
+		if err != nil {
+			info.Reason += " (arguments could not be decoded)"
+			return
+		}
+		info.Args = vals
+		parts := make([]string, len(vals))
+		for i, v := range vals {
+			parts[i] = formatABIValue(v)
+		}
+		if len(parts) > 0 {
+			info.Reason += "(" + strings.Join(parts, ", ") + ")"
+		}
+	}
+}
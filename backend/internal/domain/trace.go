@@ -0,0 +1,267 @@
+// Package domain: this file adds the optional execution trace to TrackTx. With
+// debug_traceTransaction it builds the internal call tree (callTracer, selectors
+// resolved through the signature registry) and the balance/nonce/storage changes
+// (prestateTracer in diffMode). Providers without the debug namespace yield a
+// TxTrace with Available false rather than an error.
+package domain
+
+import (
+	"encoding/json"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+	"github.com/you/eth-tx-lifecycle-backend/internal/clients/eth"
+)
+
+// traceTimeout is passed to the node's tracer so a huge tx can't hold the call
+// open. It stays just under eth.CallTimeout so the node gives up (and says so)
+// before the client drops the request.
+func traceTimeout() string {
+	d := eth.CallTimeout() - 500*time.Millisecond
+	if d < time.Second {
+		d = time.Second
+	}
+	return d.String()
+}
+
+// TrackOptions selects the optional, more expensive parts of TrackTx.
+type TrackOptions struct {
+	Trace bool // include TrackResult.Trace (mined txs only)
+}
+
+// CallFrame is one call in the internal call tree. Values and gas are as the
+// tracer reports them (hex); Method and Contract are set when known.
+type CallFrame struct {
+	Type         string      `json:"type"`
+	From         string      `json:"from"`
+	To           string      `json:"to,omitempty"`
+	Value        string      `json:"value,omitempty"`
+	Gas          string      `json:"gas,omitempty"`
+	GasUsed      string      `json:"gas_used,omitempty"`
+	Input        string      `json:"input,omitempty"`
+	Output       string      `json:"output,omitempty"`
+	Selector     string      `json:"selector,omitempty"`
+	Method       string      `json:"method,omitempty"`
+	Contract     string      `json:"contract,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	RevertReason string      `json:"revert_reason,omitempty"`
+	Calls        []CallFrame `json:"calls,omitempty"`
+}
+
+// StateDiff is one account's change. Balance and nonce are nil when unchanged;
+// Storage lists changed slots ordered by slot.
+type StateDiff struct {
+	Address     string        `json:"address"`
+	Contract    string        `json:"contract,omitempty"`
+	Balance     *BalanceDiff  `json:"balance,omitempty"`
+	Nonce       *NonceDiff    `json:"nonce,omitempty"`
+	CodeChanged bool          `json:"code_changed,omitempty"`
+	Storage     []StorageDiff `json:"storage,omitempty"`
+}
+
+// BalanceDiff is a balance change; Delta may be negative.
+type BalanceDiff struct {
+	Before Amount `json:"before"`
+	After  Amount `json:"after"`
+	Delta  Amount `json:"delta"`
+}
+
+// NonceDiff is a nonce change.
+type NonceDiff struct {
+	Before uint64 `json:"before"`
+	After  uint64 `json:"after"`
+}
+
+// StorageDiff is one changed storage slot (32-byte hex words).
+type StorageDiff struct {
+	Slot   string `json:"slot"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// TxTrace is TrackResult.Trace. Each part is independent: CallTree needs
+// callTracer, StateDiff needs prestateTracer; Unavailable says what was missing.
+type TxTrace struct {
+	Available   bool        `json:"available"`
+	CallTree    *CallFrame  `json:"call_tree,omitempty"`
+	CallCount   int         `json:"call_count,omitempty"`
+	StateDiff   []StateDiff `json:"state_diff,omitempty"`
+	Unavailable []string    `json:"unavailable,omitempty"`
+}
+
+// traceTx runs both tracers in parallel. RPC errors are logged, not returned,
+// since they may carry provider URLs; Unavailable only says which kind it was.
+func traceTx(hash string) *TxTrace {
+	tr := &TxTrace{}
+	var calls *CallFrame
+	var diff []StateDiff
+	var callErr, diffErr error
+	var g errgroup.Group
+	g.Go(func() error {
+		calls, callErr = traceCalls(hash)
+		return nil
+	})
+	g.Go(func() error {
+		diff, diffErr = traceStateDiff(hash)
+		return nil
+	})
+	_ = g.Wait()
+	if callErr != nil {
+		log.Printf("trace: callTracer %s: %v\n", hash, callErr)
+		tr.Unavailable = append(tr.Unavailable, "call_tree: "+traceFailure("callTracer", callErr))
+	} else {
+		tr.CallTree = calls
+		tr.CallCount = countCalls(calls)
+	}
+	if diffErr != nil {
+		log.Printf("trace: prestateTracer %s: %v\n", hash, diffErr)
+		tr.Unavailable = append(tr.Unavailable, "state_diff: "+traceFailure("prestateTracer (diffMode)", diffErr))
+	} else {
+		tr.StateDiff = diff
+	}
+	tr.Available = callErr == nil || diffErr == nil
+	return tr
+}
+
+// traceFailure explains a tracer error for TxTrace.Unavailable without echoing
+// it: only a missing method means the RPC lacks the debug namespace.
+func traceFailure(tracer string, err error) string {
+	if isMethodMissing(err) {
+		return "debug_traceTransaction is not available on the configured RPC"
+	}
+	return "debug_traceTransaction with " + tracer + " failed (timeout or provider error; see server log)"
+}
+
+// traceCalls returns the callTracer tree with selectors resolved.
+func traceCalls(hash string) (*CallFrame, error) {
+	raw, err := eth.Call("debug_traceTransaction", []any{hash, map[string]any{"tracer": "callTracer", "timeout": traceTimeout()}})
+	if err != nil {
+		return nil, err
+	}
+	var root CallFrame
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil, err
+	}
+	annotateCalls(&root)
+	return &root, nil
+}
+
+// annotateCalls fills Selector, Method and Contract throughout the tree.
+func annotateCalls(f *CallFrame) {
+	f.From, f.To = strings.ToLower(f.From), strings.ToLower(f.To)
+	if len(f.Input) >= 10 && !strings.HasPrefix(f.Type, "CREATE") {
+		f.Selector = strings.ToLower(f.Input[:10])
+		f.Method = functionSignature(f.Input, f.To)
+	}
+	f.Contract = contractName(f.To)
+	for i := range f.Calls {
+		annotateCalls(&f.Calls[i])
+	}
+}
+
+func countCalls(f *CallFrame) int {
+	if f == nil {
+		return 0
+	}
+	n := 1
+	for i := range f.Calls {
+		n += countCalls(&f.Calls[i])
+	}
+	return n
+}
+
+// prestateAccount is one account in prestateTracer output.
+type prestateAccount struct {
+	Balance *string           `json:"balance"`
+	Nonce   *uint64           `json:"nonce"`
+	Code    *string           `json:"code"`
+	Storage map[string]string `json:"storage"`
+}
+
+// traceStateDiff returns per-account changes, ordered by address. In diffMode,
+// post only lists fields that changed and pre holds their previous values (an
+// account or slot missing from pre was empty/zero).
+func traceStateDiff(hash string) ([]StateDiff, error) {
+	raw, err := eth.Call("debug_traceTransaction", []any{hash, map[string]any{
+		"tracer": "prestateTracer", "timeout": traceTimeout(), "tracerConfig": map[string]any{"diffMode": true},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Pre  map[string]prestateAccount `json:"pre"`
+		Post map[string]prestateAccount `json:"post"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+	addrs := map[string]bool{}
+	for a := range res.Pre {
+		addrs[a] = true
+	}
+	for a := range res.Post {
+		addrs[a] = true
+	}
+	out := make([]StateDiff, 0, len(addrs))
+	for a := range addrs {
+		pre, post := res.Pre[a], res.Post[a]
+		addr := strings.ToLower(a)
+		d := StateDiff{Address: addr, Contract: contractName(addr)}
+		if _, ok := res.Post[a]; !ok && pre.Balance != nil {
+			// In pre only: the account was deleted.
+			zero := "0x0"
+			post.Balance = &zero
+		}
+		if post.Balance != nil {
+			before, after := hexOrZero(pre.Balance), hexOrZero(post.Balance)
+			d.Balance = &BalanceDiff{Before: newAmount(before), After: newAmount(after), Delta: newAmount(new(big.Int).Sub(after, before))}
+		}
+		if post.Nonce != nil {
+			var before uint64
+			if pre.Nonce != nil {
+				before = *pre.Nonce
+			}
+			d.Nonce = &NonceDiff{Before: before, After: *post.Nonce}
+		}
+		d.CodeChanged = post.Code != nil
+		for slot, after := range post.Storage {
+			d.Storage = append(d.Storage, StorageDiff{Slot: slot, Before: storageOrZero(pre.Storage[slot]), After: after})
+		}
+		// Slots only in pre were cleared to zero.
+		for slot, before := range pre.Storage {
+			if _, ok := post.Storage[slot]; !ok {
+				d.Storage = append(d.Storage, StorageDiff{Slot: slot, Before: before, After: storageOrZero("")})
+			}
+		}
+		sort.Slice(d.Storage, func(i, j int) bool { return d.Storage[i].Slot < d.Storage[j].Slot })
+		if d.Balance == nil && d.Nonce == nil && !d.CodeChanged && len(d.Storage) == 0 {
+			continue
+		}
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Address < out[j].Address })
+	return out, nil
+}
+
+func hexOrZero(h *string) *big.Int {
+	if h == nil {
+		return new(big.Int)
+	}
+	if v, ok := config.ParseHexBigInt(*h); ok {
+		return v
+	}
+	return new(big.Int)
+}
+
+func storageOrZero(v string) string {
+	if v == "" {
+		return "0x" + strings.Repeat("0", 64)
+	}
+	return v
+}
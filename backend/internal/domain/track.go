@@ -3,11 +3,11 @@ package domain
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/you/eth-tx-lifecycle-backend/config"
-	"github.com/you/eth-tx-lifecycle-backend/internal/clients/beacon"
 	"github.com/you/eth-tx-lifecycle-backend/internal/clients/eth"
 	"github.com/you/eth-tx-lifecycle-backend/internal/clients/relay"
 )
@@ -28,84 +28,80 @@ type trackTx struct {
 	TransactionIndex     *string `json:"transactionIndex"`
 }
 
-// TrackTx returns the full lifecycle data for a transaction (or "latest").
-func TrackTx(hash string) (map[string]any, error) {
+// TrackTx returns the full lifecycle data for a transaction (or "latest"). Errors
+// wrap ErrInvalidHash, ErrTxNotFound, ErrRPCFailure or ErrDecodeFailure; failures
+// of the optional enrichments (receipt, block, relay, beacon) leave those parts empty.
+func TrackTx(hash string) (*TrackResult, error) {
+	return trackTxWith(hash, newTrackLookups(), TrackOptions{})
+}
+
+// TrackTxWithOptions is TrackTx plus the optional parts selected by opts.
+func TrackTxWithOptions(hash string, opts TrackOptions) (*TrackResult, error) {
+	return trackTxWith(hash, newTrackLookups(), opts)
+}
+
+// isTxHash reports whether h is 0x followed by 64 hex digits.
+func isTxHash(h string) bool {
+	return len(h) == 66 && strings.HasPrefix(h, "0x") && isHex(strings.ToLower(h[2:]))
+}
+
+// isAddress reports whether a is 0x followed by 40 hex digits.
+func isAddress(a string) bool {
+	return len(a) == 42 && strings.HasPrefix(a, "0x") && isHex(strings.ToLower(a[2:]))
+}
+
+// trackTxWith is TrackTx with block, relay and beacon lookups going through lk,
+// so TrackBatch can share them across hashes.
+func trackTxWith(hash string, lk *trackLookups, opts TrackOptions) (*TrackResult, error) {
 	if hash == "" {
-		return nil, nil
+		return nil, ErrInvalidHash
 	}
 	if strings.EqualFold(hash, "latest") {
-		rawBlockNum, err := eth.Call("eth_blockNumber", []any{})
-		if err != nil || string(rawBlockNum) == "null" {
+		h, err := latestInterestingTx()
+		if err != nil {
 			return nil, err
 		}
-		var blockNumStr string
-		if json.Unmarshal(rawBlockNum, &blockNumStr) != nil {
-			return nil, nil
-		}
-		rawBlock, err := eth.Call("eth_getBlockByNumber", []any{blockNumStr, true})
-		if err != nil || string(rawBlock) == "null" {
-			return nil, err
-		}
-		var blk struct {
-			Transactions []struct {
-				Hash  string  `json:"hash"`
-				To    *string `json:"to"`
-				Value string  `json:"value"`
-				Input string  `json:"input"`
-			} `json:"transactions"`
-		}
-		if json.Unmarshal(rawBlock, &blk) != nil || len(blk.Transactions) == 0 {
-			return nil, nil
-		}
-		hash = ""
-		for _, tx := range blk.Transactions {
-			decoded := DecodeTransactionInput(tx.Input, tx.To, tx.Value, nil)
-			if decoded != nil && decoded.ActionType != "" && decoded.ActionType != "contract_call" {
-				hash = tx.Hash
-				break
-			}
-			if decoded == nil || decoded.ActionType == "" || decoded.ActionType == "contract_call" {
-				rawReceipt, err := eth.Call("eth_getTransactionReceipt", []any{tx.Hash})
-				if err == nil && string(rawReceipt) != "null" {
-					decodedWithReceipt := DecodeTransactionInput(tx.Input, tx.To, tx.Value, rawReceipt)
-					if decodedWithReceipt != nil && decodedWithReceipt.ActionType != "" && decodedWithReceipt.ActionType != "contract_call" {
-						hash = tx.Hash
-						break
-					}
-				}
-			}
-		}
-		if hash == "" {
-			hash = blk.Transactions[0].Hash
-		}
+		hash = h
+	} else if !isTxHash(hash) {
+		return nil, fmt.Errorf("%w: %q (expected 0x followed by 64 hex digits)", ErrInvalidHash, hash)
 	}
 
 	rawTx, err := eth.Call("eth_getTransactionByHash", []any{hash})
 	if err != nil || string(rawTx) == "null" {
-		return nil, err
+		// Replaced, dropped and reorged-out txs are often unknown to the node; answer
+		// from mempool history and the reorg detector.
+		if res := trackFromHistory(hash); res != nil {
+			res.Labels = trackLabels(res)
+			return res, nil
+		}
+		if res := trackReorgedOut(hash); res != nil {
+			return res, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: eth_getTransactionByHash: %v", ErrRPCFailure, err)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrTxNotFound, hash)
 	}
 	var t trackTx
-	if json.Unmarshal(rawTx, &t) != nil {
-		return nil, nil
+	if err := json.Unmarshal(rawTx, &t); err != nil {
+		return nil, fmt.Errorf("%w: transaction: %v", ErrDecodeFailure, err)
 	}
 	pending := t.BlockNumber == nil
-	economics := map[string]any{"value": t.Value, "gas_limit": t.Gas}
-	if t.GasPrice != nil {
-		economics["gas_price"] = *t.GasPrice
-	}
-	if t.MaxFeePerGas != nil {
-		economics["max_fee_per_gas"] = *t.MaxFeePerGas
-	}
-	if t.MaxPriorityFeePerGas != nil {
-		economics["max_priority_fee_per_gas"] = *t.MaxPriorityFeePerGas
-	}
-	resp := map[string]any{
-		"hash": t.Hash, "from": t.From, "to": t.To, "input": t.Input,
-		"economics": economics, "status": map[string]any{"pending": pending},
-		"pbs_relay": nil, "beacon": nil, "decoded": nil,
+	res := &TrackResult{
+		Version: TrackResultVersion,
+		Stage:   StagePending,
+		Hash:    t.Hash, From: t.From, To: t.To, Input: t.Input,
+		Economics: &TrackEconomics{
+			Value: t.Value, GasLimit: t.Gas, GasPrice: t.GasPrice,
+			MaxFeePerGas: t.MaxFeePerGas, MaxPriorityFeePerGas: t.MaxPriorityFeePerGas,
+		},
+		Status:      TrackStatus{Pending: pending},
+		Replacement: replacementInfo(t.Hash),
+		Timings:     txTimings(t.Hash, false, 0, 0),
 	}
 	var rawReceipt json.RawMessage
 	if !pending {
+		res.Stage = StageIncluded
 		receiptData, err := eth.Call("eth_getTransactionReceipt", []any{t.Hash})
 		if err == nil && string(receiptData) != "null" {
 			rawReceipt = receiptData
@@ -113,108 +109,240 @@ func TrackTx(hash string) (map[string]any, error) {
 				Status            string `json:"status"`
 				GasUsed           string `json:"gasUsed"`
 				EffectiveGasPrice string `json:"effectiveGasPrice"`
+				BlobGasUsed       string `json:"blobGasUsed"`
+				BlobGasPrice      string `json:"blobGasPrice"`
 			}
 			if json.Unmarshal(rawReceipt, &receipt) == nil {
-				economics["gas_used"] = receipt.GasUsed
-				economics["effective_gas_price"] = receipt.EffectiveGasPrice
-				resp["status"] = map[string]any{"pending": false, "success": receipt.Status == "0x1"}
+				res.Economics.GasUsed = receipt.GasUsed
+				res.Economics.EffectiveGasPrice = receipt.EffectiveGasPrice
+				res.Economics.BlobGasUsed = receipt.BlobGasUsed
+				res.Economics.BlobGasPrice = receipt.BlobGasPrice
+				success := receipt.Status == "0x1"
+				res.Status.Success = &success
 			}
 		}
 	}
-	if decoded := DecodeTransactionInput(t.Input, t.To, t.Value, rawReceipt); decoded != nil {
-		resp["decoded"] = decoded
+	res.Decoded = DecodeTransactionInput(t.Input, t.To, t.Value, rawReceipt)
+	if st, ok := TxReorgStatusFor(t.Hash); ok {
+		res.Reorg = &st
+		if pending && st.Status == ReorgStatusOut {
+			res.Stage = StageReorgedOut
+			res.Status.ReorgedOut = true
+		}
+	}
+	if !pending {
+		trackInclusion(res, t, lk)
+		if res.Status.Success != nil && !*res.Status.Success {
+			res.Failure = replayRevert(t, res.Economics.GasUsed)
+		}
+		if opts.Trace {
+			res.Trace = traceTx(t.Hash)
+		}
+	}
+	res.Labels = trackLabels(res)
+	return res, nil
+}
+
+// trackLabels labels the sender, recipient, fee recipient and relay builder.
+// Addresses inside the call are labeled in Decoded.Labels.
+func trackLabels(res *TrackResult) map[string]AddressLabel {
+	addrs := []string{res.From}
+	if res.To != nil {
+		addrs = append(addrs, *res.To)
+	}
+	if res.Inclusion != nil {
+		addrs = append(addrs, res.Inclusion.Miner)
+	}
+	if res.PBSRelay != nil {
+		addrs = append(addrs, res.PBSRelay.BuilderPubkey)
+	}
+	return LabelsFor(addrs...)
+}
+
+// trackReorgedOut answers for a hash the node doesn't know but whose block the
+// reorg detector saw orphaned; nil otherwise.
+func trackReorgedOut(hash string) *TrackResult {
+	st, ok := TxReorgStatusFor(hash)
+	if !ok || st.Status != ReorgStatusOut {
+		return nil
+	}
+	return &TrackResult{
+		Version: TrackResultVersion,
+		Stage:   StageReorgedOut,
+		Hash:    strings.ToLower(hash),
+		Status:  TrackStatus{ReorgedOut: true},
+		Reorg:   &st,
+		Timings: txTimings(hash, false, 0, 0),
 	}
-	if !pending && t.BlockNumber != nil {
-		inclusion := map[string]any{"block_number": *t.BlockNumber}
-		if t.TransactionIndex != nil {
-			inclusion["transaction_index"] = *t.TransactionIndex
+}
+
+// trackFromHistory answers for a hash the node no longer knows but the mempool
+// monitor saw replaced or dropped; nil otherwise.
+func trackFromHistory(hash string) *TrackResult {
+	rec, ok := LookupMempoolTx(hash)
+	if !ok {
+		return nil
+	}
+	res := &TrackResult{Version: TrackResultVersion, Hash: rec.Hash, From: rec.From, To: rec.To, Timings: txTimings(rec.Hash, false, 0, 0)}
+	switch rec.Status {
+	case TxStatusReplaced:
+		res.Stage = StageReplaced
+		res.Status = TrackStatus{Replaced: true}
+		res.Replacement = replacementInfo(hash)
+	case TxStatusDropped:
+		res.Stage = StageDropped
+		res.Status = TrackStatus{Dropped: true}
+	default:
+		return nil
+	}
+	return res
+}
+
+// latestInterestingTx picks a tx from the latest block, preferring one whose
+// decoded action is more specific than a generic contract call.
+func latestInterestingTx() (string, error) {
+	rawBlockNum, err := eth.Call("eth_blockNumber", []any{})
+	if err != nil {
+		return "", fmt.Errorf("%w: eth_blockNumber: %v", ErrRPCFailure, err)
+	}
+	var blockNumStr string
+	if err := json.Unmarshal(rawBlockNum, &blockNumStr); err != nil {
+		return "", fmt.Errorf("%w: block number: %v", ErrDecodeFailure, err)
+	}
+	rawBlock, err := eth.Call("eth_getBlockByNumber", []any{blockNumStr, true})
+	if err != nil {
+		return "", fmt.Errorf("%w: eth_getBlockByNumber: %v", ErrRPCFailure, err)
+	}
+	if string(rawBlock) == "null" {
+		return "", fmt.Errorf("%w: block %s", ErrTxNotFound, blockNumStr)
+	}
+	var blk struct {
+		Transactions []struct {
+			Hash  string  `json:"hash"`
+			To    *string `json:"to"`
+			Value string  `json:"value"`
+			Input string  `json:"input"`
+		} `json:"transactions"`
+	}
+	if err := json.Unmarshal(rawBlock, &blk); err != nil {
+		return "", fmt.Errorf("%w: block: %v", ErrDecodeFailure, err)
+	}
+	if len(blk.Transactions) == 0 {
+		return "", fmt.Errorf("%w: block %s has no transactions", ErrTxNotFound, blockNumStr)
+	}
+	for _, tx := range blk.Transactions {
+		decoded := DecodeTransactionInput(tx.Input, tx.To, tx.Value, nil)
+		if decoded != nil && decoded.ActionType != "" && decoded.ActionType != "contract_call" {
+			return tx.Hash, nil
 		}
-		rawBlock, err := eth.Call("eth_getBlockByNumber", []any{*t.BlockNumber, true})
-		if err == nil && string(rawBlock) != "null" {
-			var b struct {
-				Hash         string           `json:"hash"`
-				Timestamp    string           `json:"timestamp"`
-				Miner        string           `json:"miner"`
-				GasUsed      string           `json:"gasUsed"`
-				GasLimit     string           `json:"gasLimit"`
-				Transactions []map[string]any `json:"transactions"`
+		rawReceipt, err := eth.Call("eth_getTransactionReceipt", []any{tx.Hash})
+		if err == nil && string(rawReceipt) != "null" {
+			decodedWithReceipt := DecodeTransactionInput(tx.Input, tx.To, tx.Value, rawReceipt)
+			if decodedWithReceipt != nil && decodedWithReceipt.ActionType != "" && decodedWithReceipt.ActionType != "contract_call" {
+				return tx.Hash, nil
 			}
-			if json.Unmarshal(rawBlock, &b) == nil {
-				inclusion["block_hash"] = b.Hash
-				inclusion["timestamp"] = b.Timestamp
-				inclusion["miner"] = b.Miner
-				inclusion["block_gas_used"] = b.GasUsed
-				inclusion["block_gas_limit"] = b.GasLimit
-				inclusion["total_transactions"] = len(b.Transactions)
-				if t.TransactionIndex != nil {
-					txIdx, _ := config.ParseHexUint64(*t.TransactionIndex)
-					start := int(txIdx) - 2
-					if start < 0 {
-						start = 0
-					}
-					end := int(txIdx) + 3
-					if end > len(b.Transactions) {
-						end = len(b.Transactions)
-					}
-					neighbors := []map[string]any{}
-					for i := start; i < end; i++ {
-						tx := b.Transactions[i]
-						neighbors = append(neighbors, map[string]any{"index": i, "hash": tx["hash"], "from": tx["from"], "to": tx["to"], "value": tx["value"]})
-					}
-					inclusion["neighboring_transactions"] = neighbors
-				}
-				if n, err := config.ParseHexUint64(*t.BlockNumber); err == nil {
-					// Query relay directly by block number for accurate lookup
-					blockNumStr := strconv.FormatUint(n, 10)
-					rawRel, relErr := relay.Get("/relay/v1/data/bidtraces/proposer_payload_delivered?block_number=" + blockNumStr)
-					if relErr == nil {
-						var entries []map[string]any
-						if json.Unmarshal(rawRel, &entries) == nil && len(entries) > 0 {
-							entry := entries[0]
-							resp["pbs_relay"] = map[string]any{
-								"builder_pubkey": entry["builder_pubkey"], "proposer_pubkey": entry["proposer_pubkey"],
-								"value": entry["value"], "relay": entry["relay"],
-							}
-						}
-					}
-					rawGenesis, _, err := beacon.Get("/eth/v1/beacon/genesis")
-					if err == nil {
-						var genesis struct {
-							Data struct {
-								GenesisTime string `json:"genesis_time"`
-							} `json:"data"`
-						}
-						if json.Unmarshal(rawGenesis, &genesis) == nil {
-							tsHex := strings.TrimPrefix(b.Timestamp, "0x")
-							blockTs, _ := strconv.ParseUint(tsHex, 16, 64)
-							genesisTs, _ := strconv.ParseUint(genesis.Data.GenesisTime, 10, 64)
-							var slot uint64
-							if blockTs >= genesisTs {
-								slot = (blockTs - genesisTs) / 12
-							}
-							rawFinality, _, err := beacon.Get("/eth/v1/beacon/states/finalized/finality_checkpoints")
-							if err == nil {
-								var final struct {
-									Data struct {
-										Finalized struct {
-											Epoch string `json:"epoch"`
-										} `json:"finalized"`
-									} `json:"data"`
-								}
-								if json.Unmarshal(rawFinality, &final) == nil {
-									epoch, _ := strconv.ParseUint(final.Data.Finalized.Epoch, 10, 64)
-									finalizedSlot := epoch*32 + 31
-									resp["beacon"] = map[string]any{
-										"slot": slot, "is_finalized": slot <= finalizedSlot, "finalized_epoch": epoch,
-									}
-								}
-							}
-						}
-					}
-				}
+		}
+	}
+	return blk.Transactions[0].Hash, nil
+}
+
+// trackInclusion fills Inclusion, PBSRelay, the fee breakdown, Beacon, Timings
+// and the consensus stage for a mined tx.
+func trackInclusion(res *TrackResult, t trackTx, lk *trackLookups) {
+	inc := &TrackInclusion{BlockNumber: *t.BlockNumber, TransactionIndex: t.TransactionIndex}
+	res.Inclusion = inc
+	b, err := lk.block(*t.BlockNumber)
+	if err != nil {
+		return
+	}
+	inc.BlockHash = b.Hash
+	inc.Timestamp = b.Timestamp
+	// A lagging or load-balanced RPC can still report the tx in an orphaned block.
+	if t.BlockHash != nil && !strings.EqualFold(*t.BlockHash, b.Hash) {
+		inc.BlockHash = *t.BlockHash
+		res.Stage = StageReorgedOut
+		res.Status.ReorgedOut = true
+		if res.Reorg == nil {
+			n, _ := config.ParseHexUint64(*t.BlockNumber)
+			res.Reorg = &TxReorgStatus{Status: ReorgStatusOut, OrphanedBlock: n, OrphanedBlockHash: strings.ToLower(*t.BlockHash)}
+		}
+		return
+	}
+	inc.Miner = b.Miner
+	inc.BlockGasUsed = b.GasUsed
+	inc.BlockGasLimit = b.GasLimit
+	inc.TotalTransactions = len(b.Transactions)
+	if t.TransactionIndex != nil {
+		txIdx, _ := config.ParseHexUint64(*t.TransactionIndex)
+		start := max(int(txIdx)-2, 0)
+		end := min(int(txIdx)+3, len(b.Transactions))
+		for i := start; i < end; i++ {
+			tx := b.Transactions[i]
+			n := NeighborTx{Index: i}
+			n.Hash, _ = tx["hash"].(string)
+			n.From, _ = tx["from"].(string)
+			n.Value, _ = tx["value"].(string)
+			if to, ok := tx["to"].(string); ok {
+				n.To = &to
 			}
+			inc.Neighbors = append(inc.Neighbors, n)
+		}
+	}
+	n, err := config.ParseHexUint64(*t.BlockNumber)
+	if err != nil {
+		return
+	}
+	res.PBSRelay = lk.relay(n, b.Hash)
+	res.Economics.Cost = txCost(res.Economics, hexBig(b.BaseFeePerGas), res.PBSRelay)
+	blockTs, _ := config.ParseHexUint64(b.Timestamp)
+	bs, ok := lk.slot(b.Hash, blockTs)
+	if !ok {
+		return
+	}
+	slot := bs.Slot
+	res.Timings = txTimings(t.Hash, true, blockTs, slot)
+	cp, err := lk.checkpoints()
+	if err != nil {
+		return
+	}
+	justified, _ := strconv.ParseUint(cp.CurrentJustified.Epoch, 10, 64)
+	finalized, _ := strconv.ParseUint(cp.Finalized.Epoch, 10, 64)
+	justifiedSlot, _ := checkpointSlot(cp.CurrentJustified)
+	finalizedSlot, _ := checkpointSlot(cp.Finalized)
+	res.Beacon = &TrackBeacon{
+		Slot:           slot,
+		SlotVerified:   bs.Verified,
+		IsJustified:    slot <= justifiedSlot,
+		JustifiedEpoch: justified,
+		JustifiedSlot:  justifiedSlot,
+		IsFinalized:    slot <= finalizedSlot,
+		FinalizedEpoch: finalized,
+		FinalizedSlot:  finalizedSlot,
+	}
+	switch {
+	case res.Beacon.IsFinalized:
+		res.Stage = StageFinalized
+	case res.Beacon.IsJustified:
+		res.Stage = StageJustified
+	}
+}
+
+// trackRelay queries the relays directly by block number for the delivered
+// bidtrace of blockHash. A relay can list several payloads for one height (e.g.
+// around a reorg), so only the entry for blockHash is used; nil if none matches.
+func trackRelay(blockNumber uint64, blockHash string) *TrackRelay {
+	raw, err := relay.Get("/relay/v1/data/bidtraces/proposer_payload_delivered?block_number=" + strconv.FormatUint(blockNumber, 10))
+	if err != nil {
+		return nil
+	}
+	var entries []TrackRelay
+	if json.Unmarshal(raw, &entries) != nil {
+		return nil
+	}
+	for i := range entries {
+		if strings.EqualFold(entries[i].BlockHash, blockHash) {
+			return &entries[i]
 		}
-		resp["inclusion"] = inclusion
 	}
-	return resp, nil
+	return nil
 }
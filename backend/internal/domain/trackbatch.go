@@ -0,0 +1,190 @@
+// Package domain: this file tracks many transactions at once. Lookups that
+// several hashes share (the including block, its relay bidtrace, its beacon slot and the
+// finality checkpoints) go through a per-request memo so each runs once.
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/you/eth-tx-lifecycle-backend/internal/clients/eth"
+)
+
+// MaxTrackBatch caps the hashes accepted by TrackBatch.
+const MaxTrackBatch = 100
+
+// trackBatchConcurrency bounds in-flight TrackTx pipelines per batch so one
+// request can't monopolize the RPC providers.
+const trackBatchConcurrency = 8
+
+// memoCell holds one lookup result; once makes concurrent callers share it.
+type memoCell[T any] struct {
+	once sync.Once
+	v    T
+	err  error
+}
+
+// memo deduplicates lookups by key for the lifetime of one request.
+type memo[T any] struct {
+	mu    sync.Mutex
+	cells map[string]*memoCell[T]
+	calls int
+}
+
+func (m *memo[T]) get(key string, fn func() (T, error)) (T, error) {
+	m.mu.Lock()
+	if m.cells == nil {
+		m.cells = make(map[string]*memoCell[T])
+	}
+	c, ok := m.cells[key]
+	if !ok {
+		c = &memoCell[T]{}
+		m.cells[key] = c
+		m.calls++
+	}
+	m.mu.Unlock()
+	c.once.Do(func() { c.v, c.err = fn() })
+	return c.v, c.err
+}
+
+func (m *memo[T]) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+// trackBlock is the subset of a full block TrackTx reads.
+type trackBlock struct {
+	Hash          string           `json:"hash"`
+	Timestamp     string           `json:"timestamp"`
+	BaseFeePerGas *string          `json:"baseFeePerGas"`
+	Miner         string           `json:"miner"`
+	GasUsed       string           `json:"gasUsed"`
+	GasLimit      string           `json:"gasLimit"`
+	Transactions  []map[string]any `json:"transactions"`
+}
+
+// trackLookups are the shared, memoized enrichment calls behind TrackTx.
+type trackLookups struct {
+	blocks   memo[*trackBlock]
+	relays   memo[*TrackRelay]
+	slots    memo[BlockSlot]
+	finality memo[*FinalityCheckpoints]
+}
+
+func newTrackLookups() *trackLookups { return &trackLookups{} }
+
+func (lk *trackLookups) block(number string) (*trackBlock, error) {
+	return lk.blocks.get(strings.ToLower(number), func() (*trackBlock, error) {
+		raw, err := eth.Call("eth_getBlockByNumber", []any{number, true})
+		if err != nil {
+			return nil, err
+		}
+		if string(raw) == "null" {
+			return nil, fmt.Errorf("block %s not found", number)
+		}
+		var b trackBlock
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return nil, err
+		}
+		return &b, nil
+	})
+}
+
+func (lk *trackLookups) relay(blockNumber uint64, blockHash string) *TrackRelay {
+	r, _ := lk.relays.get(strings.ToLower(blockHash), func() (*TrackRelay, error) {
+		return trackRelay(blockNumber, blockHash), nil
+	})
+	return r
+}
+
+func (lk *trackLookups) slot(blockHash string, ts uint64) (BlockSlot, bool) {
+	s, err := lk.slots.get(strings.ToLower(blockHash), func() (BlockSlot, error) {
+		s, ok := blockSlotFor(blockHash, ts)
+		if !ok {
+			return s, fmt.Errorf("no slot for timestamp %d", ts)
+		}
+		return s, nil
+	})
+	return s, err == nil
+}
+
+func (lk *trackLookups) checkpoints() (*FinalityCheckpoints, error) {
+	return lk.finality.get("head", fetchFinalityCheckpoints)
+}
+
+// TrackBatchItem is one hash's outcome. Err is set instead of Result on failure
+// and wraps the same errors as TrackTx.
+type TrackBatchItem struct {
+	Hash   string
+	Result *TrackResult
+	Err    error
+}
+
+// TrackBatchStats reports how much the memo saved: Blocks and Relays are the
+// distinct lookups actually made for Included txs.
+type TrackBatchStats struct {
+	Requested  int `json:"requested"`
+	Unique     int `json:"unique"`
+	Succeeded  int `json:"succeeded"`
+	Failed     int `json:"failed"`
+	Included   int `json:"included"`
+	BlockCalls int `json:"blockLookups"`
+	RelayCalls int `json:"relayLookups"`
+}
+
+// TrackBatch tracks each distinct hash (case-insensitive; "latest" isn't
+// allowed) and returns items in first-occurrence order.
+func TrackBatch(hashes []string) ([]TrackBatchItem, TrackBatchStats, error) {
+	st := TrackBatchStats{Requested: len(hashes)}
+	if len(hashes) == 0 {
+		return nil, st, fmt.Errorf("%w: no hashes", ErrInvalidHash)
+	}
+	seen := make(map[string]bool, len(hashes))
+	var unique []string
+	for _, h := range hashes {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if !seen[h] {
+			seen[h] = true
+			unique = append(unique, h)
+		}
+	}
+	if len(unique) > MaxTrackBatch {
+		return nil, st, fmt.Errorf("%w: %d hashes exceeds the batch limit of %d", ErrInvalidHash, len(unique), MaxTrackBatch)
+	}
+	st.Unique = len(unique)
+
+	lk := newTrackLookups()
+	items := make([]TrackBatchItem, len(unique))
+	var g errgroup.Group
+	g.SetLimit(trackBatchConcurrency)
+	for i, h := range unique {
+		items[i].Hash = h
+		if !isTxHash(h) {
+			items[i].Err = fmt.Errorf("%w: %q", ErrInvalidHash, h)
+			continue
+		}
+		g.Go(func() error {
+			items[i].Result, items[i].Err = trackTxWith(h, lk, TrackOptions{})
+			return nil
+		})
+	}
+	_ = g.Wait()
+	for _, it := range items {
+		if it.Err != nil {
+			st.Failed++
+			continue
+		}
+		st.Succeeded++
+		if it.Result.Inclusion != nil {
+			st.Included++
+		}
+	}
+	st.BlockCalls = lk.blocks.count()
+	st.RelayCalls = lk.relays.count()
+	return items, st, nil
+}
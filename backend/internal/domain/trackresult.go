@@ -0,0 +1,131 @@
+// Package domain: this file defines the TrackTx response model and its errors.
+// JSON field names are part of the public API (the frontend and Go consumers read
+// them); bump TrackResultVersion when a field changes meaning or is removed.
+package domain
+
+import "errors"
+
+// TrackResultVersion is the schema version reported in TrackResult.Version.
+const TrackResultVersion = 1
+
+// LifecycleStage is the furthest point a transaction has reached.
+type LifecycleStage string
+
+// Lifecycle stages, in order for a transaction that makes it all the way.
+// Replaced and dropped are terminal alternatives to inclusion; reorged_out means
+// the including block was orphaned and no canonical block includes the tx yet.
+const (
+	StagePending    LifecycleStage = "pending"
+	StageIncluded   LifecycleStage = "included"
+	StageJustified  LifecycleStage = "justified"
+	StageFinalized  LifecycleStage = "finalized"
+	StageReplaced   LifecycleStage = "replaced"
+	StageDropped    LifecycleStage = "dropped"
+	StageReorgedOut LifecycleStage = "reorged_out"
+)
+
+// Errors returned by TrackTx (and TrackNonce). Callers should test with
+// errors.Is; the wrapped message carries the underlying cause.
+var (
+	ErrInvalidHash    = errors.New("invalid transaction hash")
+	ErrInvalidAddress = errors.New("invalid address")
+	ErrTxNotFound     = errors.New("transaction not found")
+	ErrRPCFailure     = errors.New("execution RPC request failed")
+	ErrDecodeFailure  = errors.New("failed to decode RPC response")
+)
+
+// TrackResult is the full lifecycle view of one transaction. PBSRelay, Beacon
+// and Decoded are always present (null when unavailable); Inclusion only once
+// the tx is mined.
+type TrackResult struct {
+	Version     int                     `json:"version"`
+	Stage       LifecycleStage          `json:"stage"`
+	Hash        string                  `json:"hash"`
+	From        string                  `json:"from,omitempty"`
+	To          *string                 `json:"to"`
+	Input       string                  `json:"input,omitempty"`
+	Economics   *TrackEconomics         `json:"economics,omitempty"`
+	Status      TrackStatus             `json:"status"`
+	Inclusion   *TrackInclusion         `json:"inclusion,omitempty"`
+	PBSRelay    *TrackRelay             `json:"pbs_relay"`
+	Beacon      *TrackBeacon            `json:"beacon"`
+	Decoded     *DecodedTx              `json:"decoded"`
+	Replacement *ReplacementInfo        `json:"replacement,omitempty"`
+	Timings     *TxTimings              `json:"timings,omitempty"`
+	Reorg       *TxReorgStatus          `json:"reorg,omitempty"`
+	Trace       *TxTrace                `json:"trace,omitempty"`
+	Failure     *RevertInfo             `json:"failure,omitempty"`
+	Labels      map[string]AddressLabel `json:"labels,omitempty"`
+}
+
+// TrackStatus holds the execution-level flags. Success is nil until a receipt
+// exists; ReorgedOut is set while the tx sits outside the canonical chain after a reorg.
+type TrackStatus struct {
+	Pending    bool  `json:"pending"`
+	Success    *bool `json:"success,omitempty"`
+	Replaced   bool  `json:"replaced,omitempty"`
+	Dropped    bool  `json:"dropped,omitempty"`
+	ReorgedOut bool  `json:"reorged_out,omitempty"`
+}
+
+// TrackEconomics are the tx's fee fields as hex wei strings, as returned by the RPC.
+// GasUsed, EffectiveGasPrice and the blob fields come from the receipt; Cost is
+// the computed breakdown once the tx is mined.
+type TrackEconomics struct {
+	Value                string  `json:"value"`
+	GasLimit             string  `json:"gas_limit"`
+	GasPrice             *string `json:"gas_price,omitempty"`
+	MaxFeePerGas         *string `json:"max_fee_per_gas,omitempty"`
+	MaxPriorityFeePerGas *string `json:"max_priority_fee_per_gas,omitempty"`
+	GasUsed              string  `json:"gas_used,omitempty"`
+	EffectiveGasPrice    string  `json:"effective_gas_price,omitempty"`
+	BlobGasUsed          string  `json:"blob_gas_used,omitempty"`
+	BlobGasPrice         string  `json:"blob_gas_price,omitempty"`
+	Cost                 *TxCost `json:"cost,omitempty"`
+}
+
+// TrackInclusion describes the block that included the tx. Fields other than
+// BlockNumber and TransactionIndex are empty if the block couldn't be fetched.
+type TrackInclusion struct {
+	BlockNumber       string       `json:"block_number"`
+	TransactionIndex  *string      `json:"transaction_index,omitempty"`
+	BlockHash         string       `json:"block_hash,omitempty"`
+	Timestamp         string       `json:"timestamp,omitempty"`
+	Miner             string       `json:"miner,omitempty"`
+	BlockGasUsed      string       `json:"block_gas_used,omitempty"`
+	BlockGasLimit     string       `json:"block_gas_limit,omitempty"`
+	TotalTransactions int          `json:"total_transactions,omitempty"`
+	Neighbors         []NeighborTx `json:"neighboring_transactions,omitempty"`
+}
+
+// NeighborTx is a tx within two positions of the tracked one in its block.
+type NeighborTx struct {
+	Index int     `json:"index"`
+	Hash  string  `json:"hash"`
+	From  string  `json:"from"`
+	To    *string `json:"to"`
+	Value string  `json:"value"`
+}
+
+// TrackRelay is the MEV-Boost relay bidtrace for the including block.
+type TrackRelay struct {
+	BuilderPubkey  string `json:"builder_pubkey"`
+	ProposerPubkey string `json:"proposer_pubkey"`
+	BlockHash      string `json:"block_hash"`
+	Value          string `json:"value"`
+	Relay          string `json:"relay,omitempty"`
+}
+
+// TrackBeacon places the including block on the beacon chain. SlotVerified is
+// true when the beacon block at Slot carries the including block's hash;
+// JustifiedSlot and FinalizedSlot are the slots of the checkpoint root blocks.
+type TrackBeacon struct {
+	Slot           uint64 `json:"slot"`
+	SlotVerified   bool   `json:"slot_verified"`
+	IsJustified    bool   `json:"is_justified"`
+	JustifiedEpoch uint64 `json:"justified_epoch"`
+	JustifiedSlot  uint64 `json:"justified_slot"`
+	IsFinalized    bool   `json:"is_finalized"`
+	FinalizedEpoch uint64 `json:"finalized_epoch"`
+	FinalizedSlot  uint64 `json:"finalized_slot"`
+}
@@ -1,4 +1,7 @@
 // Package domain: this file decodes transaction input (transfers, swaps, etc.). Used only by track (same package).
+// Selectors resolve through the signature registry (registry.go), arguments are
+// decoded by the ABI engine in abi.go, and the action decoders below turn the
+// named arguments into Details.
 package domain
 
 import (
@@ -8,6 +11,7 @@ import (
 	"strings"
 )
 
+// methodSignatures and knownContracts are the curated part of the registry.
 var methodSignatures = map[string]string{
 	"0xa9059cbb": "transfer(address,uint256)",
 	"0x23b872dd": "transferFrom(address,address,uint256)",
@@ -34,7 +38,14 @@ var methodSignatures = map[string]string{
 	"0x94bf804d": "mintWithSignature((address,uint256,string,uint256,uint256,bytes32,bytes))",
 	"0xb61d27f6": "execute(address,uint256,bytes)",
 	"0x1cff79cd": "execute(address,bytes)",
+	"0x3593564c": "execute(bytes,bytes[],uint256)",
+	"0x24856bc3": "execute(bytes,bytes[])",
+	"0xac9650d8": "multicall(bytes[])",
+	"0x5ae401dc": "multicall(uint256,bytes[])",
+	"0x252dba42": "aggregate((address,bytes)[])",
+	"0x6a761202": "execTransaction(address,uint256,bytes,uint8,uint256,uint256,uint256,address,address,bytes)",
 	"0x1fad948c": "handleOps((address,uint256,bytes,bytes,uint256,uint256,uint256,uint256,uint256,bytes,bytes)[],address)",
+	"0x765e827f": "handleOps((address,uint256,bytes,bytes,bytes32,uint256,bytes32,bytes,bytes)[],address)",
 	"0x590e1ae3": "refund()",
 	"0xfa89401a": "refund(address)",
 }
@@ -44,6 +55,8 @@ var knownContracts = map[string]string{
 	"0xe592427a0aece92de3edee1f18e0157c05861564": "Uniswap V3 Router",
 	"0x68b3465833fb72a70ecdf485e0e4c7bd8665fc45": "Uniswap V3 Router 2",
 	"0xef1c6e67703c7bd7107eed8303fbe6ec2554bf6b": "Uniswap Universal Router",
+	"0x3fc91a3afd70395cd496c647d5a6cc9d4b2b7fad": "Uniswap Universal Router 1.2",
+	"0x66a9893cc07d91d95644aedd05d03f95e1dba8af": "Uniswap Universal Router V4",
 	"0xd9e1ce17f2641f24ae83637ab66a2cca9c378b9f": "SushiSwap Router",
 	"0x1111111254eeb25477b68fb85ed929f73a960582": "1inch V5 Router",
 	"0xa5e0829caced8ffdd4de3c43696c57f7d7a678ff": "QuickSwap Router",
@@ -52,20 +65,101 @@ var knownContracts = map[string]string{
 	"0x6b175474e89094c44da98b954eedeac495271d0f": "Dai Stablecoin (DAI)",
 	"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2": "Wrapped Ether (WETH)",
 	"0x2260fac5e5542a773aa44fbcfedf7c193bc2c599": "Wrapped BTC (WBTC)",
+	"0x5ff137d4b0fdcd49dca30c7cf57e578a026d2789": "ERC-4337 EntryPoint v0.6",
+	"0x0000000071727de22e5e9d8baf0edac6f37da032": "ERC-4337 EntryPoint v0.7",
 }
 
 // DecodedTx contains human-readable info about what a transaction does.
 type DecodedTx struct {
-	MethodSignature string                 `json:"method_signature,omitempty"`
-	MethodName      string                 `json:"method_name,omitempty"`
-	ContractType    string                 `json:"contract_type,omitempty"`
-	Action          string                 `json:"action,omitempty"`
-	ActionType      string                 `json:"action_type,omitempty"`
-	Details         map[string]interface{} `json:"details,omitempty"`
+	MethodSignature string                  `json:"method_signature,omitempty"`
+	MethodName      string                  `json:"method_name,omitempty"`
+	ContractType    string                  `json:"contract_type,omitempty"`
+	Action          string                  `json:"action,omitempty"`
+	ActionType      string                  `json:"action_type,omitempty"`
+	Arguments       []ABIValue              `json:"arguments,omitempty"`
+	Details         map[string]interface{}  `json:"details,omitempty"`
+	Calls           []InnerCall             `json:"calls,omitempty"`
+	UserOperations  []UserOperation         `json:"user_operations,omitempty"`
+	Labels          map[string]AddressLabel `json:"labels,omitempty"`
 }
 
-// DecodeTransactionInput extracts meaningful info from tx input data.
+// DecodeTransactionInput extracts meaningful info from tx input data. Batched
+// calls are decoded into Calls; Labels covers the target and every address in
+// the arguments, Details and inner calls.
 func DecodeTransactionInput(input string, to *string, value string, receipt json.RawMessage) *DecodedTx {
+	b := newDecodeBudget()
+	decoded := decodeTransactionInput(input, to, value, receipt, 0, b)
+	if decoded != nil {
+		if b.exhausted {
+			decoded.Details["decode_truncated"] = true
+		}
+		addrs := decodedAddresses(decoded)
+		if to != nil {
+			addrs = append(addrs, *to)
+		}
+		decoded.Labels = LabelsFor(addrs...)
+	}
+	return decoded
+}
+
+// decodedAddresses collects the addresses in d's arguments, Details, Calls and
+// UserOperations.
+func decodedAddresses(d *DecodedTx) []string {
+	var out []string
+	var walk func(vs []ABIValue)
+	walk = func(vs []ABIValue) {
+		for _, v := range vs {
+			switch x := v.Value.(type) {
+			case string:
+				if v.Type == "address" {
+					out = append(out, x)
+				}
+			case []ABIValue:
+				walk(x)
+			}
+		}
+	}
+	walk(d.Arguments)
+	for _, k := range []string{"recipient", "spender", "from", "to", "to_address", "target", "beneficiary", "token_in", "token_out", "contract_address"} {
+		if s, ok := d.Details[k].(string); ok {
+			out = append(out, s)
+		}
+	}
+	if path, ok := d.Details["path"].([]string); ok {
+		out = append(out, path...)
+	}
+	if transfers, ok := d.Details["transfers"].([]map[string]interface{}); ok {
+		for _, t := range transfers {
+			for _, k := range []string{"token", "from", "to"} {
+				if s, ok := t[k].(string); ok {
+					out = append(out, s)
+				}
+			}
+		}
+	}
+	if cmds, ok := d.Details["commands"].([]map[string]interface{}); ok {
+		out = append(out, urAddresses(cmds)...)
+	}
+	for _, c := range d.Calls {
+		if c.Target != "" {
+			out = append(out, c.Target)
+		}
+		if c.Decoded != nil {
+			out = append(out, decodedAddresses(c.Decoded)...)
+		}
+	}
+	for _, u := range d.UserOperations {
+		out = append(out, u.Sender, u.Factory, u.Paymaster)
+		if u.Call != nil && u.Call.Decoded != nil {
+			out = append(out, decodedAddresses(u.Call.Decoded)...)
+		}
+	}
+	return out
+}
+
+// decodeTransactionInput decodes one call; depth is its nesting level inside
+// batched calls (0 for the transaction itself).
+func decodeTransactionInput(input string, to *string, value string, receipt json.RawMessage, depth int, b *decodeBudget) *DecodedTx {
 	if input == "" || input == "0x" {
 		return &DecodedTx{
 			Action: "ETH Transfer",
@@ -77,18 +171,19 @@ func DecodeTransactionInput(input string, to *string, value string, receipt json
 	if len(input) < 10 {
 		return nil
 	}
-	methodSig := input[:10]
-	methodName, known := methodSignatures[methodSig]
-	decoded := &DecodedTx{MethodSignature: methodSig, MethodName: methodName, Details: make(map[string]interface{})}
+	methodSig := strings.ToLower(input[:10])
+	toAddr := ""
 	if to != nil {
-		toAddr := strings.ToLower(*to)
-		if name, ok := knownContracts[toAddr]; ok {
-			decoded.ContractType = name
-			decoded.Details["contract_name"] = name
-			decoded.Details["contract_address"] = toAddr
-		}
+		toAddr = strings.ToLower(*to)
 	}
-	if !known {
+	call, entry, candidates, err := resolveCall(input, toAddr)
+	decoded := &DecodedTx{MethodSignature: methodSig, Details: make(map[string]interface{})}
+	if name := contractName(toAddr); name != "" {
+		decoded.ContractType = name
+		decoded.Details["contract_name"] = name
+		decoded.Details["contract_address"] = toAddr
+	}
+	if entry == nil {
 		decoded.ActionType = "contract_call"
 		decoded.Action = "Contract Interaction"
 		decoded.Details["type"] = "contract_call"
@@ -120,111 +215,162 @@ func DecodeTransactionInput(input string, to *string, value string, receipt json
 		}
 		return decoded
 	}
+	methodName := entry.Signature
+	decoded.MethodName = methodName
+	if err != nil {
+		decoded.Details["decode_error"] = err.Error()
+	} else {
+		decoded.Arguments = call.Args
+	}
+	if entry.Source != SourceBuiltin {
+		decoded.Details["signature_source"] = entry.Source
+	}
+	if len(candidates) > 1 {
+		others := make([]string, 0, len(candidates)-1)
+		for _, c := range candidates {
+			if c.Signature != methodName {
+				others = append(others, c.Signature)
+			}
+		}
+		decoded.Details["selector_collisions"] = others
+	}
 	if strings.HasPrefix(methodName, "transfer(") {
 		decoded.ActionType = "transfer"
-		decodeTransfer(decoded, input)
+		decodeTransfer(decoded, call)
 	} else if strings.HasPrefix(methodName, "transferFrom(") {
 		decoded.ActionType = "transferFrom"
-		decodeTransferFrom(decoded, input)
+		decodeTransferFrom(decoded, call)
 	} else if strings.Contains(methodName, "swap") || strings.Contains(methodName, "Swap") {
 		decoded.ActionType = "swap"
-		decodeSwap(decoded, input, value, receipt)
+		decodeSwap(decoded, call, value, receipt)
 	} else if strings.HasPrefix(methodName, "approve(") {
 		decoded.ActionType = "approve"
-		decodeApprove(decoded, input)
+		decodeApprove(decoded, call)
 	} else if strings.HasPrefix(methodName, "deposit(") {
 		decoded.ActionType = "deposit"
-		decodeDeposit(decoded, input, value)
+		decodeDeposit(decoded, call, value)
 	} else if strings.HasPrefix(methodName, "withdraw(") {
 		decoded.ActionType = "withdraw"
-		decodeWithdraw(decoded, input)
+		decodeWithdraw(decoded, call)
 	} else if strings.HasPrefix(methodName, "mint(") || strings.Contains(methodName, "mint") {
 		decoded.ActionType = "mint"
-		decodeMint(decoded, input)
+		decodeMint(decoded, call)
 	} else if strings.HasPrefix(methodName, "claim(") || strings.Contains(methodName, "claim") || strings.Contains(methodName, "Claim") {
 		decoded.ActionType = "claim"
-		decodeClaim(decoded, input, receipt)
+		decodeClaim(decoded, receipt)
+	} else if strings.HasPrefix(methodName, "execute(bytes,bytes[]") {
+		decoded.ActionType = "universal_router"
+		decodeUniversalRouter(decoded, call, toAddr, value, receipt, b)
+	} else if strings.HasPrefix(methodName, "multicall(") {
+		decoded.ActionType = "multicall"
+		decodeMulticall(decoded, call, toAddr, receipt, depth, b)
+	} else if strings.HasPrefix(methodName, "aggregate(") {
+		decoded.ActionType = "multicall"
+		decodeAggregate(decoded, call, receipt, depth, b)
+	} else if strings.HasPrefix(methodName, "execTransaction(") {
+		decoded.ActionType = "safe_exec"
+		decodeExecTransaction(decoded, call, depth, b)
 	} else if strings.HasPrefix(methodName, "execute(") {
 		decoded.ActionType = "execute"
-		decodeExecute(decoded, input)
+		decodeExecute(decoded, call, depth, b)
 	} else if strings.Contains(methodName, "handleOps") {
 		decoded.ActionType = "handleOps"
-		decodeHandleOps(decoded, input)
+		decodeHandleOps(decoded, call, toAddr, receipt, depth, b)
 	} else if strings.HasPrefix(methodName, "refund(") {
 		decoded.ActionType = "refund"
-		decodeRefund(decoded, input, receipt)
+		decodeRefund(decoded, receipt)
+	} else {
+		// Known to the registry, but no action decoder for it.
+		decoded.ActionType = "contract_call"
+		decoded.Action = "Call " + methodName[:strings.IndexByte(methodName, '(')]
+		decoded.Details["type"] = "contract_call"
+		decoded.Details["description"] = "Contract function call: " + methodName
+		if receipt != nil {
+			extractTransferEvents(decoded, receipt)
+		}
 	}
 	return decoded
 }
 
-func decodeTransfer(decoded *DecodedTx, input string) {
+// hexAmount renders an amount the way Details always has: 0x-prefixed hex wei.
+func hexAmount(v *big.Int) string { return "0x" + v.Text(16) }
+
+func decodeTransfer(decoded *DecodedTx, call *DecodedCall) {
 	decoded.Action = "Token Transfer"
 	decoded.Details["type"] = "erc20_transfer"
-	if len(input) < 74 {
-		return
-	}
-	recipient := "0x" + input[10:74][24:]
-	if len(input) >= 138 {
-		if amount, ok := new(big.Int).SetString(input[74:138], 16); ok {
-			decoded.Details["recipient"] = strings.ToLower(recipient)
-			decoded.Details["amount_wei"] = "0x" + amount.Text(16)
-			decoded.Details["description"] = fmt.Sprintf("Transfer tokens to %s", shortenHash(recipient))
-		}
+	recipient, ok1 := call.argString("to")
+	amount, ok2 := call.argBig("amount")
+	if ok1 && ok2 {
+		decoded.Details["recipient"] = recipient
+		decoded.Details["amount_wei"] = hexAmount(amount)
+		decoded.Details["description"] = fmt.Sprintf("Transfer tokens to %s", shortenHash(recipient))
 	}
 }
 
-func decodeApprove(decoded *DecodedTx, input string) {
+func decodeApprove(decoded *DecodedTx, call *DecodedCall) {
 	decoded.Action = "Token Approval"
 	decoded.Details["type"] = "erc20_approval"
-	if len(input) < 74 {
+	spender, ok1 := call.argString("spender")
+	amount, ok2 := call.argBig("amount")
+	if !ok1 || !ok2 {
 		return
 	}
-	spender := "0x" + input[10:74][24:]
-	if len(input) >= 138 {
-		if amount, ok := new(big.Int).SetString(input[74:138], 16); ok {
-			decoded.Details["spender"] = strings.ToLower(spender)
-			decoded.Details["amount_wei"] = "0x" + amount.Text(16)
-			maxUint256 := new(big.Int)
-			maxUint256.SetString("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 16)
-			if amount.Cmp(maxUint256) == 0 {
-				decoded.Details["description"] = fmt.Sprintf("Grant unlimited approval to %s", shortenHash(spender))
-				decoded.Details["unlimited"] = true
-			} else {
-				decoded.Details["description"] = fmt.Sprintf("Approve %s to spend tokens", shortenHash(spender))
-			}
-		}
+	decoded.Details["spender"] = spender
+	decoded.Details["amount_wei"] = hexAmount(amount)
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	if amount.Cmp(maxUint256) == 0 {
+		decoded.Details["description"] = fmt.Sprintf("Grant unlimited approval to %s", shortenHash(spender))
+		decoded.Details["unlimited"] = true
+	} else {
+		decoded.Details["description"] = fmt.Sprintf("Approve %s to spend tokens", shortenHash(spender))
 	}
 }
 
-func decodeTransferFrom(decoded *DecodedTx, input string) {
+func decodeTransferFrom(decoded *DecodedTx, call *DecodedCall) {
 	decoded.Action = "Token Transfer From"
 	decoded.Details["type"] = "erc20_transfer_from"
-	if len(input) < 138 {
-		return
-	}
-	from := "0x" + input[10:74][24:]
-	to := "0x" + input[74:138][24:]
-	if len(input) >= 202 {
-		if amount, ok := new(big.Int).SetString(input[138:202], 16); ok {
-			decoded.Details["from"] = strings.ToLower(from)
-			decoded.Details["to"] = strings.ToLower(to)
-			decoded.Details["amount_wei"] = "0x" + amount.Text(16)
-			decoded.Details["description"] = fmt.Sprintf("Transfer tokens from %s to %s", shortenHash(from), shortenHash(to))
-		}
+	from, ok1 := call.argString("from")
+	to, ok2 := call.argString("to")
+	amount, ok3 := call.argBig("amount")
+	if ok1 && ok2 && ok3 {
+		decoded.Details["from"] = from
+		decoded.Details["to"] = to
+		decoded.Details["amount_wei"] = hexAmount(amount)
+		decoded.Details["description"] = fmt.Sprintf("Transfer tokens from %s to %s", shortenHash(from), shortenHash(to))
 	}
 }
 
-func decodeSwap(decoded *DecodedTx, input string, value string, receipt json.RawMessage) {
+// decodeSwap covers the Uniswap V2-style router functions: amounts, the token
+// path, recipient and deadline come from the call; realized amounts from the receipt.
+func decodeSwap(decoded *DecodedTx, call *DecodedCall, value string, receipt json.RawMessage) {
 	decoded.Action = "Token Swap"
 	decoded.Details["type"] = "dex_swap"
-	if len(input) >= 200 {
+	if call != nil {
 		decoded.Details["description"] = "Swap tokens via DEX (Uniswap/SushiSwap/etc)"
-		if value != "" && value != "0x0" && value != "0x" {
-			if v, _ := new(big.Int).SetString(strings.TrimPrefix(value, "0x"), 16); v.Cmp(big.NewInt(0)) > 0 {
-				decoded.Details["swap_type"] = "eth_to_token"
-				decoded.Details["eth_in"] = value
+		if v, ok := new(big.Int).SetString(strings.TrimPrefix(value, "0x"), 16); ok && v.Sign() > 0 {
+			decoded.Details["swap_type"] = "eth_to_token"
+			decoded.Details["eth_in"] = value
+		}
+		for _, k := range []string{"amountIn", "amountOutMin", "amountOut", "amountInMax"} {
+			if v, ok := call.argBig(k); ok {
+				decoded.Details[camelToSnake(k)] = hexAmount(v)
 			}
 		}
+		if path, ok := call.argAddresses("path"); ok && len(path) >= 2 {
+			decoded.Details["path"] = path
+			decoded.Details["token_in"] = path[0]
+			decoded.Details["token_out"] = path[len(path)-1]
+			decoded.Details["hops"] = len(path) - 1
+			decoded.Details["description"] = fmt.Sprintf("Swap %s for %s via DEX (%d hop(s))",
+				firstNonEmpty(contractName(path[0]), shortenHash(path[0])),
+				firstNonEmpty(contractName(path[len(path)-1]), shortenHash(path[len(path)-1])), len(path)-1)
+		}
+		if to, ok := call.argString("to"); ok {
+			decoded.Details["recipient"] = to
+		}
+		if d, ok := call.argBig("deadline"); ok && d.IsInt64() {
+			decoded.Details["deadline"] = d.Int64()
+		}
 	}
 	if receipt != nil {
 		extractTransferEvents(decoded, receipt)
@@ -232,52 +378,57 @@ func decodeSwap(decoded *DecodedTx, input string, value string, receipt json.Raw
 	}
 }
 
-func decodeDeposit(decoded *DecodedTx, input string, value string) {
+// camelToSnake maps an ABI argument name like amountOutMin to amount_out_min.
+func camelToSnake(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func decodeDeposit(decoded *DecodedTx, call *DecodedCall, value string) {
 	decoded.Action = "Deposit"
 	decoded.Details["type"] = "deposit"
-	if value != "" && value != "0x0" && value != "0x" {
+	decoded.Details["description"] = "Deposit"
+	if v, ok := new(big.Int).SetString(strings.TrimPrefix(value, "0x"), 16); ok && v.Sign() > 0 {
 		decoded.Details["eth_amount"] = value
 		decoded.Details["description"] = fmt.Sprintf("Deposit %s ETH", weiToEthString(value))
-	} else if len(input) >= 74 {
-		if amount, ok := new(big.Int).SetString(input[10:74], 16); ok && amount.Cmp(big.NewInt(0)) > 0 {
-			decoded.Details["amount_wei"] = "0x" + amount.Text(16)
-			decoded.Details["description"] = "Deposit tokens"
-		} else {
-			decoded.Details["description"] = "Deposit"
-		}
-	} else {
-		decoded.Details["description"] = "Deposit"
+	} else if amount, ok := call.argBig("amount"); ok && amount.Sign() > 0 {
+		decoded.Details["amount_wei"] = hexAmount(amount)
+		decoded.Details["description"] = "Deposit tokens"
 	}
 }
 
-func decodeWithdraw(decoded *DecodedTx, input string) {
+func decodeWithdraw(decoded *DecodedTx, call *DecodedCall) {
 	decoded.Action = "Withdraw"
 	decoded.Details["type"] = "withdraw"
-	if len(input) >= 74 {
-		if amount, ok := new(big.Int).SetString(input[10:74], 16); ok && amount.Cmp(big.NewInt(0)) > 0 {
-			decoded.Details["amount_wei"] = "0x" + amount.Text(16)
-			decoded.Details["description"] = fmt.Sprintf("Withdraw %s tokens/ETH", weiToEthString("0x"+amount.Text(16)))
-		} else {
-			decoded.Details["description"] = "Withdraw"
-		}
-	} else {
+	amount, ok := call.argBig("amount")
+	switch {
+	case ok && amount.Sign() > 0:
+		decoded.Details["amount_wei"] = hexAmount(amount)
+		decoded.Details["description"] = fmt.Sprintf("Withdraw %s tokens/ETH", weiToEthString(hexAmount(amount)))
+	case ok:
+		decoded.Details["description"] = "Withdraw"
+	default:
 		decoded.Details["description"] = "Withdraw all"
 	}
 }
 
-func decodeMint(decoded *DecodedTx, input string) {
+func decodeMint(decoded *DecodedTx, call *DecodedCall) {
 	decoded.Action = "Mint"
 	decoded.Details["type"] = "mint"
-	if len(input) >= 74 {
-		addr := "0x" + input[10:74][24:]
-		if len(input[10:74]) == 64 {
-			decoded.Details["to_address"] = strings.ToLower(addr)
-		}
-		if len(input) >= 138 {
-			if amount, ok := new(big.Int).SetString(input[74:138], 16); ok {
-				decoded.Details["amount"] = "0x" + amount.Text(16)
-			}
-		}
+	if to, ok := call.argString("to"); ok {
+		decoded.Details["to_address"] = to
+	}
+	if amount, ok := call.argBig("amount"); ok {
+		decoded.Details["amount"] = hexAmount(amount)
 	}
 	if strings.Contains(decoded.MethodName, "Signature") {
 		decoded.Details["description"] = "Mint with Signature (gasless mint)"
@@ -286,7 +437,7 @@ func decodeMint(decoded *DecodedTx, input string) {
 	}
 }
 
-func decodeClaim(decoded *DecodedTx, _ string, receipt json.RawMessage) {
+func decodeClaim(decoded *DecodedTx, receipt json.RawMessage) {
 	decoded.Action = "Claim"
 	decoded.Details["type"] = "claim"
 	if receipt != nil {
@@ -307,22 +458,31 @@ func decodeClaim(decoded *DecodedTx, _ string, receipt json.RawMessage) {
 	}
 }
 
-func decodeExecute(decoded *DecodedTx, input string) {
+func decodeExecute(decoded *DecodedTx, call *DecodedCall, depth int, b *decodeBudget) {
 	decoded.Action = "Execute"
 	decoded.Details["type"] = "execute"
 	decoded.Details["description"] = "Execute transaction via smart contract wallet/multisig"
-	if len(input) >= 74 {
-		decoded.Details["target"] = strings.ToLower("0x" + input[10:74][24:])
+	target, ok := call.argString("target")
+	if ok {
+		decoded.Details["target"] = target
+	}
+	value, _ := call.argBig("value")
+	if value != nil && value.Sign() > 0 {
+		decoded.Details["value_wei"] = hexAmount(value)
+	}
+	if data, ok := call.argString("data"); ok && len(data) >= 10 {
+		decoded.Details["inner_selector"] = data[:10]
+		if name := functionSignature(data, target); name != "" {
+			decoded.Details["inner_method"] = name
+		}
+		if target != "" {
+			ic := decodeInner(decoded, target, data, value, depth, b)
+			decoded.Details["description"] = fmt.Sprintf("Smart contract wallet executes %s on %s", innerAction(ic), firstNonEmpty(contractName(target), shortenHash(target)))
+		}
 	}
 }
 
-func decodeHandleOps(decoded *DecodedTx, _ string) {
-	decoded.Action = "Handle Operations"
-	decoded.Details["type"] = "handle_ops"
-	decoded.Details["description"] = "Process bundled user operations (ERC-4337 Account Abstraction)"
-}
-
-func decodeRefund(decoded *DecodedTx, _ string, receipt json.RawMessage) {
+func decodeRefund(decoded *DecodedTx, receipt json.RawMessage) {
 	decoded.Action = "Refund"
 	decoded.Details["type"] = "refund"
 	if receipt != nil {
@@ -354,7 +514,7 @@ func extractTransferEvents(decoded *DecodedTx, receipt json.RawMessage) {
 			}
 			transfers = append(transfers, map[string]interface{}{
 				"token": strings.ToLower(log.Address), "from": strings.ToLower(from), "to": strings.ToLower(to),
-				"amount": "0x" + valueHex, "token_name": knownContracts[strings.ToLower(log.Address)],
+				"amount": "0x" + valueHex, "token_name": contractName(log.Address),
 			})
 		}
 	}
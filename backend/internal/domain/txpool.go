@@ -0,0 +1,355 @@
+// Package domain: this file reads the node's real transaction pool via
+// txpool_content (or the lighter txpool_inspect) when the RPC exposes the txpool
+// namespace, splitting each sender's transactions into pending (executable) and
+// queued (nonce-gapped). Without it the monitor keeps using the pending block.
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+	"github.com/you/eth-tx-lifecycle-backend/internal/clients/eth"
+)
+
+// ErrTxPoolUnsupported is returned when the RPC has not exposed txpool_content or txpool_inspect.
+var ErrTxPoolUnsupported = errors.New("txpool namespace not available on this RPC")
+
+// TxPoolSummary is the pool-wide view reported in MempoolData.TxPool.
+type TxPoolSummary struct {
+	Supported         bool   `json:"supported"`
+	Method            string `json:"method,omitempty"`
+	PendingCount      int    `json:"pendingCount"`
+	QueuedCount       int    `json:"queuedCount"`
+	SenderCount       int    `json:"senderCount"`
+	QueuedSenderCount int    `json:"queuedSenderCount"`
+	UpdatedAt         int64  `json:"updatedAt,omitempty"`
+	LastError         string `json:"lastError,omitempty"`
+}
+
+// TxPoolSender is one sender's slice of the pool. Queued txs sit behind a nonce
+// gap: NextNonce is the first nonce not covered by a pending tx, and queued txs
+// can't execute until it is filled.
+type TxPoolSender struct {
+	Address      string      `json:"address"`
+	PendingCount int         `json:"pendingCount"`
+	QueuedCount  int         `json:"queuedCount"`
+	NextNonce    *uint64     `json:"nextNonce,omitempty"`
+	FirstQueued  *uint64     `json:"firstQueuedNonce,omitempty"`
+	Pending      []PendingTx `json:"pending,omitempty"`
+	Queued       []PendingTx `json:"queued,omitempty"`
+}
+
+// TxPoolView is the /api/mempool/txpool response without an address filter.
+type TxPoolView struct {
+	Summary TxPoolSummary  `json:"summary"`
+	Senders []TxPoolSender `json:"senders"`
+}
+
+var (
+	txpoolMu       sync.RWMutex
+	txpoolSummary  TxPoolSummary
+	txpoolSenders  map[string]*TxPoolSender
+	txpoolRetryAt  time.Time
+	txpoolInterval time.Duration
+)
+
+func init() {
+	txpoolInterval = 15 * time.Second
+	if s := config.EnvOr("TXPOOL_POLL_SECONDS", ""); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 2 && n <= 300 {
+			txpoolInterval = time.Duration(n) * time.Second
+		}
+	}
+}
+
+// txpoolContentAvailable reports whether the last pool read came from
+// txpool_content, so mempoolPoll can skip the pending-block fallback.
+func txpoolContentAvailable() bool {
+	txpoolMu.RLock()
+	defer txpoolMu.RUnlock()
+	return txpoolSummary.Supported && txpoolSummary.Method == "txpool_content"
+}
+
+// isMethodMissing recognises "method not found" errors: JSON-RPC code -32601,
+// or a message saying the method is not found / does not exist (Geth's "the
+// method txpool_content does not exist/is not available"). Other errors that
+// merely mention "not found" (unknown block, missing tx) don't count.
+func isMethodMissing(err error) bool {
+	var rpcErr *eth.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == -32601 {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	i := strings.Index(msg, "method")
+	if i < 0 {
+		return false
+	}
+	rest := msg[i:]
+	return strings.Contains(rest, "not found") || strings.Contains(rest, "does not exist")
+}
+
+// txpoolPoll reads the pool every txpoolInterval. A missing namespace is
+// re-probed every 10 minutes, transient errors after a minute.
+func txpoolPoll() {
+	ticker := time.NewTicker(txpoolInterval)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		txpoolMu.RLock()
+		wait := time.Now().Before(txpoolRetryAt)
+		txpoolMu.RUnlock()
+		if wait {
+			continue
+		}
+		if err := refreshTxPool(); err != nil {
+			retry := time.Minute
+			if errors.Is(err, ErrTxPoolUnsupported) {
+				retry = 10 * time.Minute
+			}
+			log.Printf("mempool txpool: %v (retrying in %s)\n", err, retry)
+			txpoolMu.Lock()
+			txpoolSummary = TxPoolSummary{Supported: false, LastError: err.Error()}
+			txpoolSenders = nil
+			txpoolRetryAt = time.Now().Add(retry)
+			txpoolMu.Unlock()
+			mempoolMu.Lock()
+			mempoolData.TxPool = nil
+			mempoolMu.Unlock()
+		}
+	}
+}
+
+// refreshTxPool tries txpool_content, then txpool_inspect, and publishes the result.
+func refreshTxPool() error {
+	method := "txpool_content"
+	senders, err := fetchTxPoolContent()
+	if err != nil && isMethodMissing(err) {
+		method = "txpool_inspect"
+		senders, err = fetchTxPoolInspect()
+	}
+	if err != nil {
+		if isMethodMissing(err) {
+			return ErrTxPoolUnsupported
+		}
+		return err
+	}
+	now := time.Now().Unix()
+	summary := TxPoolSummary{Supported: true, Method: method, SenderCount: len(senders), UpdatedAt: now}
+	for _, snd := range senders {
+		finishSender(snd)
+		summary.PendingCount += snd.PendingCount
+		summary.QueuedCount += snd.QueuedCount
+		if snd.QueuedCount > 0 {
+			summary.QueuedSenderCount++
+		}
+	}
+	// txpool_inspect carries no hashes, so only txpool_content can feed history.
+	// A mainnet pool is far larger than the history cap, so this only refreshes
+	// tracked hashes and adds new ones while there's room (see refresh).
+	if method == "txpool_content" {
+		var pending []PendingTx
+		for _, snd := range senders {
+			pending = append(pending, snd.Pending...)
+		}
+		history.refresh(pending, now)
+		publishPending(now)
+	}
+	txpoolMu.Lock()
+	txpoolSummary = summary
+	txpoolSenders = senders
+	txpoolMu.Unlock()
+	mempoolMu.Lock()
+	mempoolData.TxPool = &summary
+	if mempoolData.Source != "ws" && method == "txpool_content" {
+		mempoolData.Source = "txpool"
+	}
+	mempoolMu.Unlock()
+	mempoolHealth.SetSuccess()
+	return nil
+}
+
+// txpoolSections is the {pending, queued} → sender → nonce → item shape shared by
+// txpool_content (item = tx object) and txpool_inspect (item = summary string).
+type txpoolSections[T any] struct {
+	Pending map[string]map[string]T `json:"pending"`
+	Queued  map[string]map[string]T `json:"queued"`
+}
+
+func fetchTxPoolContent() (map[string]*TxPoolSender, error) {
+	raw, err := eth.Call("txpool_content", []any{})
+	if err != nil {
+		return nil, err
+	}
+	var content txpoolSections[rpcPendingTx]
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	senders := map[string]*TxPoolSender{}
+	for section, bySender := range map[string]map[string]map[string]rpcPendingTx{"pending": content.Pending, "queued": content.Queued} {
+		for addr, byNonce := range bySender {
+			snd := txpoolSender(senders, addr)
+			for _, tx := range byNonce {
+				ptx := tx.toPendingTx(now)
+				if section == "pending" {
+					snd.Pending = append(snd.Pending, ptx)
+				} else {
+					snd.Queued = append(snd.Queued, ptx)
+				}
+			}
+		}
+	}
+	return senders, nil
+}
+
+// fetchTxPoolInspect parses txpool_inspect's "0xTo: 1 wei + 21000 gas × 2 wei"
+// strings into hash-less PendingTx entries.
+func fetchTxPoolInspect() (map[string]*TxPoolSender, error) {
+	raw, err := eth.Call("txpool_inspect", []any{})
+	if err != nil {
+		return nil, err
+	}
+	var inspect txpoolSections[string]
+	if err := json.Unmarshal(raw, &inspect); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	senders := map[string]*TxPoolSender{}
+	for section, bySender := range map[string]map[string]map[string]string{"pending": inspect.Pending, "queued": inspect.Queued} {
+		for addr, byNonce := range bySender {
+			snd := txpoolSender(senders, addr)
+			for nonce, summary := range byNonce {
+				ptx, ok := parseInspectEntry(addr, nonce, summary)
+				if !ok {
+					continue
+				}
+				ptx.Timestamp = now
+				if section == "pending" {
+					snd.Pending = append(snd.Pending, ptx)
+				} else {
+					snd.Queued = append(snd.Queued, ptx)
+				}
+			}
+		}
+	}
+	return senders, nil
+}
+
+func parseInspectEntry(from, nonce, summary string) (PendingTx, bool) {
+	target, rest, ok := strings.Cut(summary, ": ")
+	if !ok {
+		return PendingTx{}, false
+	}
+	var value, gas, gasPrice string
+	if _, err := fmt.Sscanf(strings.ReplaceAll(rest, "×", "x"), "%s wei + %s gas x %s wei", &value, &gas, &gasPrice); err != nil {
+		return PendingTx{}, false
+	}
+	n, err := strconv.ParseUint(nonce, 10, 64)
+	if err != nil {
+		return PendingTx{}, false
+	}
+	ptx := PendingTx{From: strings.ToLower(from), Nonce: "0x" + strconv.FormatUint(n, 16), Type: TxTypeLegacy}
+	if strings.HasPrefix(target, "0x") {
+		to := strings.ToLower(target)
+		ptx.To = &to
+	}
+	if v, ok := new(big.Int).SetString(value, 10); ok {
+		ptx.Value = "0x" + v.Text(16)
+	}
+	if g, err := strconv.ParseUint(gas, 10, 64); err == nil {
+		h := "0x" + strconv.FormatUint(g, 16)
+		ptx.Gas = &h
+	}
+	if p, ok := new(big.Int).SetString(gasPrice, 10); ok {
+		h := "0x" + p.Text(16)
+		ptx.GasPrice = &h
+	}
+	return ptx, true
+}
+
+func txpoolSender(senders map[string]*TxPoolSender, addr string) *TxPoolSender {
+	key := strings.ToLower(addr)
+	snd, ok := senders[key]
+	if !ok {
+		snd = &TxPoolSender{Address: key}
+		senders[key] = snd
+	}
+	return snd
+}
+
+// finishSender sorts a sender's txs by nonce and derives the nonce gap fields.
+func finishSender(snd *TxPoolSender) {
+	byNonce := func(txs []PendingTx) {
+		sort.Slice(txs, func(i, j int) bool {
+			a, _ := config.ParseHexUint64(txs[i].Nonce)
+			b, _ := config.ParseHexUint64(txs[j].Nonce)
+			return a < b
+		})
+	}
+	byNonce(snd.Pending)
+	byNonce(snd.Queued)
+	snd.PendingCount, snd.QueuedCount = len(snd.Pending), len(snd.Queued)
+	if n := len(snd.Pending); n > 0 {
+		if last, err := config.ParseHexUint64(snd.Pending[n-1].Nonce); err == nil {
+			next := last + 1
+			snd.NextNonce = &next
+		}
+	}
+	if len(snd.Queued) > 0 {
+		if first, err := config.ParseHexUint64(snd.Queued[0].Nonce); err == nil {
+			snd.FirstQueued = &first
+		}
+	}
+}
+
+// GetTxPoolSender returns one sender's pending/queued split from the latest pool read.
+func GetTxPoolSender(address string) (TxPoolSender, error) {
+	txpoolMu.RLock()
+	defer txpoolMu.RUnlock()
+	if !txpoolSummary.Supported {
+		return TxPoolSender{}, ErrTxPoolUnsupported
+	}
+	key := strings.ToLower(address)
+	if snd, ok := txpoolSenders[key]; ok {
+		return *snd, nil
+	}
+	return TxPoolSender{Address: key}, nil
+}
+
+// GetTxPool returns the pool summary and up to limit senders (without tx bodies),
+// those with queued txs first, then by pending count.
+func GetTxPool(limit int) (TxPoolView, error) {
+	txpoolMu.RLock()
+	defer txpoolMu.RUnlock()
+	if !txpoolSummary.Supported {
+		return TxPoolView{Summary: txpoolSummary, Senders: []TxPoolSender{}}, ErrTxPoolUnsupported
+	}
+	out := TxPoolView{Summary: txpoolSummary, Senders: make([]TxPoolSender, 0, len(txpoolSenders))}
+	for _, snd := range txpoolSenders {
+		s := *snd
+		s.Pending, s.Queued = nil, nil
+		out.Senders = append(out.Senders, s)
+	}
+	sort.Slice(out.Senders, func(i, j int) bool {
+		a, b := out.Senders[i], out.Senders[j]
+		if a.QueuedCount != b.QueuedCount {
+			return a.QueuedCount > b.QueuedCount
+		}
+		if a.PendingCount != b.PendingCount {
+			return a.PendingCount > b.PendingCount
+		}
+		return a.Address < b.Address
+	})
+	if len(out.Senders) > limit {
+		out.Senders = out.Senders[:limit]
+	}
+	return out, nil
+}
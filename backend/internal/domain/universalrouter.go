@@ -0,0 +1,565 @@
+// Package domain: this file decodes Uniswap Universal Router execute() calls.
+// The commands byte-string is a program: each byte picks a command (low 6 bits)
+// and whether it may revert (top bit), and inputs[i] is that command's
+// ABI-encoded arguments. Every command becomes one ordered sub-action in
+// Details["commands"]; V4_SWAP's nested actions and EXECUTE_SUB_PLAN's nested
+// program are decoded the same way.
+package domain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	urCommandMask   = 0x3f
+	urAllowRevert   = 0x80
+	urMaxPlanDepth  = 4
+	urMsgSender     = "0x0000000000000000000000000000000000000001" // recipient constant: the caller
+	urAddressThis   = "0x0000000000000000000000000000000000000002" // recipient constant: the router itself
+	urNativeV4      = "0x0000000000000000000000000000000000000000" // V4 currency for ETH
+	wethAddress     = "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"
+	urCommandSubPln = 0x21
+)
+
+// urContractBalance as an amount means "the router's whole balance of the token".
+var urContractBalance = new(big.Int).Lsh(big.NewInt(1), 255)
+
+// urV1Routers predate V4: their commands 0x10-0x20 are NFT marketplace calls,
+// where later routers use 0x10-0x14 for V4 and position managers.
+var urV1Routers = map[string]bool{
+	"0xef1c6e67703c7bd7107eed8303fbe6ec2554bf6b": true,
+	"0x3fc91a3afd70395cd496c647d5a6cc9d4b2b7fad": true,
+}
+
+// urCommand is one Universal Router command: its name, what kind of sub-action
+// it is, and the signature (name + argument tuple) its input is encoded as.
+type urCommand struct {
+	Name  string
+	Kind  string
+	Sig   string
+	Names []string
+}
+
+var urCommands = map[byte]urCommand{
+	0x00: {"V3_SWAP_EXACT_IN", "swap", "V3_SWAP_EXACT_IN(address,uint256,uint256,bytes,bool)", []string{"recipient", "amountIn", "amountOutMin", "path", "payerIsUser"}},
+	0x01: {"V3_SWAP_EXACT_OUT", "swap", "V3_SWAP_EXACT_OUT(address,uint256,uint256,bytes,bool)", []string{"recipient", "amountOut", "amountInMax", "path", "payerIsUser"}},
+	0x02: {"PERMIT2_TRANSFER_FROM", "transfer", "PERMIT2_TRANSFER_FROM(address,address,uint160)", []string{"token", "recipient", "amount"}},
+	0x03: {"PERMIT2_PERMIT_BATCH", "permit", "PERMIT2_PERMIT_BATCH(((address,uint160,uint48,uint48)[],address,uint256),bytes)", []string{"permitBatch", "signature"}},
+	0x04: {"SWEEP", "sweep", "SWEEP(address,address,uint256)", []string{"token", "recipient", "amountMin"}},
+	0x05: {"TRANSFER", "transfer", "TRANSFER(address,address,uint256)", []string{"token", "recipient", "value"}},
+	0x06: {"PAY_PORTION", "pay_portion", "PAY_PORTION(address,address,uint256)", []string{"token", "recipient", "bips"}},
+	0x08: {"V2_SWAP_EXACT_IN", "swap", "V2_SWAP_EXACT_IN(address,uint256,uint256,address[],bool)", []string{"recipient", "amountIn", "amountOutMin", "path", "payerIsUser"}},
+	0x09: {"V2_SWAP_EXACT_OUT", "swap", "V2_SWAP_EXACT_OUT(address,uint256,uint256,address[],bool)", []string{"recipient", "amountOut", "amountInMax", "path", "payerIsUser"}},
+	0x0a: {"PERMIT2_PERMIT", "permit", "PERMIT2_PERMIT(((address,uint160,uint48,uint48),address,uint256),bytes)", []string{"permitSingle", "signature"}},
+	0x0b: {"WRAP_ETH", "wrap", "WRAP_ETH(address,uint256)", []string{"recipient", "amountMin"}},
+	0x0c: {"UNWRAP_WETH", "unwrap", "UNWRAP_WETH(address,uint256)", []string{"recipient", "amountMin"}},
+	0x0d: {"PERMIT2_TRANSFER_FROM_BATCH", "transfer", "PERMIT2_TRANSFER_FROM_BATCH((address,address,uint160,address)[])", []string{"batchDetails"}},
+	0x0e: {"BALANCE_CHECK_ERC20", "check", "BALANCE_CHECK_ERC20(address,address,uint256)", []string{"owner", "token", "minBalance"}},
+	0x21: {"EXECUTE_SUB_PLAN", "sub_plan", "EXECUTE_SUB_PLAN(bytes,bytes[])", []string{"commands", "inputs"}},
+}
+
+// urCommandsV2 are the V4-era commands (Universal Router 2.0).
+var urCommandsV2 = map[byte]urCommand{
+	0x10: {"V4_SWAP", "swap", "V4_SWAP(bytes,bytes[])", []string{"actions", "params"}},
+	0x11: {"V3_POSITION_MANAGER_PERMIT", "liquidity", "V3_POSITION_MANAGER_PERMIT(bytes)", []string{"call"}},
+	0x12: {"V3_POSITION_MANAGER_CALL", "liquidity", "V3_POSITION_MANAGER_CALL(bytes)", []string{"call"}},
+	0x13: {"V4_INITIALIZE_POOL", "liquidity", "V4_INITIALIZE_POOL((address,address,uint24,int24,address),uint160)", []string{"poolKey", "sqrtPriceX96"}},
+	0x14: {"V4_POSITION_MANAGER_CALL", "liquidity", "V4_POSITION_MANAGER_CALL(bytes)", []string{"call"}},
+}
+
+// v4PoolKey and v4PathKey are the V4 router's PoolKey and PathKey structs.
+const (
+	v4PoolKey = "(address,address,uint24,int24,address)"
+	v4PathKey = "(address,uint24,int24,address,bytes)"
+)
+
+// v4Actions are the V4 router actions inside a V4_SWAP (params[i] is one struct).
+var v4Actions = map[byte]urCommand{
+	0x06: {"SWAP_EXACT_IN_SINGLE", "swap", "SWAP_EXACT_IN_SINGLE((" + v4PoolKey + ",bool,uint128,uint128,bytes))", nil},
+	0x07: {"SWAP_EXACT_IN", "swap", "SWAP_EXACT_IN((address," + v4PathKey + "[],uint128,uint128))", nil},
+	0x08: {"SWAP_EXACT_OUT_SINGLE", "swap", "SWAP_EXACT_OUT_SINGLE((" + v4PoolKey + ",bool,uint128,uint128,bytes))", nil},
+	0x09: {"SWAP_EXACT_OUT", "swap", "SWAP_EXACT_OUT((address," + v4PathKey + "[],uint128,uint128))", nil},
+	0x0b: {"SETTLE", "settle", "SETTLE(address,uint256,bool)", []string{"token", "amount", "payerIsUser"}},
+	0x0c: {"SETTLE_ALL", "settle", "SETTLE_ALL(address,uint256)", []string{"token", "maxAmount"}},
+	0x0d: {"SETTLE_PAIR", "settle", "SETTLE_PAIR(address,address)", []string{"token0", "token1"}},
+	0x0e: {"TAKE", "take", "TAKE(address,address,uint256)", []string{"token", "recipient", "amount"}},
+	0x0f: {"TAKE_ALL", "take", "TAKE_ALL(address,uint256)", []string{"token", "minAmount"}},
+	0x10: {"TAKE_PORTION", "take", "TAKE_PORTION(address,address,uint256)", []string{"token", "recipient", "bips"}},
+	0x11: {"TAKE_PAIR", "take", "TAKE_PAIR(address,address,address)", []string{"token0", "token1", "recipient"}},
+	0x12: {"CLOSE_CURRENCY", "settle", "CLOSE_CURRENCY(address)", []string{"token"}},
+	0x14: {"SWEEP", "sweep", "SWEEP(address,address)", []string{"token", "recipient"}},
+	0x15: {"WRAP", "wrap", "WRAP(uint256)", []string{"amount"}},
+	0x16: {"UNWRAP", "unwrap", "UNWRAP(uint256)", []string{"amount"}},
+}
+
+// decodeUniversalRouter fills Details["commands"] from execute(commands, inputs[, deadline]),
+// plus the overall token_in/token_out of the swaps it contains.
+func decodeUniversalRouter(decoded *DecodedTx, call *DecodedCall, to, value string, receipt json.RawMessage, b *decodeBudget) {
+	decoded.Action = "Universal Router"
+	decoded.Details["type"] = "universal_router"
+	commands, ok1 := call.argString("commands")
+	inputs := call.arg("inputs")
+	if !ok1 || inputs == nil {
+		decoded.Details["description"] = "Uniswap Universal Router execute (inputs could not be decoded)"
+		return
+	}
+	if d, ok := call.argBig("deadline"); ok && d.IsInt64() {
+		decoded.Details["deadline"] = d.Int64()
+	}
+	v1 := urV1Routers[strings.ToLower(to)]
+	subs := urDecodePlan(commands, abiList(*inputs), v1, 0, b)
+	decoded.Details["commands"] = subs
+	decoded.Details["command_count"] = len(subs)
+
+	var names []string
+	var swaps []map[string]interface{}
+	wrapped, unwrapped := false, false
+	for _, s := range urFlatten(subs) {
+		switch {
+		case s["kind"] == "swap" && s["token_in"] != nil:
+			swaps = append(swaps, s)
+		case s["kind"] == "wrap" && len(swaps) == 0:
+			wrapped = true
+		case s["kind"] == "unwrap" && len(swaps) > 0:
+			unwrapped = true
+		}
+	}
+	for _, s := range subs {
+		names = append(names, s["command"].(string))
+	}
+	desc := "Uniswap Universal Router: " + strings.Join(names, " → ")
+	if len(swaps) > 0 {
+		first, last := swaps[0], swaps[len(swaps)-1]
+		tokenIn, _ := first["token_in"].(string)
+		tokenOut, _ := last["token_out"].(string)
+		// WRAP_ETH before the swaps or UNWRAP_WETH after them make it an ETH trade.
+		if wrapped && tokenIn == wethAddress {
+			tokenIn = "ETH"
+		}
+		if unwrapped && tokenOut == wethAddress {
+			tokenOut = "ETH"
+		}
+		decoded.Action = "Token Swap"
+		decoded.ActionType = "swap"
+		decoded.Details["type"] = "dex_swap"
+		decoded.Details["token_in"] = tokenIn
+		decoded.Details["token_out"] = tokenOut
+		decoded.Details["swap_count"] = len(swaps)
+		for _, k := range []string{"amount_in", "amount_in_max"} {
+			if v, ok := first[k]; ok {
+				decoded.Details[k] = v
+			}
+		}
+		for _, k := range []string{"amount_out", "amount_out_min"} {
+			if v, ok := last[k]; ok {
+				decoded.Details[k] = v
+			}
+		}
+		if v, ok := new(big.Int).SetString(strings.TrimPrefix(value, "0x"), 16); ok && v.Sign() > 0 {
+			decoded.Details["swap_type"] = "eth_to_token"
+			decoded.Details["eth_in"] = value
+		}
+		desc = fmt.Sprintf("Swap %s for %s via Uniswap Universal Router (%s)", urTokenName(tokenIn), urTokenName(tokenOut), strings.Join(names, " → "))
+	}
+	decoded.Details["description"] = desc
+	if receipt != nil {
+		extractTransferEvents(decoded, receipt)
+		if len(swaps) > 0 {
+			calculateSwapPrice(decoded)
+		}
+	}
+}
+
+// urDecodePlan decodes one commands/inputs program into ordered sub-actions.
+// Each decoded input is charged to b; inputs aliasing an earlier one aren't decoded.
+func urDecodePlan(commands string, inputs []ABIValue, v1 bool, depth int, b *decodeBudget) []map[string]interface{} {
+	cmds, err := hex.DecodeString(strings.TrimPrefix(commands, "0x"))
+	if err != nil {
+		return nil
+	}
+	out := make([]map[string]interface{}, 0, len(cmds))
+	seen := payloadSet{}
+	for i, c := range cmds {
+		sub := map[string]interface{}{"index": i, "command_byte": fmt.Sprintf("0x%02x", c)}
+		if c&urAllowRevert != 0 {
+			sub["allow_revert"] = true
+		}
+		out = append(out, sub)
+		code := c & urCommandMask
+		cmd, ok := urCommands[code]
+		if !ok && !v1 {
+			cmd, ok = urCommandsV2[code]
+		}
+		if !ok {
+			sub["command"], sub["kind"] = fmt.Sprintf("UNKNOWN_0x%02x", code), "unknown"
+			if v1 && code >= 0x10 && code <= 0x20 {
+				sub["command"], sub["kind"] = fmt.Sprintf("NFT_0x%02x", code), "nft"
+			}
+			continue
+		}
+		sub["command"], sub["kind"] = cmd.Name, cmd.Kind
+		if i >= len(inputs) {
+			sub["decode_error"] = "missing input"
+			continue
+		}
+		raw, ok := urTakeInput(sub, seen, inputs[i], i, "command", b)
+		if !ok {
+			continue
+		}
+		args, err := decodeABIArgs(cmd.Sig, raw, cmd.Names)
+		if err != nil {
+			sub["decode_error"] = err.Error()
+			continue
+		}
+		urFillCommand(sub, code, args, v1, depth, b)
+	}
+	return out
+}
+
+// urFillCommand adds a decoded command's tokens, amounts and recipient to sub.
+func urFillCommand(sub map[string]interface{}, code byte, args []ABIValue, v1 bool, depth int, b *decodeBudget) {
+	arg := func(name string) ABIValue {
+		for _, a := range args {
+			if a.Name == name {
+				return a
+			}
+		}
+		return ABIValue{}
+	}
+	if r := abiStr(arg("recipient")); r != "" {
+		urRecipient(sub, r)
+	}
+	for _, k := range []string{"amountIn", "amountOutMin", "amountOut", "amountInMax", "amountMin", "amount", "value"} {
+		if a := arg(k); a.Value != nil {
+			urAmount(sub, camelToSnake(k), abiStr(a))
+		}
+	}
+	if t := abiStr(arg("token")); t != "" {
+		sub["token"] = t
+	}
+	switch code {
+	case 0x00, 0x01: // V3: path is token(20) fee(3) token(20)..., reversed for exact-out
+		tokens, fees := urV3Path(abiStr(arg("path")))
+		if code == 0x01 {
+			for i, j := 0, len(tokens)-1; i < j; i, j = i+1, j-1 {
+				tokens[i], tokens[j] = tokens[j], tokens[i]
+			}
+			for i, j := 0, len(fees)-1; i < j; i, j = i+1, j-1 {
+				fees[i], fees[j] = fees[j], fees[i]
+			}
+		}
+		urPath(sub, tokens)
+		if len(fees) > 0 {
+			sub["fees"] = fees
+		}
+	case 0x08, 0x09:
+		var path []string
+		for _, v := range abiList(arg("path")) {
+			path = append(path, abiStr(v))
+		}
+		urPath(sub, path)
+	case 0x06:
+		sub["bips"] = urInt(abiStr(arg("bips")))
+	case 0x0a: // ((token, amount, expiration, nonce), spender, sigDeadline)
+		single := abiList(arg("permitSingle"))
+		if len(single) == 3 {
+			details := abiList(single[0])
+			if len(details) == 4 {
+				sub["token"] = abiStr(details[0])
+				urAmount(sub, "amount", abiStr(details[1]))
+				sub["expiration"] = urInt(abiStr(details[2]))
+			}
+			sub["spender"] = abiStr(single[1])
+		}
+	case 0x03:
+		batch := abiList(arg("permitBatch"))
+		if len(batch) == 3 {
+			var tokens []string
+			for _, d := range abiList(batch[0]) {
+				if fields := abiList(d); len(fields) == 4 {
+					tokens = append(tokens, abiStr(fields[0]))
+				}
+			}
+			sub["tokens"] = tokens
+			sub["spender"] = abiStr(batch[1])
+		}
+	case 0x0d:
+		var transfers []map[string]interface{}
+		for _, d := range abiList(arg("batchDetails")) {
+			if f := abiList(d); len(f) == 4 {
+				t := map[string]interface{}{"from": abiStr(f[0]), "to": abiStr(f[1]), "token": abiStr(f[3])}
+				urAmount(t, "amount", abiStr(f[2]))
+				transfers = append(transfers, t)
+			}
+		}
+		sub["transfers"] = transfers
+	case 0x0b:
+		sub["token_in"], sub["token_out"] = "ETH", wethAddress
+	case 0x0c:
+		sub["token_in"], sub["token_out"] = wethAddress, "ETH"
+	case urCommandSubPln:
+		if depth+1 >= urMaxPlanDepth {
+			sub["decode_error"] = "sub-plan nesting too deep"
+			return
+		}
+		sub["sub_plan"] = urDecodePlan(abiStr(arg("commands")), abiList(arg("inputs")), v1, depth+1, b)
+	case 0x10:
+		if !v1 {
+			urFillV4Swap(sub, abiStr(arg("actions")), abiList(arg("params")), b)
+		}
+	}
+}
+
+// urFillV4Swap decodes the V4 router actions inside a V4_SWAP command. The
+// command's own token_in/token_out come from its first and last swap action.
+func urFillV4Swap(sub map[string]interface{}, actions string, params []ABIValue, b *decodeBudget) {
+	codes, err := hex.DecodeString(strings.TrimPrefix(actions, "0x"))
+	if err != nil {
+		return
+	}
+	seen := payloadSet{}
+	var list, swaps []map[string]interface{}
+	for i, code := range codes {
+		a := map[string]interface{}{"index": i, "action_byte": fmt.Sprintf("0x%02x", code)}
+		list = append(list, a)
+		act, ok := v4Actions[code]
+		if !ok {
+			a["action"], a["kind"] = fmt.Sprintf("UNKNOWN_0x%02x", code), "unknown"
+			continue
+		}
+		a["action"], a["kind"] = act.Name, act.Kind
+		if i >= len(params) {
+			a["decode_error"] = "missing params"
+			continue
+		}
+		raw, ok := urTakeInput(a, seen, params[i], i, "action", b)
+		if !ok {
+			continue
+		}
+		args, err := decodeABIArgs(act.Sig, raw, act.Names)
+		if err != nil {
+			a["decode_error"] = err.Error()
+			continue
+		}
+		if act.Kind != "swap" {
+			for _, v := range args {
+				switch v.Name {
+				case "token", "token0", "token1":
+					a[v.Name] = urCurrency(abiStr(v))
+				case "recipient":
+					urRecipient(a, abiStr(v))
+				case "amount", "maxAmount", "minAmount":
+					urAmount(a, camelToSnake(v.Name), abiStr(v))
+				case "bips":
+					a["bips"] = urInt(abiStr(v))
+				}
+			}
+			continue
+		}
+		p := abiList(args[0])
+		switch code {
+		case 0x06, 0x08: // (poolKey, zeroForOne, amount, limit, hookData)
+			if len(p) != 5 {
+				continue
+			}
+			key := abiList(p[0])
+			if len(key) != 5 {
+				continue
+			}
+			c0, c1 := urCurrency(abiStr(key[0])), urCurrency(abiStr(key[1]))
+			if zeroForOne, _ := p[1].Value.(bool); zeroForOne {
+				a["token_in"], a["token_out"] = c0, c1
+			} else {
+				a["token_in"], a["token_out"] = c1, c0
+			}
+			a["fee"] = urInt(abiStr(key[2]))
+			if hooks := abiStr(key[4]); hooks != urNativeV4 {
+				a["hooks"] = hooks
+			}
+			if code == 0x06 {
+				urAmount(a, "amount_in", abiStr(p[2]))
+				urAmount(a, "amount_out_min", abiStr(p[3]))
+			} else {
+				urAmount(a, "amount_out", abiStr(p[2]))
+				urAmount(a, "amount_in_max", abiStr(p[3]))
+			}
+		case 0x07, 0x09: // (currencyIn|currencyOut, PathKey[], amount, limit)
+			if len(p) != 4 {
+				continue
+			}
+			var hops []string
+			for _, k := range abiList(p[1]) {
+				if f := abiList(k); len(f) == 5 {
+					hops = append(hops, urCurrency(abiStr(f[0])))
+				}
+			}
+			edge := urCurrency(abiStr(p[0]))
+			path := append([]string{edge}, hops...)
+			if code == 0x09 {
+				path = append(hops, edge)
+			}
+			urPath(a, path)
+			if code == 0x07 {
+				urAmount(a, "amount_in", abiStr(p[2]))
+				urAmount(a, "amount_out_min", abiStr(p[3]))
+			} else {
+				urAmount(a, "amount_out", abiStr(p[2]))
+				urAmount(a, "amount_in_max", abiStr(p[3]))
+			}
+		}
+		if a["token_in"] != nil {
+			swaps = append(swaps, a)
+		}
+	}
+	sub["actions"] = list
+	if len(swaps) > 0 {
+		first, last := swaps[0], swaps[len(swaps)-1]
+		sub["token_in"], sub["token_out"] = first["token_in"], last["token_out"]
+		for _, k := range []string{"amount_in", "amount_in_max"} {
+			if v, ok := first[k]; ok {
+				sub[k] = v
+			}
+		}
+		for _, k := range []string{"amount_out", "amount_out_min"} {
+			if v, ok := last[k]; ok {
+				sub[k] = v
+			}
+		}
+	}
+}
+
+// urTakeInput returns the raw bytes of input i (of a command or action),
+// charged to b. It records a decode_error in sub instead when the input aliases
+// an earlier one or the budget is spent.
+func urTakeInput(sub map[string]interface{}, seen payloadSet, input ABIValue, i int, what string, b *decodeBudget) ([]byte, bool) {
+	if j, ok := seen.alias(input, i); ok {
+		sub["decode_error"] = fmt.Sprintf("input aliases %s %d", what, j)
+		return nil, false
+	}
+	raw, _ := hex.DecodeString(strings.TrimPrefix(abiStr(input), "0x"))
+	if !b.take(len(raw)) {
+		sub["decode_error"] = "decode budget exhausted"
+		return nil, false
+	}
+	return raw, true
+}
+
+// urFlatten lists sub-actions depth-first, including sub-plan commands.
+func urFlatten(subs []map[string]interface{}) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, s := range subs {
+		out = append(out, s)
+		if plan, ok := s["sub_plan"].([]map[string]interface{}); ok {
+			out = append(out, urFlatten(plan)...)
+		}
+	}
+	return out
+}
+
+// urV3Path splits a V3 packed path into tokens and fee tiers.
+func urV3Path(path string) ([]string, []int64) {
+	b, err := hex.DecodeString(strings.TrimPrefix(path, "0x"))
+	if err != nil || len(b) < 20 || (len(b)-20)%23 != 0 {
+		return nil, nil
+	}
+	tokens := []string{"0x" + hex.EncodeToString(b[:20])}
+	var fees []int64
+	for off := 20; off < len(b); off += 23 {
+		fees = append(fees, int64(b[off])<<16|int64(b[off+1])<<8|int64(b[off+2]))
+		tokens = append(tokens, "0x"+hex.EncodeToString(b[off+3:off+23]))
+	}
+	return tokens, fees
+}
+
+func urPath(sub map[string]interface{}, path []string) {
+	if len(path) < 2 {
+		return
+	}
+	sub["path"] = path
+	sub["token_in"], sub["token_out"] = path[0], path[len(path)-1]
+}
+
+// urRecipient records a recipient, naming the router's sentinel addresses.
+func urRecipient(sub map[string]interface{}, r string) {
+	sub["recipient"] = r
+	switch r {
+	case urMsgSender:
+		sub["recipient_role"] = "msg.sender"
+	case urAddressThis:
+		sub["recipient_role"] = "router"
+	}
+}
+
+// urAmount stores a decimal amount as 0x-hex wei, flagging CONTRACT_BALANCE.
+func urAmount(sub map[string]interface{}, key, decimal string) {
+	v, ok := new(big.Int).SetString(decimal, 10)
+	if !ok {
+		return
+	}
+	if v.Cmp(urContractBalance) == 0 {
+		sub[key+"_is_contract_balance"] = true
+		return
+	}
+	sub[key] = hexAmount(v)
+}
+
+// urCurrency maps V4's zero-address currency to "ETH".
+func urCurrency(c string) string {
+	if c == urNativeV4 {
+		return "ETH"
+	}
+	return c
+}
+
+func urInt(decimal string) int64 {
+	v, ok := new(big.Int).SetString(decimal, 10)
+	if !ok || !v.IsInt64() {
+		return 0
+	}
+	return v.Int64()
+}
+
+func urTokenName(t string) string {
+	if t == "ETH" || t == "" {
+		return firstNonEmpty(t, "?")
+	}
+	return firstNonEmpty(contractName(t), shortenHash(t))
+}
+
+// urAddresses lists the token, recipient and spender addresses in sub-actions,
+// including V4 actions and sub-plans, for labelling.
+func urAddresses(subs []map[string]interface{}) []string {
+	var out []string
+	for _, s := range subs {
+		for _, k := range []string{"token_in", "token_out", "token", "token0", "token1", "recipient", "spender", "from", "to"} {
+			if v, ok := s[k].(string); ok && strings.HasPrefix(v, "0x") {
+				out = append(out, v)
+			}
+		}
+		for _, k := range []string{"path", "tokens"} {
+			if v, ok := s[k].([]string); ok {
+				out = append(out, v...)
+			}
+		}
+		for _, k := range []string{"actions", "sub_plan", "transfers"} {
+			if v, ok := s[k].([]map[string]interface{}); ok {
+				out = append(out, urAddresses(v)...)
+			}
+		}
+	}
+	return out
+}
+
+func abiStr(v ABIValue) string {
+	s, _ := v.Value.(string)
+	return s
+}
+
+func abiList(v ABIValue) []ABIValue {
+	l, _ := v.Value.([]ABIValue)
+	return l
+}
@@ -0,0 +1,311 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Mainnet addresses used by the Universal Router fixtures.
+const (
+	urRouterV1_2 = "0x3fc91a3afd70395cd496c647d5a6cc9d4b2b7fad" // Universal Router 1.2 (pre-V4)
+	urRouterV2   = "0x66a9893cc07d91d95644aedd05d03f95e1dba8af" // Universal Router 2.0 (V4)
+	tokenUSDC    = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	tokenDAI     = "0x6b175474e89094c44da98b954eedeac495271d0f"
+)
+
+// urInput encodes one command's (or V4 action's) input for signature sig.
+func urInput(t testing.TB, sig string, vals ...any) string {
+	t.Helper()
+	return "0x" + calldata(t, sig, vals...)[10:]
+}
+
+// urExecute encodes execute(commands, inputs, deadline).
+func urExecute(t testing.TB, commands string, inputs ...string) string {
+	t.Helper()
+	in := make([]any, len(inputs))
+	for i, s := range inputs {
+		in[i] = s
+	}
+	return calldata(t, "execute(bytes,bytes[],uint256)", commands, in, "1700000000")
+}
+
+// v3Path packs token(20) fee(3) token(20)... as the V3 router expects.
+func v3Path(tokens []string, fees ...int) string {
+	var b strings.Builder
+	b.WriteString("0x")
+	for i, tok := range tokens {
+		b.WriteString(strings.TrimPrefix(tok, "0x"))
+		if i < len(fees) {
+			fmt.Fprintf(&b, "%06x", fees[i])
+		}
+	}
+	return b.String()
+}
+
+// detailAt walks Details by a dotted path of map keys and slice indexes.
+func detailAt(v any, path string) any {
+	for _, k := range strings.Split(path, ".") {
+		switch x := v.(type) {
+		case map[string]interface{}:
+			v = x[k]
+		case []map[string]interface{}:
+			i, err := strconv.Atoi(k)
+			if err != nil || i >= len(x) {
+				return nil
+			}
+			v = x[i]
+		case []string:
+			i, err := strconv.Atoi(k)
+			if err != nil || i >= len(x) {
+				return nil
+			}
+			v = x[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+func TestDecodeUniversalRouter(t *testing.T) {
+	const (
+		oneETH     = "1000000000000000000"
+		oneETHHex  = "0xde0b6b3a7640000"
+		usdc3k     = "3000000000" // 3,000 USDC (6 decimals)
+		usdc3kHex  = "0xb2d05e00"
+		usdc2990   = "2990000000"
+		usdc2990Hx = "0xb237c780"
+		dai1k      = "1000000000000000000000"
+		dai1kHex   = "0x3635c9adc5dea00000"
+		hookData   = "0x"
+	)
+	v4Single := "(" + v4PoolKey + ",bool,uint128,uint128,bytes)"
+	v4Multi := "(address," + v4PathKey + "[],uint128,uint128)"
+	sig65 := "0x" + strings.Repeat("ab", 65)
+
+	tests := []struct {
+		name  string
+		to    string
+		value string
+		input string
+		want  map[string]any // Details paths → values (compared with fmt.Sprint)
+	}{
+		{
+			name:  "V2 exact-in ETH for USDC",
+			to:    urRouterV1_2,
+			value: oneETHHex,
+			input: urExecute(t, "0x0b08",
+				urInput(t, urCommands[0x0b].Sig, urAddressThis, oneETH),
+				urInput(t, urCommands[0x08].Sig, urMsgSender, oneETH, usdc2990, []any{wethAddress, tokenUSDC}, false)),
+			want: map[string]any{
+				"type":                      "dex_swap",
+				"command_count":             2,
+				"token_in":                  "ETH",
+				"token_out":                 tokenUSDC,
+				"amount_in":                 oneETHHex,
+				"amount_out_min":            usdc2990Hx,
+				"swap_type":                 "eth_to_token",
+				"deadline":                  1700000000,
+				"commands.0.command":        "WRAP_ETH",
+				"commands.0.command_byte":   "0x0b",
+				"commands.0.recipient_role": "router",
+				"commands.0.amount_min":     oneETHHex,
+				"commands.1.command":        "V2_SWAP_EXACT_IN",
+				"commands.1.recipient_role": "msg.sender",
+				"commands.1.path":           []string{wethAddress, tokenUSDC},
+				"commands.1.amount_in":      oneETHHex,
+				"commands.1.allow_revert":   nil,
+			},
+		},
+		{
+			name: "V3 exact-out USDC for ETH with permit and unwrap",
+			to:   urRouterV1_2,
+			input: urExecute(t, "0x0a010c",
+				urInput(t, urCommands[0x0a].Sig, []any{[]any{tokenUSDC, "1461501637330902918203684832716283019655932542975", "1702592000", "0"}, urRouterV1_2, "1700001800"}, sig65),
+				urInput(t, urCommands[0x01].Sig, urAddressThis, oneETH, usdc3k, v3Path([]string{wethAddress, tokenUSDC}, 500), true),
+				urInput(t, urCommands[0x0c].Sig, urMsgSender, oneETH)),
+			want: map[string]any{
+				"type":                      "dex_swap",
+				"command_count":             3,
+				"token_in":                  tokenUSDC,
+				"token_out":                 "ETH",
+				"amount_out":                oneETHHex,
+				"amount_in_max":             usdc3kHex,
+				"swap_count":                1,
+				"swap_type":                 nil,
+				"commands.0.command":        "PERMIT2_PERMIT",
+				"commands.0.token":          tokenUSDC,
+				"commands.0.spender":        urRouterV1_2,
+				"commands.0.expiration":     1702592000,
+				"commands.0.amount":         "0xffffffffffffffffffffffffffffffffffffffff",
+				"commands.1.command":        "V3_SWAP_EXACT_OUT",
+				"commands.1.path":           []string{tokenUSDC, wethAddress},
+				"commands.1.fees":           []int64{500},
+				"commands.1.amount_out":     oneETHHex,
+				"commands.1.amount_in_max":  usdc3kHex,
+				"commands.1.recipient_role": "router",
+				"commands.2.command":        "UNWRAP_WETH",
+				"commands.2.recipient_role": "msg.sender",
+				"commands.2.token_out":      "ETH",
+			},
+		},
+		{
+			name: "V3 exact-out multi-hop reverses the path",
+			to:   urRouterV1_2,
+			input: urExecute(t, "0x01",
+				urInput(t, urCommands[0x01].Sig, urMsgSender, dai1k, usdc3k, v3Path([]string{tokenDAI, wethAddress, tokenUSDC}, 3000, 500), true)),
+			want: map[string]any{
+				"token_in":           tokenUSDC,
+				"token_out":          tokenDAI,
+				"amount_out":         dai1kHex,
+				"commands.0.path":    []string{tokenUSDC, wethAddress, tokenDAI},
+				"commands.0.fees":    []int64{500, 3000},
+				"commands.0.command": "V3_SWAP_EXACT_OUT",
+			},
+		},
+		{
+			name:  "V4 single-hop exact-in ETH for USDC",
+			to:    urRouterV2,
+			value: oneETHHex,
+			input: urExecute(t, "0x10",
+				urInput(t, urCommandsV2[0x10].Sig, "0x060c0f", []any{
+					urInput(t, "f("+v4Single+")", []any{[]any{urNativeV4, tokenUSDC, "500", "10", urNativeV4}, true, oneETH, usdc2990, hookData}),
+					urInput(t, v4Actions[0x0c].Sig, urNativeV4, oneETH),
+					urInput(t, v4Actions[0x0f].Sig, tokenUSDC, usdc2990),
+				})),
+			want: map[string]any{
+				"type":                            "dex_swap",
+				"token_in":                        "ETH",
+				"token_out":                       tokenUSDC,
+				"amount_in":                       oneETHHex,
+				"amount_out_min":                  usdc2990Hx,
+				"swap_type":                       "eth_to_token",
+				"commands.0.command":              "V4_SWAP",
+				"commands.0.token_in":             "ETH",
+				"commands.0.token_out":            tokenUSDC,
+				"commands.0.actions.0.action":     "SWAP_EXACT_IN_SINGLE",
+				"commands.0.actions.0.fee":        500,
+				"commands.0.actions.0.hooks":      nil,
+				"commands.0.actions.0.token_in":   "ETH",
+				"commands.0.actions.0.amount_in":  oneETHHex,
+				"commands.0.actions.1.action":     "SETTLE_ALL",
+				"commands.0.actions.1.token":      "ETH",
+				"commands.0.actions.1.max_amount": oneETHHex,
+				"commands.0.actions.2.action":     "TAKE_ALL",
+				"commands.0.actions.2.token":      tokenUSDC,
+				"commands.0.actions.2.min_amount": usdc2990Hx,
+			},
+		},
+		{
+			name: "V4 multi-hop exact-in and exact-out",
+			to:   urRouterV2,
+			input: urExecute(t, "0x10",
+				urInput(t, urCommandsV2[0x10].Sig, "0x0709", []any{
+					urInput(t, "f("+v4Multi+")", []any{urNativeV4, []any{
+						[]any{tokenUSDC, "500", "10", urNativeV4, hookData},
+						[]any{tokenDAI, "100", "1", urNativeV4, hookData},
+					}, oneETH, dai1k}),
+					urInput(t, "f("+v4Multi+")", []any{tokenDAI, []any{
+						[]any{tokenUSDC, "100", "1", urNativeV4, hookData},
+						[]any{wethAddress, "3000", "60", urNativeV4, hookData},
+					}, dai1k, usdc3k}),
+				})),
+			want: map[string]any{
+				"token_in":                           "ETH",
+				"token_out":                          tokenDAI,
+				"amount_in":                          oneETHHex,
+				"amount_out":                         dai1kHex,
+				"swap_count":                         1,
+				"commands.0.actions.0.action":        "SWAP_EXACT_IN",
+				"commands.0.actions.0.path":          []string{"ETH", tokenUSDC, tokenDAI},
+				"commands.0.actions.0.amount_in":     oneETHHex,
+				"commands.0.actions.1.action":        "SWAP_EXACT_OUT",
+				"commands.0.actions.1.path":          []string{tokenUSDC, wethAddress, tokenDAI},
+				"commands.0.actions.1.amount_out":    dai1kHex,
+				"commands.0.actions.1.amount_in_max": usdc3kHex,
+			},
+		},
+		{
+			name:  "EXECUTE_SUB_PLAN with allow-revert",
+			to:    urRouterV2,
+			value: oneETHHex,
+			input: urExecute(t, "0xa1",
+				urInput(t, urCommands[0x21].Sig, "0x0b00", []any{
+					urInput(t, urCommands[0x0b].Sig, urAddressThis, urContractBalance.String()),
+					urInput(t, urCommands[0x00].Sig, urMsgSender, urContractBalance.String(), usdc2990, v3Path([]string{wethAddress, tokenUSDC}, 3000), false),
+				})),
+			want: map[string]any{
+				"token_in":                      "ETH",
+				"token_out":                     tokenUSDC,
+				"amount_out_min":                usdc2990Hx,
+				"commands.0.command":            "EXECUTE_SUB_PLAN",
+				"commands.0.allow_revert":       true,
+				"commands.0.sub_plan.0.command": "WRAP_ETH",
+				"commands.0.sub_plan.0.amount_min_is_contract_balance": true,
+				"commands.0.sub_plan.1.command":                        "V3_SWAP_EXACT_IN",
+				"commands.0.sub_plan.1.amount_in_is_contract_balance":  true,
+				"commands.0.sub_plan.1.fees":                           []int64{3000},
+			},
+		},
+		{
+			name:  "pre-V4 router reads 0x10 as an NFT command",
+			to:    urRouterV1_2,
+			input: urExecute(t, "0x10", "0x"),
+			want: map[string]any{
+				"type":               "universal_router",
+				"commands.0.command": "NFT_0x10",
+				"commands.0.kind":    "nft",
+			},
+		},
+		{
+			name:  "missing and malformed inputs",
+			to:    urRouterV2,
+			input: urExecute(t, "0x0805", "0x1234"),
+			want: map[string]any{
+				"type":                    "universal_router",
+				"commands.0.command":      "V2_SWAP_EXACT_IN",
+				"commands.0.decode_error": "abi decode failed: read past end of data at offset 0",
+				"commands.1.command":      "TRANSFER",
+				"commands.1.decode_error": "missing input",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to := tt.to
+			d := DecodeTransactionInput(tt.input, &to, firstNonEmpty(tt.value, "0x0"), nil)
+			if d == nil || d.ActionType == "contract_call" {
+				t.Fatalf("not decoded as a Universal Router call: %+v", d)
+			}
+			for path, want := range tt.want {
+				if got := detailAt(d.Details, path); fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("%s = %v, want %v", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUniversalRouterSubPlanDepth(t *testing.T) {
+	// Each level wraps the next in EXECUTE_SUB_PLAN; the innermost is a TRANSFER.
+	inner := urInput(t, urCommands[0x05].Sig, tokenUSDC, urMsgSender, "1")
+	commands := "0x05"
+	for i := 0; i < urMaxPlanDepth+1; i++ {
+		inner = urInput(t, urCommands[0x21].Sig, commands, []any{inner})
+		commands = "0x21"
+	}
+	to := urRouterV2
+	d := DecodeTransactionInput(urExecute(t, commands, inner), &to, "0x0", nil)
+	path := "commands.0"
+	for i := 0; i < urMaxPlanDepth-1; i++ {
+		if got := detailAt(d.Details, path+".command"); got != "EXECUTE_SUB_PLAN" {
+			t.Fatalf("%s.command = %v", path, got)
+		}
+		path += ".sub_plan.0"
+	}
+	if got := detailAt(d.Details, path+".decode_error"); got != "sub-plan nesting too deep" {
+		t.Errorf("%s.decode_error = %v, want the nesting limit", path, got)
+	}
+}
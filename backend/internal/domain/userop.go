@@ -0,0 +1,220 @@
+// Package domain: this file decodes ERC-4337 handleOps bundles. Each
+// UserOperation (v0.6 struct or v0.7 PackedUserOperation) is unpacked into its
+// sender, nonce, paymaster and gas fields, its callData is decoded as a call
+// into the account, and with a receipt each op is matched to its
+// UserOperationEvent (by sender and nonce) for success and actual gas cost.
+package domain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	userOpEventTopic  = keccakTopic("UserOperationEvent(bytes32,address,address,uint256,bool,uint256,uint256)")
+	userOpRevertTopic = keccakTopic("UserOperationRevertReason(bytes32,address,uint256,bytes)")
+)
+
+// UserOperation is one op of a handleOps bundle. Gas and fee fields are 0x-hex.
+// The receipt fields are empty when the tx isn't mined or no event matched.
+type UserOperation struct {
+	Index                         int        `json:"index"`
+	Sender                        string     `json:"sender"`
+	Nonce                         string     `json:"nonce"`
+	Factory                       string     `json:"factory,omitempty"` // from initCode: the op deploys its account
+	Paymaster                     string     `json:"paymaster,omitempty"`
+	CallGasLimit                  string     `json:"call_gas_limit"`
+	VerificationGasLimit          string     `json:"verification_gas_limit"`
+	PreVerificationGas            string     `json:"pre_verification_gas"`
+	MaxFeePerGas                  string     `json:"max_fee_per_gas"`
+	MaxPriorityFeePerGas          string     `json:"max_priority_fee_per_gas"`
+	PaymasterVerificationGasLimit string     `json:"paymaster_verification_gas_limit,omitempty"` // v0.7
+	PaymasterPostOpGasLimit       string     `json:"paymaster_post_op_gas_limit,omitempty"`      // v0.7
+	Call                          *InnerCall `json:"call,omitempty"`                             // callData, as a call into the account
+	UserOpHash                    string     `json:"user_op_hash,omitempty"`
+	Success                       *bool      `json:"success,omitempty"`
+	ActualGasCost                 string     `json:"actual_gas_cost,omitempty"`
+	ActualGasUsed                 string     `json:"actual_gas_used,omitempty"`
+	RevertReason                  string     `json:"revert_reason,omitempty"`
+}
+
+// decodeHandleOps fills decoded.UserOperations from handleOps(ops, beneficiary);
+// v0.7 is told apart by its packed bytes32 gas fields.
+func decodeHandleOps(decoded *DecodedTx, call *DecodedCall, to string, receipt json.RawMessage, depth int, b *decodeBudget) {
+	decoded.Action = "Handle Operations"
+	decoded.Details["type"] = "handle_ops"
+	decoded.Details["description"] = "Process bundled user operations (ERC-4337 Account Abstraction)"
+	packed := strings.Contains(decoded.MethodName, "bytes32")
+	decoded.Details["entry_point_version"] = "v0.6"
+	if packed {
+		decoded.Details["entry_point_version"] = "v0.7"
+	}
+	if beneficiary, ok := call.argString("beneficiary"); ok {
+		decoded.Details["beneficiary"] = beneficiary
+	}
+	ops := call.arg("ops")
+	if ops == nil {
+		return
+	}
+	seen := payloadSet{}
+	for i, op := range abiList(*ops) {
+		f := abiList(op)
+		var u UserOperation
+		if packed && len(f) == 9 {
+			u = unpackUserOpV07(f)
+		} else if !packed && len(f) == 11 {
+			u = unpackUserOpV06(f)
+		} else {
+			continue
+		}
+		u.Index = i
+		if initCode := abiStr(f[2]); len(initCode) >= 42 {
+			u.Factory = initCode[:42]
+		}
+		if of, ok := seen.alias(f[3], i); ok {
+			u.Call = &InnerCall{Target: strings.ToLower(u.Sender), AliasOf: &of}
+		} else {
+			ic := innerCall(0, u.Sender, abiStr(f[3]), nil, depth, b)
+			u.Call = &ic
+		}
+		decoded.UserOperations = append(decoded.UserOperations, u)
+	}
+	decoded.Details["op_count"] = len(decoded.UserOperations)
+	if receipt != nil {
+		matchUserOpEvents(decoded, receipt, to)
+	}
+
+	parts := make([]string, len(decoded.UserOperations))
+	for i := range decoded.UserOperations {
+		u := &decoded.UserOperations[i]
+		parts[i] = fmt.Sprintf("%s by %s", innerAction(u.Call), firstNonEmpty(contractName(u.Sender), shortenHash(u.Sender)))
+		if u.Success != nil && !*u.Success {
+			parts[i] += " (failed)"
+		}
+	}
+	decoded.Details["description"] = fmt.Sprintf("ERC-4337 bundle of %d user operation(s): %s", len(parts), strings.Join(parts, "; "))
+}
+
+// unpackUserOpV06 reads the v0.6 UserOperation struct: sender, nonce, initCode,
+// callData, callGasLimit, verificationGasLimit, preVerificationGas,
+// maxFeePerGas, maxPriorityFeePerGas, paymasterAndData, signature.
+func unpackUserOpV06(f []ABIValue) UserOperation {
+	u := UserOperation{
+		Sender:               abiStr(f[0]),
+		Nonce:                abiHex(f[1]),
+		CallGasLimit:         abiHex(f[4]),
+		VerificationGasLimit: abiHex(f[5]),
+		PreVerificationGas:   abiHex(f[6]),
+		MaxFeePerGas:         abiHex(f[7]),
+		MaxPriorityFeePerGas: abiHex(f[8]),
+	}
+	if pm := abiStr(f[9]); len(pm) >= 42 {
+		u.Paymaster = pm[:42]
+	}
+	return u
+}
+
+// unpackUserOpV07 reads the v0.7 PackedUserOperation: sender, nonce, initCode,
+// callData, accountGasLimits (verificationGasLimit | callGasLimit),
+// preVerificationGas, gasFees (maxPriorityFeePerGas | maxFeePerGas),
+// paymasterAndData (paymaster | verification gas | postOp gas | data), signature.
+func unpackUserOpV07(f []ABIValue) UserOperation {
+	u := UserOperation{Sender: abiStr(f[0]), Nonce: abiHex(f[1]), PreVerificationGas: abiHex(f[5])}
+	u.VerificationGasLimit, u.CallGasLimit = splitUint128s(abiStr(f[4]))
+	u.MaxPriorityFeePerGas, u.MaxFeePerGas = splitUint128s(abiStr(f[6]))
+	if pm := abiStr(f[7]); len(pm) >= 42 {
+		u.Paymaster = pm[:42]
+		if len(pm) >= 42+64 {
+			u.PaymasterVerificationGasLimit, u.PaymasterPostOpGasLimit = splitUint128s("0x" + pm[42:42+64])
+		}
+	}
+	return u
+}
+
+// matchUserOpEvents copies each op's outcome from the EntryPoint's
+// UserOperationEvent / UserOperationRevertReason logs in receipt.
+func matchUserOpEvents(decoded *DecodedTx, receipt json.RawMessage, entryPoint string) {
+	var rec struct {
+		Logs []struct {
+			Address string   `json:"address"`
+			Topics  []string `json:"topics"`
+			Data    string   `json:"data"`
+		} `json:"logs"`
+	}
+	if json.Unmarshal(receipt, &rec) != nil {
+		return
+	}
+	byOp := map[string]*UserOperation{}
+	for i := range decoded.UserOperations {
+		u := &decoded.UserOperations[i]
+		byOp[strings.ToLower(u.Sender)+"/"+u.Nonce] = u
+	}
+	matched, failed := 0, 0
+	total := new(big.Int)
+	for _, l := range rec.Logs {
+		if len(l.Topics) < 3 || (entryPoint != "" && !strings.EqualFold(l.Address, entryPoint)) {
+			continue
+		}
+		topic := strings.ToLower(l.Topics[0])
+		if topic != userOpEventTopic && topic != userOpRevertTopic {
+			continue
+		}
+		data, err := hex.DecodeString(strings.TrimPrefix(l.Data, "0x"))
+		if err != nil || len(data) < 32 || len(l.Topics[2]) < 40 {
+			continue
+		}
+		sender := "0x" + strings.ToLower(l.Topics[2][len(l.Topics[2])-40:])
+		u := byOp[sender+"/"+hexAmount(new(big.Int).SetBytes(data[:32]))]
+		if u == nil {
+			continue
+		}
+		if topic == userOpRevertTopic {
+			if vals, err := decodeABIArgs("UserOperationRevertReason(uint256,bytes)", data, nil); err == nil {
+				info := &RevertInfo{}
+				decodeRevertData(info, abiStr(vals[1]), u.Sender)
+				u.RevertReason = info.Reason
+			}
+			continue
+		}
+		// data: nonce, success, actualGasCost, actualGasUsed
+		if len(data) < 128 {
+			continue
+		}
+		u.UserOpHash = strings.ToLower(l.Topics[1])
+		ok := new(big.Int).SetBytes(data[32:64]).Sign() != 0
+		u.Success = &ok
+		cost := new(big.Int).SetBytes(data[64:96])
+		u.ActualGasCost = hexAmount(cost)
+		u.ActualGasUsed = hexAmount(new(big.Int).SetBytes(data[96:128]))
+		total.Add(total, cost)
+		matched++
+		if !ok {
+			failed++
+		}
+	}
+	if matched > 0 {
+		decoded.Details["failed_op_count"] = failed
+		decoded.Details["total_actual_gas_cost"] = hexAmount(total)
+	}
+}
+
+// splitUint128s splits a bytes32 into its high and low uint128 halves as 0x-hex.
+func splitUint128s(word string) (string, string) {
+	b, err := hex.DecodeString(strings.TrimPrefix(word, "0x"))
+	if err != nil || len(b) != 32 {
+		return "", ""
+	}
+	return hexAmount(new(big.Int).SetBytes(b[:16])), hexAmount(new(big.Int).SetBytes(b[16:]))
+}
+
+// abiHex renders a decoded integer (decimal string) as 0x-hex.
+func abiHex(v ABIValue) string {
+	n, ok := new(big.Int).SetString(abiStr(v), 10)
+	if !ok {
+		return ""
+	}
+	return hexAmount(n)
+}
@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const (
+	entryPointV06 = "0x5ff137d4b0fdcd49dca30c7cf57e578a026d2789"
+	entryPointV07 = "0x0000000071727de22e5e9d8baf0edac6f37da032"
+	opSenderA     = "0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	opSenderB     = "0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	opFactory     = "0xfacf000000000000000000000000000000000001"
+	opPaymaster   = "0x9a70000000000000000000000000000000000002"
+	opBeneficiary = "0xbe00000000000000000000000000000000000003"
+
+	handleOpsV06 = "handleOps((address,uint256,bytes,bytes,uint256,uint256,uint256,uint256,uint256,bytes,bytes)[],address)"
+	handleOpsV07 = "handleOps((address,uint256,bytes,bytes,bytes32,uint256,bytes32,bytes,bytes)[],address)"
+)
+
+// opTopic left-pads an address to a 32-byte log topic.
+func opTopic(addr string) string {
+	return "0x" + strings.Repeat("0", 24) + strings.TrimPrefix(addr, "0x")
+}
+
+// opLog is one receipt log; data is ABI-encoded from types and vals.
+func opLog(t testing.TB, address string, topics []string, types []string, vals ...any) map[string]any {
+	t.Helper()
+	return map[string]any{"address": address, "topics": topics, "data": "0x" + hex.EncodeToString(abiEncode(t, types, vals...))}
+}
+
+func opReceipt(t testing.TB, logs ...map[string]any) json.RawMessage {
+	t.Helper()
+	raw, err := json.Marshal(map[string]any{"logs": logs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestDecodeHandleOpsV06(t *testing.T) {
+	approve := calldata(t, "approve(address,uint256)", opBeneficiary, "1000")
+	ops := []any{
+		[]any{opSenderA, "5", opFactory + "deadbeef", approve, "100000", "200000", "50000", "30000000000", "1500000000", opPaymaster + "cafe", "0x01"},
+		[]any{opSenderB, "0", "0x", approve[:10], "1", "2", "3", "4", "5", "0x", "0x"},
+	}
+	to := entryPointV06
+	d := DecodeTransactionInput(calldata(t, handleOpsV06, ops, opBeneficiary), &to, "0x0", nil)
+	if d == nil || d.Details["type"] != "handle_ops" {
+		t.Fatalf("not decoded as handleOps: %+v", d)
+	}
+	if d.Details["entry_point_version"] != "v0.6" || d.Details["beneficiary"] != opBeneficiary || d.Details["op_count"] != 2 {
+		t.Errorf("details: %v", d.Details)
+	}
+	if len(d.UserOperations) != 2 {
+		t.Fatalf("want 2 ops, got %d", len(d.UserOperations))
+	}
+	a := d.UserOperations[0]
+	want := UserOperation{
+		Index: 0, Sender: opSenderA, Nonce: "0x5", Factory: opFactory, Paymaster: opPaymaster,
+		CallGasLimit: "0x186a0", VerificationGasLimit: "0x30d40", PreVerificationGas: "0xc350",
+		MaxFeePerGas: "0x6fc23ac00", MaxPriorityFeePerGas: "0x59682f00",
+	}
+	got := a
+	got.Call = nil
+	if got != want {
+		t.Errorf("op 0:\n got %+v\nwant %+v", got, want)
+	}
+	if a.Call == nil || a.Call.Target != opSenderA || a.Call.Selector != "0x095ea7b3" || a.Call.Decoded == nil || a.Call.Decoded.ActionType != "approve" {
+		t.Errorf("op 0 callData not decoded as approve: %+v", a.Call)
+	}
+	b := d.UserOperations[1]
+	if b.Index != 1 || b.Factory != "" || b.Paymaster != "" || b.MaxPriorityFeePerGas != "0x5" {
+		t.Errorf("op 1: %+v", b)
+	}
+	if desc, _ := d.Details["description"].(string); !strings.Contains(desc, "2 user operation(s)") {
+		t.Errorf("description: %v", d.Details["description"])
+	}
+}
+
+func TestDecodeHandleOpsV07(t *testing.T) {
+	keyedNonce := "18446744073709551617" // key 1, sequence 1
+	accountGasLimits := "0x" + strings.Repeat("0", 27) + "30d40" + strings.Repeat("0", 27) + "186a0"
+	gasFees := "0x" + strings.Repeat("0", 24) + "59682f00" + strings.Repeat("0", 23) + "6fc23ac00"
+	paymasterAndData := opPaymaster + strings.Repeat("0", 28) + "ea60" + strings.Repeat("0", 28) + "7530" + "cafe"
+	ops := []any{
+		[]any{opSenderA, keyedNonce, "0x", calldata(t, "approve(address,uint256)", opBeneficiary, "1"), accountGasLimits, "50000", gasFees, paymasterAndData, "0x01"},
+	}
+	to := entryPointV07
+	d := DecodeTransactionInput(calldata(t, handleOpsV07, ops, opBeneficiary), &to, "0x0", nil)
+	if d == nil || d.Details["entry_point_version"] != "v0.7" || len(d.UserOperations) != 1 {
+		t.Fatalf("not decoded as a v0.7 bundle: %+v", d)
+	}
+	got := d.UserOperations[0]
+	if got.Call == nil || got.Call.Decoded == nil || got.Call.Decoded.ActionType != "approve" {
+		t.Errorf("callData not decoded as approve: %+v", got.Call)
+	}
+	got.Call = nil
+	want := UserOperation{
+		Sender: opSenderA, Nonce: "0x10000000000000001", Paymaster: opPaymaster,
+		CallGasLimit: "0x186a0", VerificationGasLimit: "0x30d40", PreVerificationGas: "0xc350",
+		MaxFeePerGas: "0x6fc23ac00", MaxPriorityFeePerGas: "0x59682f00",
+		PaymasterVerificationGasLimit: "0xea60", PaymasterPostOpGasLimit: "0x7530",
+	}
+	if got != want {
+		t.Errorf("op:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestHandleOpsEvents(t *testing.T) {
+	const (
+		hashA = "0x1111111111111111111111111111111111111111111111111111111111111111"
+		hashB = "0x2222222222222222222222222222222222222222222222222222222222222222"
+	)
+	eventTypes := []string{"uint256", "bool", "uint256", "uint256"}
+	revert := "0x08c379a0" + hex.EncodeToString(abiEncode(t, []string{"string"}, "insufficient balance"))
+	approve := calldata(t, "approve(address,uint256)", opBeneficiary, "1")
+	ops := []any{
+		[]any{opSenderA, "5", "0x", approve, "1", "1", "1", "1", "1", "0x", "0x"},
+		[]any{opSenderB, "7", "0x", approve, "1", "1", "1", "1", "1", "0x", "0x"},
+	}
+	input := calldata(t, handleOpsV06, ops, opBeneficiary)
+	to := entryPointV06
+
+	t.Run("success and revert", func(t *testing.T) {
+		receipt := opReceipt(t,
+			opLog(t, entryPointV06, []string{userOpEventTopic, hashA, opTopic(opSenderA), opTopic(opPaymaster)}, eventTypes, "5", true, "1000", "21000"),
+			opLog(t, entryPointV06, []string{userOpRevertTopic, hashB, opTopic(opSenderB)}, []string{"uint256", "bytes"}, "7", revert),
+			opLog(t, entryPointV06, []string{userOpEventTopic, hashB, opTopic(opSenderB), opTopic(opPaymaster)}, eventTypes, "7", false, "500", "9000"),
+		)
+		d := DecodeTransactionInput(input, &to, "0x0", receipt)
+		a, b := d.UserOperations[0], d.UserOperations[1]
+		if a.UserOpHash != hashA || a.Success == nil || !*a.Success || a.ActualGasCost != "0x3e8" || a.ActualGasUsed != "0x5208" || a.RevertReason != "" {
+			t.Errorf("op 0: %+v", a)
+		}
+		if b.UserOpHash != hashB || b.Success == nil || *b.Success || b.ActualGasCost != "0x1f4" || b.RevertReason != "insufficient balance" {
+			t.Errorf("op 1: %+v", b)
+		}
+		if d.Details["failed_op_count"] != 1 || d.Details["total_actual_gas_cost"] != "0x5dc" {
+			t.Errorf("details: %v", d.Details)
+		}
+		if desc, _ := d.Details["description"].(string); !strings.Contains(desc, "(failed)") {
+			t.Errorf("description doesn't flag the failed op: %q", desc)
+		}
+	})
+
+	t.Run("unmatched logs", func(t *testing.T) {
+		receipt := opReceipt(t,
+			// Right op, wrong contract: another EntryPoint's event.
+			opLog(t, entryPointV07, []string{userOpEventTopic, hashA, opTopic(opSenderA), opTopic(opPaymaster)}, eventTypes, "5", true, "1000", "21000"),
+			// Right contract, wrong nonce.
+			opLog(t, entryPointV06, []string{userOpEventTopic, hashB, opTopic(opSenderB), opTopic(opPaymaster)}, eventTypes, "8", true, "1000", "21000"),
+		)
+		d := DecodeTransactionInput(input, &to, "0x0", receipt)
+		for _, u := range d.UserOperations {
+			if u.Success != nil || u.UserOpHash != "" || u.ActualGasCost != "" {
+				t.Errorf("op %d matched a foreign log: %+v", u.Index, u)
+			}
+		}
+		if _, ok := d.Details["failed_op_count"]; ok {
+			t.Errorf("failed_op_count set without matches: %v", d.Details)
+		}
+	})
+}
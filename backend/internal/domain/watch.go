@@ -0,0 +1,287 @@
+// Package domain: this file follows one transaction through its lifecycle for
+// /api/track/tx/{hash}/watch. It re-checks on every new head (RPC) and every
+// mempool/finality event (local state only) and emits an event per transition.
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/you/eth-tx-lifecycle-backend/config"
+	"github.com/you/eth-tx-lifecycle-backend/internal/clients/eth"
+)
+
+// Watch event types, in the order a healthy tx emits them. Dropped is terminal
+// like finalized; replaced is followed by included once the original or its
+// replacement is mined (events then carry replaced_by if it was the
+// replacement); reorged_out is followed by pending or included.
+const (
+	WatchPending    = "pending"
+	WatchIncluded   = "included"
+	WatchJustified  = "justified"
+	WatchFinalized  = "finalized"
+	WatchReplaced   = "replaced"
+	WatchDropped    = "dropped"
+	WatchReorgedOut = "reorged_out"
+)
+
+// TxWatchEvent is one lifecycle transition for a watched hash.
+type TxWatchEvent struct {
+	Type            string `json:"type"`
+	Hash            string `json:"hash"`
+	Time            int64  `json:"time"`
+	BlockNumber     uint64 `json:"block_number,omitempty"`
+	BlockHash       string `json:"block_hash,omitempty"`
+	Slot            uint64 `json:"slot,omitempty"`
+	ReplacedBy      string `json:"replaced_by,omitempty"`
+	ReplacementType string `json:"replacement_type,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+}
+
+// watchFallbackPoll re-checks via RPC when no heads arrive (e.g. MEMPOOL_DISABLE).
+const watchFallbackPoll = 30 * time.Second
+
+// watchMaxReplacements bounds how far a chain of replacements is followed.
+const watchMaxReplacements = 8
+
+type txWatcher struct {
+	ctx     context.Context
+	out     chan<- TxWatchEvent
+	hash    string
+	started int64
+	last    string
+	// nodePending is the node's answer at the last RPC check: it knows the tx
+	// and it isn't mined yet.
+	nodePending bool
+	// replacedBy is the latest replacement of hash seen in the mempool history;
+	// once set, either hash or replacedBy may be the tx that gets included.
+	replacedBy string
+
+	includedHash string // hash or replacedBy, whichever blockHash holds
+	blockHash    string
+	blockNumber  uint64
+	slot         uint64
+	slotKnown    bool
+	justified    bool
+}
+
+// WatchTx streams lifecycle events for hash until it (or its replacement) is
+// finalized, it is dropped, or ctx is cancelled; the channel is closed when
+// watching stops.
+func WatchTx(ctx context.Context, hash string) <-chan TxWatchEvent {
+	out := make(chan TxWatchEvent, 16)
+	go func() {
+		defer close(out)
+		events, cancel := SubscribeEvents([]string{TopicHeads, TopicFinality, TopicMempool}, 16)
+		defer cancel()
+		w := &txWatcher{ctx: ctx, out: out, hash: strings.ToLower(hash), started: time.Now().Unix()}
+		if w.check(true) {
+			return
+		}
+		ticker := time.NewTicker(watchFallbackPoll)
+		defer ticker.Stop()
+		for {
+			var done bool
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-events:
+				done = w.check(ev.Topic == TopicHeads)
+			case <-ticker.C:
+				done = w.check(true)
+			}
+			if done {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// emit sends ev unless the client went away; it returns false in that case.
+func (w *txWatcher) emit(ev TxWatchEvent) bool {
+	ev.Hash = w.hash
+	ev.Time = time.Now().Unix()
+	w.last = ev.Type
+	select {
+	case w.out <- ev:
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
+
+// lookup asks the node about the tx (see lookupTxState). After a replacement,
+// a mined replacement counts as the tx's inclusion unless the original is mined.
+func (w *txWatcher) lookup() (includedHash string, found bool, blockHash string, blockNumber uint64, ok bool) {
+	found, blockHash, blockNumber, ok = lookupTxState(w.hash)
+	if !ok || blockHash != "" || w.replacedBy == "" {
+		return w.hash, found, blockHash, blockNumber, ok
+	}
+	_, rBlockHash, rBlockNumber, rOK := lookupTxState(w.replacedBy)
+	if !rOK {
+		return w.hash, found, "", 0, false
+	}
+	if rBlockHash != "" {
+		return w.replacedBy, found, rBlockHash, rBlockNumber, true
+	}
+	return w.hash, found, "", 0, true
+}
+
+// lookupTxState asks the node about hash. found is false if the node doesn't
+// know it; blockHash is empty while it is pending; ok is false on RPC errors.
+func lookupTxState(hash string) (found bool, blockHash string, blockNumber uint64, ok bool) {
+	raw, err := eth.Call("eth_getTransactionByHash", []any{hash})
+	if err != nil {
+		return false, "", 0, false
+	}
+	if string(raw) == "null" {
+		return false, "", 0, true
+	}
+	var tx struct {
+		BlockHash   *string `json:"blockHash"`
+		BlockNumber *string `json:"blockNumber"`
+	}
+	if json.Unmarshal(raw, &tx) != nil {
+		return false, "", 0, false
+	}
+	if tx.BlockHash == nil || tx.BlockNumber == nil {
+		return true, "", 0, true
+	}
+	n, _ := config.ParseHexUint64(*tx.BlockNumber)
+	return true, strings.ToLower(*tx.BlockHash), n, true
+}
+
+// blockSlot resolves the slot of blockHash from its timestamp, checked against
+// the beacon block's execution payload (see blockSlotFor).
+func blockSlot(blockHash string) (uint64, bool) {
+	raw, err := eth.Call("eth_getBlockByHash", []any{blockHash, false})
+	if err != nil || string(raw) == "null" {
+		return 0, false
+	}
+	var b struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if json.Unmarshal(raw, &b) != nil {
+		return 0, false
+	}
+	ts, err := config.ParseHexUint64(b.Timestamp)
+	if err != nil {
+		return 0, false
+	}
+	bs, ok := blockSlotFor(blockHash, ts)
+	return bs.Slot, ok
+}
+
+// check re-evaluates the tx (asking the node when rpc is set) and emits any
+// transitions. It returns true once a terminal event was sent or the client left.
+func (w *txWatcher) check(rpc bool) bool {
+	if rpc {
+		includedHash, found, blockHash, blockNumber, ok := w.lookup()
+		if ok {
+			if w.blockHash != "" && (blockHash != w.blockHash || includedHash != w.includedHash) {
+				if !w.emit(w.blockEvent(WatchReorgedOut)) {
+					return true
+				}
+				w.includedHash, w.blockHash, w.blockNumber, w.slotKnown, w.justified = "", "", 0, false, false
+			}
+			if blockHash != "" && w.blockHash == "" {
+				w.includedHash, w.blockHash, w.blockNumber = includedHash, blockHash, blockNumber
+				w.slot, w.slotKnown = blockSlot(blockHash)
+				if !w.emit(w.blockEvent(WatchIncluded)) {
+					return true
+				}
+			}
+			w.nodePending = found && blockHash == ""
+		}
+	}
+	if w.blockHash == "" {
+		return w.checkMempool()
+	}
+	if !w.slotKnown {
+		w.slot, w.slotKnown = blockSlot(w.blockHash)
+		if !w.slotKnown {
+			return false
+		}
+	}
+	cp := currentCheckpoints()
+	if cp == nil {
+		return false
+	}
+	if !w.justified && checkpointCovers(cp.CurrentJustified, w.slot) {
+		w.justified = true
+		if !w.emit(w.blockEvent(WatchJustified)) {
+			return true
+		}
+	}
+	if checkpointCovers(cp.Finalized, w.slot) {
+		w.emit(w.blockEvent(WatchFinalized))
+		return true
+	}
+	return false
+}
+
+// blockEvent is an event of type typ about the block holding the tx, naming
+// the replacement when that is what was included.
+func (w *txWatcher) blockEvent(typ string) TxWatchEvent {
+	ev := TxWatchEvent{Type: typ, BlockNumber: w.blockNumber, BlockHash: w.blockHash, Slot: w.slot}
+	if w.includedHash != w.hash {
+		ev.ReplacedBy = w.includedHash
+	}
+	return ev
+}
+
+// checkMempool handles the not-included case from the mempool history. The
+// node still holding the original as pending outranks the history's view, as
+// a replaced tx can still be mined (e.g. by a builder that had it privately).
+func (w *txWatcher) checkMempool() bool {
+	rec, seen := LookupMempoolTx(w.hash)
+	switch {
+	case w.nodePending:
+		if w.last != WatchPending {
+			return !w.emit(TxWatchEvent{Type: WatchPending})
+		}
+	case seen && rec.Status == TxStatusReplaced:
+		return w.checkReplacement(rec)
+	case seen && rec.Status == TxStatusPending:
+		if w.last != WatchPending {
+			return !w.emit(TxWatchEvent{Type: WatchPending})
+		}
+	case seen && rec.Status == TxStatusDropped:
+		w.emit(TxWatchEvent{Type: WatchDropped, Reason: "not seen in the mempool for " + history.dropAfter.String()})
+		return true
+	case !seen && time.Now().Unix()-w.started > int64(history.dropAfter.Seconds()):
+		w.emit(TxWatchEvent{Type: WatchDropped, Reason: "never seen pending or included"})
+		return true
+	}
+	return false
+}
+
+// checkReplacement follows rec's chain of replacements to the latest one and
+// emits replaced when it changes. Watching goes on until the original or the
+// replacement is included, or the replacement is dropped.
+func (w *txWatcher) checkReplacement(rec MempoolTxRecord) bool {
+	latest, replacementType, status := w.hash, "", TxStatusReplaced
+	for i := 0; i < watchMaxReplacements && status == TxStatusReplaced && rec.ReplacedBy != ""; i++ {
+		latest, replacementType, status = rec.ReplacedBy, rec.ReplacementType, TxStatusPending
+		if next, ok := LookupMempoolTx(latest); ok {
+			rec, status = next, next.Status
+		}
+	}
+	if latest == w.hash {
+		return false
+	}
+	if latest != w.replacedBy {
+		w.replacedBy = latest
+		if !w.emit(TxWatchEvent{Type: WatchReplaced, ReplacedBy: latest, ReplacementType: replacementType}) {
+			return true
+		}
+	}
+	if status == TxStatusDropped {
+		w.emit(TxWatchEvent{Type: WatchDropped, ReplacedBy: latest, Reason: "replacement not seen in the mempool for " + history.dropAfter.String()})
+		return true
+	}
+	return false
+}
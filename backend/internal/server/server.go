@@ -7,10 +7,14 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math/big"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -67,8 +71,295 @@ func parseLimit(r *http.Request, defaultLimit int) int {
 	return limit
 }
 
-func handleMempool(w http.ResponseWriter, _ *http.Request) {
-	writeOK(w, domain.GetData())
+// maxDecimalDigits bounds the integer part of an amount (2^256 has 78 digits),
+// so a query string can't make us build a huge integer.
+const maxDecimalDigits = 78
+
+// parseDecimalUnits turns a plain decimal amount ("10", "1.5", ".25") into base
+// units, e.g. ETH to wei with decimals=18; digits below one base unit are
+// truncated. Exponents, signs, Inf/NaN and over-long amounts return ok=false.
+func parseDecimalUnits(s string, decimals int) (*big.Int, bool) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole+frac == "" || len(whole) > maxDecimalDigits || !isDigits(whole) || !isDigits(frac) {
+		return nil, false
+	}
+	if len(frac) > decimals {
+		frac = frac[:decimals]
+	}
+	n, ok := new(big.Int).SetString(whole+frac+strings.Repeat("0", decimals-len(frac)), 10)
+	return n, ok
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// handleMempool returns the raw snapshot when called without parameters, and a
+// filtered page (see domain.QueryMempool) when any filter, sort or cursor is given:
+// ?from=&to=&method=&action=&type=&min_value=(ETH)&min_tip=(gwei)&sort=&order=&cursor=&limit=&input=1
+func handleMempool(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	if len(qs) == 0 {
+		writeOK(w, domain.GetData())
+		return
+	}
+	q := domain.MempoolQuery{
+		From:         qs.Get("from"),
+		To:           qs.Get("to"),
+		Method:       qs.Get("method"),
+		Action:       qs.Get("action"),
+		Type:         qs.Get("type"),
+		Sort:         qs.Get("sort"),
+		Desc:         !strings.EqualFold(qs.Get("order"), "asc"),
+		Cursor:       qs.Get("cursor"),
+		Limit:        parseLimit(r, 50),
+		IncludeInput: qs.Get("input") == "1" || strings.EqualFold(qs.Get("input"), "true"),
+	}
+	if s := qs.Get("min_value"); s != "" {
+		v, ok := parseDecimalUnits(s, 18)
+		if !ok {
+			writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid min_value", "min_value is an amount in ETH, e.g. ?min_value=10")
+			return
+		}
+		q.MinValueWei = v
+	}
+	if s := qs.Get("min_tip"); s != "" {
+		v, ok := parseDecimalUnits(s, 9)
+		if !ok {
+			writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid min_tip", "min_tip is a priority fee in gwei, e.g. ?min_tip=2")
+			return
+		}
+		q.MinTipWei = v
+	}
+	page, err := domain.QueryMempool(q)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", err.Error(), "sort is one of seen, tip, value, fee, gas; type is 0x0-0x4 or legacy, access_list, dynamic_fee, blob, set_code; cursor comes from nextCursor")
+		return
+	}
+	writeOK(w, page)
+}
+
+func handleMempoolHistory(w http.ResponseWriter, r *http.Request) {
+	status := strings.ToLower(r.URL.Query().Get("status"))
+	if hash := r.URL.Query().Get("hash"); hash != "" {
+		rec, ok := domain.LookupMempoolTx(hash)
+		if !ok {
+			writeErr(w, http.StatusNotFound, "TX_NOT_SEEN", "Transaction not seen by the mempool monitor", "History only covers transactions observed since the backend started, within the retention window")
+			return
+		}
+		writeOK(w, rec)
+		return
+	}
+	hist, err := domain.GetHistory(status, parseLimit(r, 50))
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", err.Error(), "status must be one of pending, included, replaced, dropped")
+		return
+	}
+	writeOK(w, hist)
+}
+
+func handleTxPool(w http.ResponseWriter, r *http.Request) {
+	if addr := r.URL.Query().Get("address"); addr != "" {
+		snd, err := domain.GetTxPoolSender(addr)
+		if err != nil {
+			writeErr(w, http.StatusNotImplemented, "TXPOOL_UNSUPPORTED", "RPC does not expose txpool_content or txpool_inspect", "Use a node with the txpool namespace enabled (Geth, Erigon, Reth, Nethermind)")
+			return
+		}
+		writeOK(w, snd)
+		return
+	}
+	view, err := domain.GetTxPool(parseLimit(r, 20))
+	if err != nil {
+		writeErr(w, http.StatusNotImplemented, "TXPOOL_UNSUPPORTED", "RPC does not expose txpool_content or txpool_inspect", "Use a node with the txpool namespace enabled (Geth, Erigon, Reth, Nethermind)")
+		return
+	}
+	writeOK(w, view)
+}
+
+func handleFeeEstimate(w http.ResponseWriter, r *http.Request) {
+	tip := -1.0
+	if s := r.URL.Query().Get("tip"); s != "" {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f < 0 {
+			writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid tip", "tip is a priority fee in gwei, e.g. ?tip=1.5")
+			return
+		}
+		tip = f
+	}
+	est, err := domain.EstimateFees(tip)
+	if err != nil {
+		writeErr(w, http.StatusBadGateway, "FEE_HISTORY", "Failed to fetch fee history", "The RPC provider must support eth_feeHistory")
+		return
+	}
+	writeOK(w, est)
+}
+
+func handlePrivateFlow(w http.ResponseWriter, r *http.Request) {
+	if s := r.URL.Query().Get("block"); s != "" {
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid block", "block is a decimal block number, e.g. ?block=19000000")
+			return
+		}
+		bf, err := domain.GetPrivateFlowBlock(n)
+		if err != nil {
+			writeErr(w, http.StatusNotFound, "BLOCK_NOT_CLASSIFIED", err.Error(), "Only blocks seen by the head follower since startup are classified")
+			return
+		}
+		writeOK(w, bf)
+		return
+	}
+	writeOK(w, domain.GetPrivateFlow(parseLimit(r, 20)))
+}
+
+func handleLatencyStats(w http.ResponseWriter, r *http.Request) {
+	blocks := 100
+	if s := r.URL.Query().Get("blocks"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid blocks", "blocks is how many recent blocks to summarize, e.g. ?blocks=100")
+			return
+		}
+		blocks = n
+	}
+	writeOK(w, domain.GetLatencyStats(blocks))
+}
+
+// handleReorgs returns the reorgs the head follower detected, newest first (?limit=, default 20).
+func handleReorgs(w http.ResponseWriter, r *http.Request) {
+	writeOK(w, domain.GetReorgs(parseLimit(r, 20)))
+}
+
+// handleRegistry returns the signature registry status, or with ?selector=
+// (4-byte selector or 32-byte event topic) every candidate signature for it.
+func handleRegistry(w http.ResponseWriter, r *http.Request) {
+	if sel := r.URL.Query().Get("selector"); sel != "" {
+		res, ok := domain.LookupSignature(sel)
+		if !ok {
+			writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid selector", "selector is a 4-byte function/error selector (0x + 8 hex) or a 32-byte event topic (0x + 64 hex)")
+			return
+		}
+		writeOK(w, res)
+		return
+	}
+	writeOK(w, domain.GetRegistryStatus())
+}
+
+// handleLabels serves the address labels. GET /api/labels lists them (?category=,
+// ?limit=, default 100); GET /api/labels/{address} returns one; POST /api/labels
+// adds one label object or an array of them at runtime.
+func handleLabels(w http.ResponseWriter, r *http.Request) {
+	addr := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/labels"), "/")
+	switch {
+	case r.Method == http.MethodPost && addr == "":
+		var raw json.RawMessage
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&raw)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid JSON body", `Send {"address": "0x…", "name": "…", "category": "searcher"} or an array of them`)
+			return
+		}
+		var in []domain.AddressLabel
+		if raw = bytes.TrimSpace(raw); len(raw) > 0 && raw[0] == '[' {
+			err = json.Unmarshal(raw, &in)
+		} else {
+			var one domain.AddressLabel
+			err = json.Unmarshal(raw, &one)
+			in = []domain.AddressLabel{one}
+		}
+		if err != nil || len(in) == 0 {
+			writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Expected a label object or a non-empty array of them", `Send {"address": "0x…", "name": "…", "category": "searcher"} with string fields`)
+			return
+		}
+		added, err := domain.AddLabels(in)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, "BAD_REQUEST", err.Error(), "address is 0x + 40 hex (or a 48-byte builder pubkey), name 1-64 characters, category e.g. router, token, builder, searcher, cex, bridge")
+			return
+		}
+		writeOK(w, map[string]any{"added": added, "count": len(added)})
+	case r.Method != http.MethodGet && r.Method != http.MethodPost:
+		w.Header().Set("Allow", "GET, POST")
+		writeErr(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Use GET or POST", "GET /api/labels[/{address}] to query, POST /api/labels to add")
+	case r.Method == http.MethodPost:
+		writeErr(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "POST to /api/labels", "The address goes in the JSON body")
+	case addr != "":
+		l, ok := domain.LookupLabel(addr)
+		if !ok {
+			writeErr(w, http.StatusNotFound, "NOT_FOUND", "No label for "+addr, "POST /api/labels to add one")
+			return
+		}
+		writeOK(w, l)
+	default:
+		writeOK(w, domain.ListLabels(r.URL.Query().Get("category"), parseLimit(r, 100)))
+	}
+}
+
+// streamHeartbeat keeps idle SSE connections alive through proxies that close
+// silent streams.
+const streamHeartbeat = 15 * time.Second
+
+// startSSE writes the event-stream headers and the client reconnect delay.
+func startSSE(w http.ResponseWriter, flusher http.Flusher) {
+	w.Header().Set("content-type", "text/event-stream")
+	w.Header().Set("cache-control", "no-cache")
+	w.Header().Set("connection", "keep-alive")
+	w.Header().Set("x-accel-buffering", "no")
+	fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+}
+
+// handleStream pushes domain events as Server-Sent Events. ?topics=mempool,heads
+// narrows the stream; by default every topic is sent.
+func handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErr(w, http.StatusInternalServerError, "STREAM", "Streaming unsupported", "The response writer cannot flush; check for buffering middleware")
+		return
+	}
+	var topics []string
+	if s := r.URL.Query().Get("topics"); s != "" {
+		for _, t := range strings.Split(s, ",") {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if t == "" {
+				continue
+			}
+			if !slices.Contains(domain.EventTopics, t) {
+				writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Unknown topic "+t, "topics is a comma-separated list of "+strings.Join(domain.EventTopics, ", "))
+				return
+			}
+			topics = append(topics, t)
+		}
+	}
+	events, cancel := domain.SubscribeEvents(topics, 256)
+	defer cancel()
+	startSSE(w, flusher)
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev := <-events:
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Topic, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
 }
 
 // relayDeliveredLimit is the max limit accepted by standard MEV-Boost relay APIs.
@@ -118,9 +409,23 @@ func handleRelaysDelivered(w http.ResponseWriter, _ *http.Request) {
 		"delivered_payloads": deliveredPayloads,
 		"count":              len(deliveredPayloads),
 		"latest_block":       latestBlockNum,
+		"labels":             bidTraceLabels(deliveredPayloads),
 	})
 }
 
+// bidTraceLabels labels the builders (by pubkey) and proposer fee recipients in relay bid traces.
+func bidTraceLabels(traces []map[string]any) map[string]domain.AddressLabel {
+	keys := make([]string, 0, 2*len(traces))
+	for _, t := range traces {
+		for _, k := range []string{"builder_pubkey", "proposer_fee_recipient"} {
+			if s, ok := t[k].(string); ok {
+				keys = append(keys, s)
+			}
+		}
+	}
+	return domain.LabelsFor(keys...)
+}
+
 // relayReceivedLimit is the max limit accepted by standard MEV-Boost relay APIs.
 const relayReceivedLimit = 200
 
@@ -210,6 +515,7 @@ func handleRelaysReceived(w http.ResponseWriter, _ *http.Request) {
 		"received_blocks": receivedBlocks,
 		"count":           len(receivedBlocks),
 		"latest_block":    latestBlockNum,
+		"labels":          bidTraceLabels(receivedBlocks),
 	}
 	if fallbackDelivered {
 		payload["fallback_delivered"] = true
@@ -343,6 +649,7 @@ func handleMEV(w http.ResponseWriter, r *http.Request) {
 		"liquidationCount": analysis.LiquidationCount,
 		"jitLiquidity":     analysis.JITLiquidity,
 		"jitCount":         analysis.JITCount,
+		"labels":           analysis.Labels,
 		"sources":          map[string]any{"rpc_http": httpURL, "rpc_ws": wsURL, "beacon_api": beacon.SourceInfo(), "relays": relay.SourceInfo()},
 		"note":             "MEV detection: sandwiches (frontrun+backrun), arbitrage (multi-pool swaps), liquidations (Aave/Compound), JIT liquidity (mint→swap→burn).",
 	})
@@ -350,22 +657,179 @@ func handleMEV(w http.ResponseWriter, r *http.Request) {
 
 func handleTrackTx(w http.ResponseWriter, r *http.Request) {
 	hash := r.URL.Path[len("/api/track/tx/"):]
+	if h, ok := strings.CutSuffix(hash, "/watch"); ok {
+		handleWatchTx(w, r, h)
+		return
+	}
 	if hash == "" {
 		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Missing transaction hash", "Invoke /api/track/tx/{hash} or /api/track/tx/latest")
 		return
 	}
-	resp, err := domain.TrackTx(hash)
-	if err != nil {
-		writeErr(w, http.StatusNotFound, "TX_NOT_FOUND", "Transaction not visible on this execution node", "")
+	opts := domain.TrackOptions{}
+	switch r.URL.Query().Get("trace") {
+	case "", "0", "false":
+	case "1", "true":
+		opts.Trace = true
+	default:
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid trace", "trace=1 adds the internal call tree and state diff (needs debug_traceTransaction on the RPC)")
 		return
 	}
-	if resp == nil {
-		writeErr(w, http.StatusBadGateway, "RPC_ERROR", "Failed to resolve transaction", "")
+	resp, err := domain.TrackTxWithOptions(hash, opts)
+	if err != nil {
+		writeTrackErr(w, err)
 		return
 	}
 	writeOK(w, resp)
 }
 
+// handleTrackNonce serves /api/track/account/{address}/nonce/{n}: the tx currently
+// occupying that sender+nonce slot, with the same lifecycle view as /api/track/tx.
+func handleTrackNonce(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/api/track/account/"):], "/"), "/")
+	if len(parts) != 3 || parts[1] != "nonce" {
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Malformed path", "Invoke /api/track/account/{address}/nonce/{n}")
+		return
+	}
+	addr := parts[0]
+	nonce, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		nonce, err = config.ParseHexUint64(parts[2])
+	}
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid nonce", "nonce is a decimal or 0x-prefixed hex integer")
+		return
+	}
+	res, err := domain.TrackNonce(addr, nonce)
+	if err != nil {
+		status, kind, msg := trackErrInfo(err)
+		hint := ""
+		switch {
+		case status == http.StatusBadRequest:
+			hint = "address is 0x followed by 40 hex digits"
+		case res != nil && res.State == domain.NonceMined && res.Hash == "":
+			hint = "Locating an already-mined nonce reads historical state; older nonces may need an archive node"
+		case status == http.StatusNotFound:
+			hint = "No transaction is known for this slot yet; retry /api/track/account/{address}/nonce/{n} once one is broadcast"
+		}
+		writeErr(w, status, kind, msg, hint)
+		return
+	}
+	writeOK(w, res)
+}
+
+// trackErrInfo maps domain.TrackTx errors to an HTTP status, error kind and message.
+func trackErrInfo(err error) (int, string, string) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidHash):
+		return http.StatusBadRequest, "BAD_REQUEST", "Invalid transaction hash"
+	case errors.Is(err, domain.ErrInvalidAddress):
+		return http.StatusBadRequest, "BAD_REQUEST", "Invalid address"
+	case errors.Is(err, domain.ErrTxNotFound):
+		return http.StatusNotFound, "TX_NOT_FOUND", "Transaction not visible on this execution node"
+	case errors.Is(err, domain.ErrDecodeFailure):
+		log.Printf("track: %v\n", err)
+		return http.StatusBadGateway, "DECODE_ERROR", "Unexpected RPC response shape"
+	default:
+		// RPC errors can embed provider URLs (and API keys), so only log them.
+		log.Printf("track: %v\n", err)
+		return http.StatusBadGateway, "RPC_ERROR", "Failed to resolve transaction"
+	}
+}
+
+func writeTrackErr(w http.ResponseWriter, err error) {
+	status, kind, msg := trackErrInfo(err)
+	hint := ""
+	if status == http.StatusBadRequest {
+		hint = "Invoke /api/track/tx/{hash} or /api/track/tx/latest"
+	}
+	writeErr(w, status, kind, msg, hint)
+}
+
+// trackBatchItem is one entry of the /api/track/batch response: Data or Error.
+type trackBatchItem struct {
+	Hash  string    `json:"hash"`
+	Data  any       `json:"data,omitempty"`
+	Error *eduError `json:"error,omitempty"`
+}
+
+// handleTrackBatch serves POST /api/track/batch with {"hashes": [...]}. It always
+// answers 200 when the request itself is valid; per-hash failures are reported
+// in each item's error.
+func handleTrackBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeErr(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Use POST", `Send {"hashes": ["0x…", …]} as the JSON body`)
+		return
+	}
+	var body struct {
+		Hashes []string `json:"hashes"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&body); err != nil {
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid JSON body", `Send {"hashes": ["0x…", …]}`)
+		return
+	}
+	items, stats, err := domain.TrackBatch(body.Hashes)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", err.Error(), fmt.Sprintf("Send between 1 and %d distinct hashes", domain.MaxTrackBatch))
+		return
+	}
+	out := make([]trackBatchItem, len(items))
+	for i, it := range items {
+		out[i].Hash = it.Hash
+		if it.Err != nil {
+			_, kind, msg := trackErrInfo(it.Err)
+			out[i].Error = &eduError{Kind: kind, Message: msg}
+			continue
+		}
+		out[i].Data = it.Result
+	}
+	writeOK(w, map[string]any{"results": out, "stats": stats})
+}
+
+// handleWatchTx streams one tx's lifecycle events (pending, included, justified,
+// finalized, replaced, dropped, reorged_out) as SSE and ends the stream once the
+// tx is finalized, replaced or dropped.
+func handleWatchTx(w http.ResponseWriter, r *http.Request, hash string) {
+	if len(hash) != 66 || !strings.HasPrefix(hash, "0x") {
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid transaction hash", "Invoke /api/track/tx/{0x-prefixed 32-byte hash}/watch")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErr(w, http.StatusInternalServerError, "STREAM", "Streaming unsupported", "The response writer cannot flush; check for buffering middleware")
+		return
+	}
+	events := domain.WatchTx(r.Context(), hash)
+	startSSE(w, flusher)
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	var id int
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev, open := <-events:
+			if !open {
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			id++
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, ev.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
 var snapshotCache *pkg.Cache[[]byte]
 
 func init() {
@@ -463,6 +927,16 @@ func Run() error {
 	mux := http.NewServeMux()
 	// Data endpoints: mempool, relay (delivered/received), beacon (headers, finality), block, snapshot.
 	mux.HandleFunc("/api/mempool", handleMempool)
+	mux.HandleFunc("/api/mempool/history", handleMempoolHistory)
+	mux.HandleFunc("/api/mempool/txpool", handleTxPool)
+	mux.HandleFunc("/api/fees/estimate", handleFeeEstimate)
+	mux.HandleFunc("/api/stream", handleStream)
+	mux.HandleFunc("/api/privateflow", handlePrivateFlow)
+	mux.HandleFunc("/api/stats/latency", handleLatencyStats)
+	mux.HandleFunc("/api/reorgs", handleReorgs)
+	mux.HandleFunc("/api/registry", handleRegistry)
+	mux.HandleFunc("/api/labels", handleLabels)
+	mux.HandleFunc("/api/labels/", handleLabels)
 	mux.HandleFunc("/api/relays/delivered", handleRelaysDelivered)
 	mux.HandleFunc("/api/relays/received", handleRelaysReceived)
 	mux.HandleFunc("/api/validators/head", handleBeaconHeaders)
@@ -471,6 +945,8 @@ func Run() error {
 	mux.HandleFunc("/api/block/", handleBlock)
 	mux.HandleFunc("/api/mev/sandwich", handleMEV)
 	mux.HandleFunc("/api/track/tx/", handleTrackTx)
+	mux.HandleFunc("/api/track/account/", handleTrackNonce)
+	mux.HandleFunc("/api/track/batch", handleTrackBatch)
 	mux.HandleFunc("/api/health", handleHealth)
 	mux.HandleFunc("/api/health/live", handleHealthLiveness)
 	mux.HandleFunc("/api/health/ready", handleHealthReadiness)